@@ -3,13 +3,29 @@
 // license that can be found in the LICENSE file.
 
 // Package account defines the Account data structure used to represent
-// stored user credentials, including website, username, email, and password.
+// stored user credentials, including website, username, email, password,
+// an optional two-factor authentication key, notes, custom fields, tags and
+// the folder the account is filed in, along with when it was created,
+// modified, had its password changed and was last accessed.
+// Every account carries a random ID that identifies it independently of
+// its position.
 package account
 
+import (
+	"crypto/rand"
+	"encoding/base32"
+	"strings"
+	"time"
+)
+
 // Account represents a single credential entry stored in the application.
 // Each field includes a JSON tag to ensure proper encoding and decoding
 // when saving or loading accounts from the storage file.
 type Account struct {
+	// ID identifies the account for as long as it exists. Accounts
+	// saved by older versions have none until one is assigned.
+	ID string `json:"id,omitempty"`
+
 	// Website is the domain or service the credentials belong to.
 	Website string `json:"website"`
 
@@ -21,4 +37,118 @@ type Account struct {
 
 	// Pwd stores the password for the account.
 	Pwd string `json:"pwd"`
+
+	// OTP is the otpauth:// URI of the account's two-factor
+	// authentication key, if it has one.
+	OTP string `json:"otp,omitempty"`
+
+	// Notes is free-form text, which may span several lines.
+	Notes string `json:"notes,omitempty"`
+
+	// Fields are custom fields, such as security questions, account
+	// numbers or API keys, in the order they were added.
+	Fields []Field `json:"fields,omitempty"`
+
+	// Folder is the slash-separated path of the folder the account is
+	// filed in, as in "work/aws/prod", or empty for the top level.
+	Folder string `json:"folder,omitempty"`
+
+	// Tags are free-form labels, compared ignoring case.
+	Tags []string `json:"tags,omitempty"`
+
+	// Created is when the account was added. The timestamps are zero
+	// when unknown, as for accounts saved by older versions until the
+	// event they record happens again.
+	Created time.Time `json:"created,omitzero"`
+
+	// Modified is when any field of the account last changed.
+	Modified time.Time `json:"modified,omitzero"`
+
+	// PasswordChanged is when the password was last set.
+	PasswordChanged time.Time `json:"password_changed,omitzero"`
+
+	// Accessed is when the password or another secret of the account
+	// was last shown or copied.
+	Accessed time.Time `json:"accessed,omitzero"`
+}
+
+// HasTag reports whether the account has the tag, ignoring case.
+func (a Account) HasTag(tag string) bool {
+	for _, t := range a.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// CleanFolder returns the canonical form of a folder path: its names
+// trimmed and separated by single slashes, without a leading or trailing
+// slash.
+func CleanFolder(path string) string {
+	names := []string{}
+	for name := range strings.SplitSeq(path, "/") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// InFolder reports whether the account is filed in the folder at path or
+// in one of its subfolders. Every account is in the top level, "".
+func (a Account) InFolder(path string) bool {
+	path = CleanFolder(path)
+	return path == "" || a.Folder == path || strings.HasPrefix(a.Folder, path+"/")
+}
+
+// Field is a custom field of an account.
+type Field struct {
+	// Name is what the field holds, as in "Security question".
+	Name string `json:"name"`
+
+	// Value is the contents of the field.
+	Value string `json:"value"`
+
+	// Concealed marks a secret: it is masked in listings, like a
+	// password, and only searched on request.
+	Concealed bool `json:"concealed,omitempty"`
+}
+
+// Field returns the custom field called name, ignoring case.
+func (a Account) Field(name string) (Field, bool) {
+	for _, f := range a.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return Field{}, false
+}
+
+// idBytes is the number of random bytes in an ID, enough to make a
+// collision between any two accounts practically impossible.
+const idBytes = 10
+
+// IDLen is the length of an account ID.
+const IDLen = 16
+
+// idAlphabet is lowercase base32, which is easy to read out and type.
+const idAlphabet = "abcdefghijklmnopqrstuvwxyz234567"
+
+// encoding writes IDs in idAlphabet.
+var encoding = base32.NewEncoding(idAlphabet).WithPadding(base32.NoPadding)
+
+// NewID returns a new random account ID of IDLen lowercase base32
+// characters.
+func NewID() string {
+	b := make([]byte, idBytes)
+	rand.Read(b)
+	return encoding.EncodeToString(b)
+}
+
+// IsID reports whether s has the form of an account ID or of a prefix of
+// one.
+func IsID(s string) bool {
+	return s != "" && len(s) <= encoding.EncodedLen(idBytes) &&
+		strings.Trim(s, idAlphabet) == ""
 }
@@ -0,0 +1,97 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package account_test contains unit tests for the account package.
+package account_test
+
+import (
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+)
+
+// TestNewID verifies that new IDs have the documented form and differ
+// from each other.
+func TestNewID(t *testing.T) {
+	seen := map[string]bool{}
+	for range 100 {
+		id := account.NewID()
+		if len(id) != 16 || !account.IsID(id) {
+			t.Fatalf("NewID() = %q; want 16 lowercase base32 characters", id)
+		}
+		if seen[id] {
+			t.Fatalf("NewID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestIsID verifies which strings are taken for IDs or ID prefixes.
+func TestIsID(t *testing.T) {
+	tests := map[string]bool{
+		"k7q2":              true,
+		"k7q2mx4abcdefgh2":  true,
+		"k7q2mx4abcdefgh23": false, // too long
+		"":                  false,
+		"K7Q2":              false, // IDs are lowercase
+		"k7q1":              false, // 1 is not base32
+		"github.com":        false,
+	}
+
+	for s, want := range tests {
+		if got := account.IsID(s); got != want {
+			t.Errorf("IsID(%q) = %v; want %v", s, got, want)
+		}
+	}
+}
+
+// TestField verifies that custom fields are looked up by name, ignoring
+// case.
+func TestField(t *testing.T) {
+	a := account.Account{Fields: []account.Field{{Name: "Security question", Value: "First pet?"}}}
+
+	if f, ok := a.Field("security QUESTION"); !ok || f.Value != "First pet?" {
+		t.Errorf("Field(security QUESTION) = %v, %v; want the security question", f, ok)
+	}
+	if _, ok := a.Field("PIN"); ok {
+		t.Errorf("Field(PIN) found a field; want none")
+	}
+}
+
+// TestFolders verifies that folder paths are cleaned and that accounts are
+// in their folder and its parents only.
+func TestFolders(t *testing.T) {
+	for in, want := range map[string]string{
+		"work/aws/prod":     "work/aws/prod",
+		"/work//aws/ prod/": "work/aws/prod",
+		" / ":               "",
+	} {
+		if got := account.CleanFolder(in); got != want {
+			t.Errorf("CleanFolder(%q) = %q; want %q", in, got, want)
+		}
+	}
+
+	a := account.Account{Folder: "work/aws/prod"}
+	for path, want := range map[string]bool{
+		"":              true,
+		"work":          true,
+		"work/aws/":     true,
+		"work/aws/prod": true,
+		"work/aw":       false,
+		"work/aws/dev":  false,
+		"personal":      false,
+	} {
+		if got := a.InFolder(path); got != want {
+			t.Errorf("InFolder(%q) = %v; want %v", path, got, want)
+		}
+	}
+}
+
+// TestHasTag verifies that tags are compared ignoring case.
+func TestHasTag(t *testing.T) {
+	a := account.Account{Tags: []string{"Prod", "shared"}}
+	if !a.HasTag("prod") || !a.HasTag("SHARED") || a.HasTag("dev") {
+		t.Errorf("HasTag() on %v reports the wrong tags", a.Tags)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package breach checks passwords against a local copy of the Have I Been
+// Pwned "Pwned Passwords" corpus, so that nothing is sent over the
+// network. The corpus is a text file of "HASH:COUNT" lines sorted by hash,
+// as produced by the official downloader with SHA-1 or NTLM hashes. The
+// file is far too large to load, so it is searched in place by binary
+// search over byte offsets.
+package breach
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+
+	// MD4 is broken, but NTLM hashes are MD4 by definition.
+	"golang.org/x/crypto/md4"
+)
+
+// Hash is the hash function of a corpus file.
+type Hash int
+
+// Supported hash functions.
+const (
+	// SHA1 is the SHA-1 of the UTF-8 password.
+	SHA1 Hash = iota
+
+	// NTLM is the MD4 of the UTF-16LE password.
+	NTLM
+)
+
+// String returns the name of the hash function.
+func (h Hash) String() string {
+	if h == NTLM {
+		return "NTLM"
+	}
+	return "SHA-1"
+}
+
+// Sum returns the upper-case hexadecimal hash of password.
+func (h Hash) Sum(password string) string {
+	var sum []byte
+	switch h {
+	case NTLM:
+		d := md4.New()
+		for _, u := range utf16.Encode([]rune(password)) {
+			binary.Write(d, binary.LittleEndian, u)
+		}
+		sum = d.Sum(nil)
+	default:
+		s := sha1.Sum([]byte(password))
+		sum = s[:]
+	}
+	return strings.ToUpper(hex.EncodeToString(sum))
+}
+
+// ErrFormat is returned when a file does not look like a corpus file.
+var ErrFormat = errors.New("not a Pwned Passwords hash file")
+
+// maxLine bounds the length of a line: a hash, a colon, a count and the
+// line ending.
+const maxLine = 128
+
+// File is an open corpus file.
+type File struct {
+	f    *os.File
+	size int64
+
+	// Hash is the hash function of the file, detected from its first line.
+	Hash Hash
+}
+
+// Open opens the corpus file at path and detects its hash function.
+func Open(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	file := &File{f: f, size: info.Size()}
+	hash, _, _, err := file.lineAt(0)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	switch len(hash) {
+	case 2 * sha1.Size:
+		file.Hash = SHA1
+	case 2 * md4.Size:
+		file.Hash = NTLM
+	default:
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, ErrFormat)
+	}
+	return file, nil
+}
+
+// Close closes the file.
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// Count returns how many times password appears in the corpus, or zero if
+// it does not.
+func (f *File) Count(password string) (int, error) {
+	return f.Lookup(f.Hash.Sum(password))
+}
+
+// Lookup returns the count of the hexadecimal hash, or zero if the file
+// does not contain it.
+//
+// The search keeps lo at the start of a line and narrows [lo, hi) to the
+// lines that may hold the hash: the first line starting at or after the
+// midpoint is compared, and the range continues either after it or
+// before the midpoint.
+func (f *File) Lookup(hash string) (int, error) {
+	hash = strings.ToUpper(hash)
+	lo, hi := int64(0), f.size
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		start, err := f.lineStart(mid)
+		if err != nil {
+			return 0, err
+		}
+		if start >= hi {
+			hi = mid
+			continue
+		}
+
+		h, count, next, err := f.lineAt(start)
+		if err != nil {
+			return 0, err
+		}
+		switch c := strings.Compare(h, hash); {
+		case c == 0:
+			return count, nil
+		case c < 0:
+			lo = next
+		default:
+			hi = mid
+		}
+	}
+	return 0, nil
+}
+
+// lineStart returns the offset of the first line starting at or after off.
+func (f *File) lineStart(off int64) (int64, error) {
+	if off == 0 {
+		return 0, nil
+	}
+
+	// Look for the end of the line containing off-1.
+	buf := make([]byte, maxLine)
+	n, err := f.f.ReadAt(buf, off-1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+	i := bytes.IndexByte(buf[:n], '\n')
+	if i < 0 {
+		if int64(n) < maxLine {
+			return f.size, nil
+		}
+		return 0, ErrFormat
+	}
+	return off + int64(i), nil
+}
+
+// lineAt parses the line starting at off and returns its hash, its count
+// and the offset of the next line. A line without a count counts once.
+func (f *File) lineAt(off int64) (hash string, count int, next int64, err error) {
+	buf := make([]byte, maxLine)
+	n, err := f.f.ReadAt(buf, off)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", 0, 0, err
+	}
+	buf = buf[:n]
+
+	end := bytes.IndexByte(buf, '\n')
+	switch {
+	case end >= 0:
+		next = off + int64(end) + 1
+		buf = buf[:end]
+	case int64(n) < maxLine:
+		next = off + int64(n)
+	default:
+		return "", 0, 0, ErrFormat
+	}
+
+	line := strings.TrimRight(string(buf), "\r")
+	hash, countText, hasCount := strings.Cut(line, ":")
+	hash = strings.ToUpper(hash)
+	if hash == "" {
+		return "", 0, 0, ErrFormat
+	}
+	count = 1
+	if hasCount {
+		if count, err = strconv.Atoi(strings.TrimSpace(countText)); err != nil {
+			return "", 0, 0, ErrFormat
+		}
+	}
+	return hash, count, next, nil
+}
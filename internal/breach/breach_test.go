@@ -0,0 +1,153 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package breach_test contains unit tests for the breach package.
+// These tests search small generated hash files laid out like the Pwned
+// Passwords downloads.
+package breach_test
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/breach"
+)
+
+// writeFixture writes a sorted hash file holding the given passwords with
+// their counts, plus filler random hashes, and returns its path. Lines end
+// with CRLF like the official downloads; trailingNewline controls whether
+// the last line does too.
+func writeFixture(t *testing.T, h breach.Hash, passwords map[string]int, filler int, trailingNewline bool) string {
+	t.Helper()
+
+	lines := []string{}
+	for pwd, count := range passwords {
+		lines = append(lines, fmt.Sprintf("%s:%d", h.Sum(pwd), count))
+	}
+	size := len(h.Sum(""))
+	for i := range filler {
+		b := make([]byte, size/2)
+		if _, err := rand.Read(b); err != nil {
+			t.Fatalf("rand.Read() failed: %v", err)
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(b)), i+1))
+	}
+	sort.Strings(lines)
+
+	content := strings.Join(lines, "\r\n")
+	if trailingNewline {
+		content += "\r\n"
+	}
+	path := filepath.Join(t.TempDir(), "pwned-passwords.txt")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write fixture: %v", err)
+	}
+	return path
+}
+
+// TestSum verifies the hashes against known values.
+func TestSum(t *testing.T) {
+	tests := []struct {
+		hash breach.Hash
+		want string
+	}{
+		{breach.SHA1, "5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8"},
+		{breach.NTLM, "8846F7EAEE8FB117AD06BDD830B7586C"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.hash.Sum("password"); got != tt.want {
+			t.Errorf("%s.Sum(password) = %s; want %s", tt.hash, got, tt.want)
+		}
+	}
+}
+
+// TestCount verifies that every password in the file is found with its
+// count, for both hash functions and with or without a final newline.
+func TestCount(t *testing.T) {
+	passwords := map[string]int{
+		"password": 9545824,
+		"123456":   37359195,
+		"hunter2":  17043,
+		"Pa55word": 3,
+	}
+
+	for _, h := range []breach.Hash{breach.SHA1, breach.NTLM} {
+		for _, trailing := range []bool{true, false} {
+			path := writeFixture(t, h, passwords, 2000, trailing)
+
+			f, err := breach.Open(path)
+			if err != nil {
+				t.Fatalf("Open() failed: %v", err)
+			}
+			defer f.Close()
+
+			// The hash function is detected from the file.
+			if f.Hash != h {
+				t.Fatalf("Open().Hash = %s; want %s", f.Hash, h)
+			}
+
+			for pwd, want := range passwords {
+				got, err := f.Count(pwd)
+				if err != nil {
+					t.Fatalf("%s: Count(%q) failed: %v", h, pwd, err)
+				}
+				if got != want {
+					t.Errorf("%s: Count(%q) = %d; want %d", h, pwd, got, want)
+				}
+			}
+
+			// Passwords that are not in the file count zero.
+			for _, pwd := range []string{"", "correct horse battery staple", "password1"} {
+				if got, err := f.Count(pwd); err != nil || got != 0 {
+					t.Errorf("%s: Count(%q) = %d, %v; want 0", h, pwd, got, err)
+				}
+			}
+		}
+	}
+}
+
+// TestLookupEveryLine verifies that the first, last and every other line
+// of a file can be found.
+func TestLookupEveryLine(t *testing.T) {
+	path := writeFixture(t, breach.SHA1, nil, 300, true)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() failed: %v", err)
+	}
+
+	f, err := breach.Open(path)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	defer f.Close()
+
+	for _, line := range strings.Fields(string(data)) {
+		hash, count, _ := strings.Cut(line, ":")
+		got, err := f.Lookup(hash)
+		if err != nil || fmt.Sprint(got) != count {
+			t.Fatalf("Lookup(%s) = %d, %v; want %s", hash, got, err, count)
+		}
+	}
+}
+
+// TestOpenInvalid verifies that files that are not hash files are
+// rejected.
+func TestOpenInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello:world\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() failed: %v", err)
+	}
+
+	if _, err := breach.Open(path); !errors.Is(err, breach.ErrFormat) {
+		t.Fatalf("Open(notes.txt) error = %v; want ErrFormat", err)
+	}
+}
@@ -0,0 +1,226 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/breach"
+	"github.com/nullzeiger/pwdcli/internal/config"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/strength"
+	"github.com/nullzeiger/pwdcli/internal/util"
+)
+
+// runAudit reports weak and reused passwords.
+func runAudit(e *env, args []string) error {
+	fs := e.flagSet("audit")
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	findings, err := handling.Audit(store, e.settings.Strength.MinScore)
+	if err != nil {
+		return err
+	}
+	return printFindings(findings, e.settings.Format)
+}
+
+// runBreaches reports the passwords found in a Pwned Passwords hash file.
+func runBreaches(e *env, args []string) error {
+	fs := e.flagSet("breaches")
+	hibpFile := fs.String("hibp-file", "", "Path of a Pwned Passwords hash file, SHA-1 or NTLM (overrides the config file)")
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	path := e.settings.Breach.File
+	if *hibpFile != "" {
+		path = *hibpFile
+	}
+
+	// Open the hash file before asking for the master password.
+	f, err := openBreaches(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	breaches, err := handling.Breached(store, f)
+	if err != nil {
+		return err
+	}
+	return printBreaches(breaches, e.settings.Format)
+}
+
+// ErrWeakPassword is returned when a strict strength policy refuses a
+// password.
+var ErrWeakPassword = errors.New("password too weak")
+
+// checkStrength estimates the strength of the new entry's password. Below
+// the policy's minimum score it prints a warning on stderr, or refuses
+// the password with ErrWeakPassword under a strict policy.
+func checkStrength(act handling.Act, policy config.ResolvedStrength) error {
+	r := handling.PasswordStrength(act)
+	if r.Score >= policy.MinScore {
+		return nil
+	}
+
+	label := "Warning: weak password"
+	if policy.Strict {
+		label = "Weak password"
+	}
+	fmt.Fprintf(os.Stderr, "%s, score %s.\n", label, r)
+	if r.Feedback.Warning != "" {
+		fmt.Fprintln(os.Stderr, "  "+r.Feedback.Warning)
+	}
+	for _, s := range r.Feedback.Suggestions {
+		fmt.Fprintln(os.Stderr, "  - "+s)
+	}
+
+	if policy.Strict {
+		return fmt.Errorf("%w: score %d, the policy requires %d", ErrWeakPassword, r.Score, policy.MinScore)
+	}
+	return nil
+}
+
+// jsonFinding is the JSON form of an audit finding.
+type jsonFinding struct {
+	Index     int    `json:"index"`
+	ID        string `json:"id,omitempty"`
+	Website   string `json:"website"`
+	Username  string `json:"username"`
+	Score     int    `json:"score"`
+	CrackTime string `json:"crack_time"`
+	Warning   string `json:"warning,omitempty"`
+	ReusedBy  []int  `json:"reused_by,omitempty"`
+}
+
+// printFindings writes the audit findings to standard output in the given
+// format. Passwords are never shown.
+func printFindings(findings []handling.Finding, format string) error {
+	switch format {
+	case config.FormatJSON:
+		out := make([]jsonFinding, 0, len(findings))
+		for _, f := range findings {
+			out = append(out, jsonFinding{
+				Index:     f.Index,
+				ID:        f.Account.ID,
+				Website:   f.Account.Website,
+				Username:  f.Account.Username,
+				Score:     f.Strength.Score,
+				CrackTime: f.Strength.CrackTimeString(strength.OfflineSlowHash),
+				Warning:   f.Strength.Feedback.Warning,
+				ReusedBy:  f.ReusedBy,
+			})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case config.FormatText, "":
+		if len(findings) == 0 {
+			fmt.Println("No weak or reused passwords found.")
+			return nil
+		}
+		for _, f := range findings {
+			notes := []string{"Score " + f.Strength.String() + "."}
+			if f.Strength.Feedback.Warning != "" {
+				notes = append(notes, f.Strength.Feedback.Warning)
+			}
+			if len(f.ReusedBy) > 0 {
+				notes = append(notes, fmt.Sprintf("Also used by %v.", f.ReusedBy))
+			}
+			fmt.Printf("%s Website: %s Username: %s: %s\n",
+				f.Label(), f.Account.Website, f.Account.Username, strings.Join(notes, " "))
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// ErrBreachedPassword is returned when a strict policy refuses a password
+// found in the breach corpus.
+var ErrBreachedPassword = errors.New("password found in breaches")
+
+// openBreaches opens the Pwned Passwords hash file at path.
+func openBreaches(path string) (*breach.File, error) {
+	if path == "" {
+		return nil, errors.New("no hash file: use -hibp-file or set breach.file in the config file")
+	}
+	path, err := util.ExpandHome(path)
+	if err != nil {
+		return nil, err
+	}
+	return breach.Open(path)
+}
+
+// checkBreached looks up a new password in the hash file at path. A
+// password found there is reported on stderr, or refused with
+// ErrBreachedPassword under a strict policy.
+func checkBreached(pwd, path string, strict bool) error {
+	f, err := openBreaches(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := f.Count(pwd)
+	if err != nil || n == 0 {
+		return err
+	}
+	if strict {
+		return fmt.Errorf("%w %d times", ErrBreachedPassword, n)
+	}
+	fmt.Fprintf(os.Stderr, "Warning: this password appears %d times in known breaches.\n", n)
+	return nil
+}
+
+// jsonBreach is the JSON form of a breached entry.
+type jsonBreach struct {
+	Index    int    `json:"index"`
+	ID       string `json:"id,omitempty"`
+	Website  string `json:"website"`
+	Username string `json:"username"`
+	Count    int    `json:"count"`
+}
+
+// printBreaches writes the breached entries to standard output in the
+// given format. Passwords are never shown.
+func printBreaches(breaches []handling.Breach, format string) error {
+	switch format {
+	case config.FormatJSON:
+		out := make([]jsonBreach, 0, len(breaches))
+		for _, b := range breaches {
+			out = append(out, jsonBreach{Index: b.Index, ID: b.Account.ID, Website: b.Account.Website, Username: b.Account.Username, Count: b.Count})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case config.FormatText, "":
+		if len(breaches) == 0 {
+			fmt.Println("No breached passwords found.")
+			return nil
+		}
+		for _, b := range breaches {
+			fmt.Printf("%s Website: %s Username: %s: found %d times in breaches\n",
+				b.Label(), b.Account.Website, b.Account.Username, b.Count)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
@@ -3,121 +3,335 @@
 // license that can be found in the LICENSE file.
 
 // Package cli provides the command-line interface used for interacting
-// with the password management system. It handles parsing flags,
-// dispatching commands, and coordinating with the underlying handling
-// and storage layers.
+// with the password management system. Every operation is a subcommand
+// with its own flags, help text and validation, as in "pwdcli add" or
+// "pwdcli rm 3"; the single flag set of earlier versions is still
+// accepted and translated. The package coordinates with the underlying
+// handling and storage layers.
 package cli
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/nullzeiger/pwdcli/internal/config"
 	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/profile"
 	"github.com/nullzeiger/pwdcli/internal/storage"
+	"github.com/nullzeiger/pwdcli/internal/util"
 )
 
-// Run is the main entry point for the CLI. It defines and parses flags,
-// ensures the storage file exists, and dispatches the appropriate action
-// based on the user’s command-line arguments.
-func Run() {
-	// --- Command Flags ---
-	// Basic operations
-	listFlag := flag.Bool("all", false, "List all password entries")
-	addFlag := flag.Bool("add", false, "Add a new password entry")
-	deleteFlag := flag.Int("delete", -1, "Delete an entry by index")
-	searchFlag := flag.String("search", "", "Search entries by keyword")
-
-	// Fields required when using -add
-	website := flag.String("website", "", "Website (required for -add)")
-	username := flag.String("username", "", "Username (required for -add)")
-	email := flag.String("email", "", "Email (required for -add)")
-	password := flag.String("pwd", "", "Password (required for -add)")
-
-	flag.Parse()
-
-	// Ensure the storage file exists (~/.passwords.json)
-	// If it doesn't, it is automatically created.
-	if err := storage.Create(); err != nil {
-		fmt.Println("Error creating password file:", err)
-		return
+// command is a pwdcli subcommand.
+type command struct {
+	// name is what the user types to run the command.
+	name string
+
+	// args describes the positional arguments, as in "<entry>".
+	args string
+
+	// summary is a one-line description shown in the help.
+	summary string
+
+	// run parses the arguments that follow the command name and runs it.
+	run func(e *env, args []string) error
+}
+
+// commands lists the subcommands in the order the help shows them. It is
+// filled in by init because runHelp refers back to it.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"list", "", "List all entries, or those in a folder, with a tag or of an age", runList},
+		{"tree", "[folder]", "Show the entries as a tree of folders", runTree},
+		{"show", "<entry>", "Show every field of an entry", runShow},
+		{"add", "", "Add an entry; the password is prompted for unless generated", runAdd},
+		{"edit", "<entry>", "Change fields of an entry, keeping its position", runEdit},
+		{"rm", "<entry>", "Delete an entry", runRm},
+		{"search", "<keyword>", "Search entries by keyword", runSearch},
+		{"copy", "<entry> [field]", "Copy a field of an entry to the clipboard (default pwd)", runCopy},
+		{"otp", "<entry>", "Print the current one-time password of an entry", runOTP},
+		{"import-otp", "<uri|file>", "Import 2FA keys from otpauth:// or otpauth-migration:// URIs", runImportOTP},
+		{"generate", "", "Print a random password", runGenerate},
+		{"passphrase", "", "Print a random passphrase", runPassphrase},
+		{"audit", "", "Report weak and reused passwords", runAudit},
+		{"breaches", "", "Report passwords found in a local Pwned Passwords hash file", runBreaches},
+		{"cp", "<entry> <profile>", "Copy an entry to another profile", runCp},
+		{"mv", "<entry> <profile>", "Move an entry to another profile", runMv},
+		{"passwd", "", "Change the master password", runPasswd},
+		{"rekey", "", "Re-encrypt the vault with new KDF parameters", runRekey},
+		{"migrate", "<backend>", "Convert the vault to another backend (sqlite)", runMigrate},
+		{"folder", "list | rename <folder> <name> | mv <folder> <path>", "List, rename or move folders with all their entries", runFolder},
+		{"profile", "list | create <name> | rename <old> <new> | remove <name>", "Manage profiles", runProfile},
+		{"config", "list | get <key> | set <key> <value>", "Show or change the configuration", runConfig},
+		{"help", "[command]", "Show help for a command", runHelp},
 	}
+}
 
-	// --- LIST COMMAND ---
-	if *listFlag {
-		entries, err := handling.All()
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
-		for _, e := range entries {
-			fmt.Println(e)
+// lookup returns the command called name.
+func lookup(name string) (command, bool) {
+	for _, c := range commands {
+		if c.name == name {
+			return c, true
 		}
+	}
+	return command{}, false
+}
+
+// errUsage is returned once a usage error has been reported, together
+// with the help of the command.
+var errUsage = errors.New("usage error")
+
+// Run is the main entry point for the CLI. It runs the subcommand named
+// on the command line and exits with status 1 if it fails, or 2 if it
+// was used incorrectly.
+func Run() {
+	// The background process clearing the clipboard has no arguments.
+	if clearClipboardLater() {
 		return
 	}
+	os.Exit(run(os.Args[1:]))
+}
 
-	// --- ADD COMMAND ---
-	if *addFlag {
-		// Validate required fields
-		if *website == "" || *username == "" || *email == "" || *password == "" {
-			fmt.Println("Missing fields for -add: --website --username --email --pwd")
-			os.Exit(1)
-		}
+// run runs the command line args and returns the exit status.
+func run(args []string) int {
+	args, warning, err := translateLegacy(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		usage()
+		return 2
+	}
+	if warning != "" {
+		fmt.Fprintln(os.Stderr, "Warning:", warning)
+	}
 
-		// Construct new entry
-		newEntry := handling.Act{
-			Website:  *website,
-			Username: *username,
-			Email:    *email,
-			Pwd:      *password,
-		}
+	if len(args) == 0 {
+		usage()
+		return 2
+	}
+	if args[0] == "-h" || args[0] == "-help" || args[0] == "--help" {
+		usage()
+		return 0
+	}
+	c, ok := lookup(args[0])
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Error: unknown command %q\n", args[0])
+		usage()
+		return 2
+	}
 
-		// Save the new entry
-		if err := handling.Create(newEntry); err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
+	e := &env{lockTimeout: storage.DefaultLockTimeout}
+	if e.cfg, err = loadConfig(); err != nil {
+		fmt.Println("Error:", err)
+		return 1
+	}
 
-		fmt.Println("Entry added successfully.")
-		return
+	err = c.run(e, args[1:])
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Println("Error:", err)
+		return 1
+	}
+}
+
+// usage prints the list of commands to stderr.
+func usage() {
+	w := os.Stderr
+	fmt.Fprintln(w, "Usage: pwdcli <command> [flags] [arguments]")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
 	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "An <entry> is its ID or a prefix of at least 4 characters, its website or")
+	fmt.Fprintln(w, "website/username, or its index in listings, which changes when entries are")
+	fmt.Fprintln(w, `deleted. Prefix a short ID with "id:" to avoid mistaking it for an index,`)
+	fmt.Fprintln(w, `and when it has no digit, to avoid mistaking it for a website.`)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, `Run "pwdcli help <command>" for the flags and arguments of a command.`)
+}
 
-	// --- DELETE COMMAND ---
-	if *deleteFlag >= 0 {
-		ok, err := handling.Delete(*deleteFlag)
-		if err != nil {
-			fmt.Println("Error:", err)
-			return
-		}
-		if ok {
-			fmt.Printf("Entry [%d] deleted.\n", *deleteFlag)
-		}
-		return
+// env holds the state shared by the commands of one run: the global
+// flags, the configuration and the settings it resolves to.
+type env struct {
+	// Global flags, accepted by every command.
+	vault       string
+	profile     string
+	format      string
+	lockTimeout time.Duration
+
+	cfg      config.Config
+	settings config.Resolved
+
+	// path is the location of the vault opened by open.
+	path string
+}
+
+// flagSet returns the flag set of the named command, with the global
+// flags and a usage text built from the command's description.
+func (e *env) flagSet(name string) *flag.FlagSet {
+	c, _ := lookup(name)
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.StringVar(&e.vault, "vault", e.vault, "Path of the vault file (overrides "+util.EnvVault+" and the config file)")
+	fs.StringVar(&e.profile, "profile", e.profile, "Use the vault of the named profile")
+	fs.StringVar(&e.format, "format", e.format, "Output format: text or json (overrides the config file)")
+	fs.DurationVar(&e.lockTimeout, "lock-timeout", e.lockTimeout, "How long to wait for another pwdcli process to release the vault")
+
+	fs.Usage = func() {
+		w := fs.Output()
+		fmt.Fprintf(w, "Usage: %s\n\n%s.\n\nFlags:\n", strings.TrimSpace("pwdcli "+c.name+" [flags] "+c.args), c.summary)
+		fs.PrintDefaults()
 	}
+	return fs
+}
 
-	// --- SEARCH COMMAND ---
-	if *searchFlag != "" {
-		matches, err := handling.Search(*searchFlag)
-		if err != nil {
-			fmt.Println("Error:", err)
+// parse parses the flags and arguments of a command, which may come in
+// any order until "--", and returns the arguments. Their number must be
+// between min and max, where a negative max means any number. The
+// settings of the selected profile are resolved once the flags are known.
+func (e *env) parse(fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	positional := []string{}
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, err
+			}
+			return nil, errUsage
 		}
 
-		// No results found
-		if len(matches) == 0 {
-			fmt.Println("No results found.")
-			return
+		// Parse stops at the first argument, or after "--" which ends
+		// the flags.
+		rest := fs.Args()
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			positional = append(positional, rest...)
+			break
 		}
+		if len(rest) == 0 {
+			break
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+
+	switch {
+	case len(positional) < min:
+		return nil, usageError(fs, "missing arguments")
+	case max >= 0 && len(positional) > max:
+		return nil, usageError(fs, "unexpected arguments: "+strings.Join(positional[max:], " "))
+	}
+
+	profileName := e.profile
+	if profileName == "" {
+		profileName = profile.Default
+	}
+	e.settings = e.cfg.Resolve(profileName)
+	if e.format != "" {
+		e.settings.Format = e.format
+	}
+	return positional, nil
+}
+
+// usageError reports a misuse of the command of fs, followed by its help.
+func usageError(fs *flag.FlagSet, msg string) error {
+	fmt.Fprintf(fs.Output(), "%s: %s\n", fs.Name(), msg)
+	fs.Usage()
+	return errUsage
+}
+
+// open resolves where the vault lives, asks for its master password and
+// creates the vault if it does not exist yet.
+func (e *env) open() (storage.Vault, error) {
+	if e.vault != "" && e.profile != "" {
+		return nil, errors.New("use either -vault or -profile, not both")
+	}
+	path, err := vaultPath(e.cfg, e.vault, e.profile)
+	if err != nil {
+		return nil, err
+	}
+	store := storage.Open(path, e.lockTimeout)
+	if fs, ok := store.(*storage.FileStore); ok {
+		fs.Backups = e.settings.BackupRetention
+	}
+
+	// Ask for the master password before touching storage.
+	if err := unlock(store); err != nil {
+		return nil, err
+	}
 
-		// Print matching entries
-		for _, m := range matches {
-			fmt.Printf(
-				"[%d] Website: %s Username: %s Email: %s Password: %s\n",
-				m.Index, m.Account.Website, m.Account.Username, m.Account.Email, m.Account.Pwd,
-			)
+	// Ensure the storage file exists.
+	// If it doesn't, it is automatically created.
+	if err := store.Create(); err != nil {
+		return nil, fmt.Errorf("creating the vault: %w", err)
+	}
+
+	// Entries saved by older versions get their IDs on first use.
+	if _, err := handling.AssignIDs(store); err != nil {
+		return nil, err
+	}
+	e.path = path
+	return store, nil
+}
+
+// isSet reports whether the named flag was given on the command line,
+// which tells an explicitly empty value apart from the default.
+func isSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
 		}
-		return
+	})
+	return set
+}
+
+// runHelp prints the list of commands, or the help of one command.
+func runHelp(e *env, args []string) error {
+	fs := e.flagSet("help")
+	args, err := e.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		usage()
+		return nil
 	}
 
-	// If no command was matched, print usage help.
-	flag.Usage()
+	c, ok := lookup(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+	// Every command prints its help when asked with -h.
+	return c.run(e, []string{"-h"})
+}
+
+// loadConfig reads the configuration file. A configuration file that
+// cannot be located, because there is no home directory, is skipped.
+func loadConfig() (config.Config, error) {
+	p, err := config.Path()
+	if err != nil {
+		return config.Config{}, nil
+	}
+	return config.Load(p)
+}
+
+// vaultPath resolves the vault location of the named profile or, without
+// a profile, from the -vault flag, the environment and the configuration
+// file.
+func vaultPath(cfg config.Config, flagValue, profileName string) (string, error) {
+	if profileName != "" && profileName != profile.Default {
+		if !profile.Exists(profileName, cfg) {
+			return "", fmt.Errorf("%w: %s (create it with \"pwdcli profile create\")", profile.ErrNotFound, profileName)
+		}
+		return profile.VaultPath(profileName, cfg)
+	}
+	return util.VaultPath(flagValue, cfg.Vault)
 }
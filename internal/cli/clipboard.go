@@ -0,0 +1,193 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/clipboard"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+	"golang.org/x/term"
+)
+
+// Environment variables that turn pwdcli into the background process
+// clearing the clipboard. The digest of the copied value is passed on
+// standard input instead, where other processes cannot see it.
+const (
+	envClipboardProvider = "PWDCLI_CLIPBOARD_PROVIDER"
+	envClipboardTimeout  = "PWDCLI_CLIPBOARD_TIMEOUT"
+)
+
+// copyFields are the built-in fields the copy command accepts, besides
+// the custom fields of the entry.
+var copyFields = []string{"pwd", "username", "email", "website", "otp", "notes"}
+
+// runCopy copies a field of an entry to the clipboard.
+func runCopy(e *env, args []string) error {
+	fs := e.flagSet("copy")
+	args, err := e.parse(fs, args, 1, 2)
+	if err != nil {
+		return err
+	}
+	field := "pwd"
+	if len(args) > 1 {
+		field = args[1]
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	entry, err := handling.Select(store, args[0])
+	if err != nil {
+		return err
+	}
+	value, err := entryField(store, entry, field)
+	if err != nil {
+		return err
+	}
+	timeout := e.settings.ClipboardTimeout
+	if err := copyToClipboard(value, timeout); err != nil {
+		return err
+	}
+	if secretField(entry, field) {
+		if err := handling.TouchBy(store, entry.Selector()); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Copied %s of entry %s to the clipboard", field, entryName(entry))
+	if timeout > 0 {
+		fmt.Printf("; it will be cleared in %s", timeout)
+	}
+	fmt.Println(".")
+	return nil
+}
+
+// secretField reports whether the named field of an entry is a secret,
+// whose copying marks the entry as accessed: the password or a concealed
+// custom field. The one-time password is left out because handling.Code
+// records the access itself.
+func secretField(e handling.Entry, field string) bool {
+	switch field {
+	case "pwd", "password":
+		return true
+	case "otp", "username", "email", "website", "notes":
+		return false
+	}
+	f, ok := e.Account.Field(field)
+	return ok && f.Concealed
+}
+
+// entryField returns the named field of an entry, which may also be one
+// of its custom fields. The otp field is the entry's current one-time
+// password.
+func entryField(store storage.Store, e handling.Entry, field string) (string, error) {
+	switch field {
+	case "otp":
+		code, _, err := handling.CodeBy(store, e.Selector(), time.Now())
+		return code, err
+	case "pwd", "password":
+		return e.Account.Pwd, nil
+	case "username":
+		return e.Account.Username, nil
+	case "email":
+		return e.Account.Email, nil
+	case "website":
+		return e.Account.Website, nil
+	case "notes":
+		return e.Account.Notes, nil
+	}
+	if f, ok := e.Account.Field(field); ok {
+		return f.Value, nil
+	}
+	return "", fmt.Errorf("unknown field %q: use one of %s or a custom field", field, strings.Join(copyFields, ", "))
+}
+
+// terminal returns the terminal for OSC 52 sequences, or nil if standard
+// error is not one.
+func terminal() io.Writer {
+	if term.IsTerminal(int(os.Stderr.Fd())) {
+		return os.Stderr
+	}
+	return nil
+}
+
+// copyToClipboard puts value on the clipboard and, unless timeout is
+// zero, starts a background pwdcli process that clears it after timeout.
+func copyToClipboard(value string, timeout time.Duration) error {
+	p, err := clipboard.Detect(terminal())
+	if err != nil {
+		return err
+	}
+	if err := p.Copy(value); err != nil {
+		return err
+	}
+	if timeout <= 0 {
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cmd := exec.Command(exe)
+	cmd.Env = append(os.Environ(),
+		envClipboardProvider+"="+p.Name(),
+		envClipboardTimeout+"="+timeout.String())
+	if p.Name() == "osc52" {
+		cmd.Stderr = os.Stderr
+	}
+	detach(cmd)
+
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	fmt.Fprintln(in, clipboard.Digest(value))
+	in.Close()
+	return cmd.Process.Release()
+}
+
+// clearClipboardLater runs the background process started by
+// copyToClipboard, if this is one, and reports whether it was.
+func clearClipboardLater() bool {
+	name := os.Getenv(envClipboardProvider)
+	if name == "" {
+		return false
+	}
+
+	timeout, err := time.ParseDuration(os.Getenv(envClipboardTimeout))
+	if err != nil {
+		return true
+	}
+	digest, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return true
+	}
+
+	var tty io.Writer
+	if name == "osc52" {
+		tty = os.Stderr
+	}
+	p, err := clipboard.Lookup(name, tty)
+	if err != nil {
+		return true
+	}
+
+	time.Sleep(timeout)
+	clipboard.Clear(p, strings.TrimSpace(digest))
+	return true
+}
@@ -0,0 +1,78 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+)
+
+// runConfig runs one of the configuration actions: get prints the
+// effective value of a key, set stores a value (an empty value unsets the
+// key) and list prints every setting.
+func runConfig(e *env, args []string) error {
+	fs := e.flagSet("config")
+	args, err := e.parse(fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	action, args := args[0], args[1:]
+	cfg := e.cfg
+
+	switch {
+	// --- CONFIG LIST ---
+	case action == "list" && len(args) == 0:
+		for _, s := range cfg.List() {
+			if s.Default {
+				fmt.Printf("%s=%s (default)\n", s.Key, s.Value)
+			} else {
+				fmt.Printf("%s=%s\n", s.Key, s.Value)
+			}
+		}
+		return nil
+
+	// --- CONFIG GET ---
+	case action == "get" && len(args) == 1:
+		value, err := cfg.Get(args[0])
+		if err != nil {
+			return err
+		}
+		fmt.Println(value)
+		return nil
+
+	// --- CONFIG SET ---
+	case action == "set" && len(args) > 0:
+		// The value may follow the key or be joined to it with "=".
+		key, value, ok := strings.Cut(args[0], "=")
+		switch {
+		case len(args) == 2 && !ok:
+			value = args[1]
+		case len(args) != 1 || !ok:
+			return usageError(fs, "set expects a key and a value")
+		}
+		key = strings.TrimSpace(key)
+		if err := cfg.Set(key, strings.TrimSpace(value)); err != nil {
+			return err
+		}
+
+		path, err := config.Path()
+		if err != nil {
+			return err
+		}
+		if err := config.Save(path, cfg); err != nil {
+			return err
+		}
+
+		fmt.Printf("%s saved to %s.\n", key, path)
+		return nil
+
+	case action == "list" || action == "get":
+		return usageError(fs, "wrong number of arguments for "+action)
+	default:
+		return usageError(fs, fmt.Sprintf("unknown action %q", action))
+	}
+}
@@ -0,0 +1,12 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix
+
+package cli
+
+import "os/exec"
+
+// detach does nothing: the child process already outlives pwdcli.
+func detach(cmd *exec.Cmd) {}
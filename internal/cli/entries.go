@@ -0,0 +1,396 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/otp"
+)
+
+// runList prints every entry, or those in a folder, with given tags or
+// whose password or last access is older than an age, in the chosen order.
+func runList(e *env, args []string) error {
+	fs := e.flagSet("list")
+	var filter handling.Filter
+	fs.StringVar(&filter.Folder, "folder", "", "Only list the entries in the folder at `path` and its subfolders")
+	var tags tagList
+	fs.Var(&tags, "tag", "Only list the entries with the tag; repeat or separate with commas to require more tags")
+	var olderThan, unusedFor ageValue
+	fs.Var(&olderThan, "older-than", "Only list the entries whose password is older than `age`, as in 180d, or of unknown age")
+	fs.Var(&unusedFor, "unused-for", "Only list the entries not accessed for `age`, as in 90d")
+	sortKey := fs.String("sort", "index", "Sort by `key`, one of "+strings.Join(handling.SortKeys, ", ")+"; times sort oldest first")
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	if !slices.Contains(handling.SortKeys, *sortKey) {
+		return usageError(fs, fmt.Sprintf("unknown sort key %q", *sortKey))
+	}
+	filter.Tags = tags
+	now := time.Now()
+	if isSet(fs, "older-than") {
+		filter.ChangedBefore = now.Add(-time.Duration(olderThan))
+	}
+	if isSet(fs, "unused-for") {
+		filter.AccessedBefore = now.Add(-time.Duration(unusedFor))
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	entries, err := handling.List(store)
+	if err != nil {
+		return err
+	}
+	entries = filter.Apply(entries)
+	if err := handling.SortEntries(entries, *sortKey); err != nil {
+		return err
+	}
+	return printShown(store, entries, e.settings.Format, e.settings.Mask)
+}
+
+// runShow prints every field of one entry. The entry was asked for by
+// name, so its secrets are shown even when listings are masked, and it is
+// marked as accessed; the access time printed is the previous one.
+func runShow(e *env, args []string) error {
+	fs := e.flagSet("show")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	entry, err := handling.Select(store, args[0])
+	if err != nil {
+		return err
+	}
+	if err := printEntry(entry, e.settings.Format); err != nil {
+		return err
+	}
+	return handling.TouchBy(store, entry.Selector())
+}
+
+// runAdd adds an entry. The password is generated with -generate or
+// -passphrase, or prompted for unless -pwd gives it.
+func runAdd(e *env, args []string) error {
+	fs := e.flagSet("add")
+	website := fs.String("website", "", "Website (required)")
+	username := fs.String("username", "", "Username (required)")
+	email := fs.String("email", "", "Email (required)")
+	password := fs.String("pwd", "", "Password; prompted for when omitted or - (avoid: it shows in the shell history and process list)")
+	otpURI := fs.String("otp-uri", "", "otpauth:// URI of the two-factor authentication key")
+	generateFlag := fs.Bool("generate", false, "Use a random password")
+	passphraseFlag := fs.Bool("passphrase", false, "Use a random passphrase")
+	hibpFile := fs.String("hibp-file", "", "Pwned Passwords hash file to check the password against (overrides the config file)")
+	var g generatorFlags
+	g.register(fs)
+	var p passphraseFlags
+	p.register(fs)
+	var ff fieldFlags
+	ff.register(fs)
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	// Validate the flags before asking for any password.
+	if *website == "" || *username == "" || *email == "" {
+		return usageError(fs, "-website, -username and -email are required")
+	}
+	if *generateFlag && *passphraseFlag {
+		return usageError(fs, "use either -generate or -passphrase, not both")
+	}
+	if (*generateFlag || *passphraseFlag) && *password != "" {
+		return usageError(fs, "use either -pwd or a generated password, not both")
+	}
+	newEntry := handling.Act{
+		Website:  *website,
+		Username: *username,
+		Email:    *email,
+		Pwd:      *password,
+		OTP:      strings.TrimSpace(*otpURI),
+	}
+	if newEntry.OTP != "" {
+		if _, err := otp.Parse(newEntry.OTP); err != nil {
+			return err
+		}
+	}
+	notes, err := ff.readNotes()
+	if err != nil {
+		return err
+	}
+	newEntry.Notes = notes
+
+	// A generated password is shown once the entry is saved.
+	switch {
+	case *generateFlag:
+		newEntry.Pwd, err = g.generate(e.settings.Generator)
+	case *passphraseFlag:
+		newEntry.Pwd, err = p.generate(e.settings.Passphrase)
+	}
+	if err != nil {
+		return err
+	}
+
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	// Ask for the password rather than taking it from the command line,
+	// where it would end up in the shell history.
+	if newEntry.Pwd == "" || newEntry.Pwd == "-" {
+		if newEntry.Pwd, err = entryPassword(newEntry.Website); err != nil {
+			return err
+		}
+		if newEntry.Pwd == "" {
+			return errors.New("the password must not be empty")
+		}
+	}
+
+	// Fields repeated with the same name keep the last value.
+	if err := ff.promptConcealed(); err != nil {
+		return err
+	}
+	newEntry, _ = ff.edit().Apply(newEntry)
+
+	// Warn about, or refuse, a weak or breached password.
+	if err := checkStrength(newEntry, e.settings.Strength); err != nil {
+		return err
+	}
+	breaches := e.settings.Breach
+	if *hibpFile != "" {
+		breaches.File, breaches.CheckOnAdd = *hibpFile, true
+	}
+	if breaches.CheckOnAdd {
+		if err := checkBreached(newEntry.Pwd, breaches.File, e.settings.Strength.Strict); err != nil {
+			return err
+		}
+	}
+
+	// Save the new entry
+	if err := handling.Create(store, newEntry); err != nil {
+		return err
+	}
+
+	fmt.Println("Entry added successfully.")
+	if *generateFlag || *passphraseFlag {
+		fmt.Println("Generated password:", newEntry.Pwd)
+	}
+	return nil
+}
+
+// runEdit changes the fields of an entry given by flags. A new password
+// is generated with -generate or -passphrase, or prompted for with -pwd -.
+func runEdit(e *env, args []string) error {
+	fs := e.flagSet("edit")
+	website := fs.String("website", "", "New website")
+	username := fs.String("username", "", "New username")
+	email := fs.String("email", "", "New email")
+	password := fs.String("pwd", "", "New password, or - to be prompted for it (a literal value shows in the shell history and process list)")
+	otpURI := fs.String("otp-uri", "", "New otpauth:// URI of the two-factor authentication key; empty removes the key")
+	generateFlag := fs.Bool("generate", false, "Use a new random password")
+	passphraseFlag := fs.Bool("passphrase", false, "Use a new random passphrase")
+	hibpFile := fs.String("hibp-file", "", "Pwned Passwords hash file to check a new password against (overrides the config file)")
+	var removeFields stringList
+	fs.Var(&removeFields, "remove-field", "Remove the custom field called `name`; repeat for more fields")
+	var untag tagList
+	fs.Var(&untag, "untag", "Remove a tag; repeat or separate with commas for more tags")
+	var g generatorFlags
+	g.register(fs)
+	var p passphraseFlags
+	p.register(fs)
+	var ff fieldFlags
+	ff.register(fs)
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	// Validate the flags before asking for any password. Only the OTP key,
+	// the notes and custom fields may be cleared; the other fields keep a
+	// value.
+	edit := ff.edit()
+	edit.RemoveFields, edit.RemoveTags = removeFields, untag
+	for _, f := range []struct {
+		name  string
+		value *string
+		field **string
+	}{
+		{"website", website, &edit.Website},
+		{"username", username, &edit.Username},
+		{"email", email, &edit.Email},
+		{"pwd", password, &edit.Pwd},
+	} {
+		if !isSet(fs, f.name) {
+			continue
+		}
+		if *f.value == "" {
+			return usageError(fs, "-"+f.name+" must not be empty")
+		}
+		*f.field = f.value
+	}
+	if isSet(fs, "otp-uri") {
+		uri := strings.TrimSpace(*otpURI)
+		if uri != "" {
+			if _, err := otp.Parse(uri); err != nil {
+				return err
+			}
+		}
+		edit.OTP = &uri
+	}
+	if ff.notesSet() {
+		notes, err := ff.readNotes()
+		if err != nil {
+			return err
+		}
+		edit.Notes = &notes
+	}
+	if *generateFlag && *passphraseFlag {
+		return usageError(fs, "use either -generate or -passphrase, not both")
+	}
+	if (*generateFlag || *passphraseFlag) && edit.Pwd != nil {
+		return usageError(fs, "use either -pwd or a generated password, not both")
+	}
+	if edit.IsZero() && !*generateFlag && !*passphraseFlag {
+		return usageError(fs, "nothing to change")
+	}
+
+	// A generated password is shown once the entry is saved.
+	var generated string
+	switch {
+	case *generateFlag:
+		generated, err = g.generate(e.settings.Generator)
+	case *passphraseFlag:
+		generated, err = p.generate(e.settings.Passphrase)
+	}
+	if err != nil {
+		return err
+	}
+	if generated != "" {
+		edit.Pwd = &generated
+	}
+
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+	current, err := handling.Select(store, args[0])
+	if err != nil {
+		return err
+	}
+	if err := ff.promptConcealed(); err != nil {
+		return err
+	}
+
+	if edit.Pwd != nil {
+		// Ask for the password rather than taking it from the command
+		// line, where it would end up in the shell history.
+		if *edit.Pwd == "-" {
+			pwd, err := entryPassword(current.Account.Website)
+			if err != nil {
+				return err
+			}
+			if pwd == "" {
+				return errors.New("the password must not be empty")
+			}
+			edit.Pwd = &pwd
+		}
+
+		// Warn about, or refuse, a weak or breached password. The
+		// strength is judged against the fields as they will be.
+		act, _ := edit.Apply(current.Account)
+		if err := checkStrength(act, e.settings.Strength); err != nil {
+			return err
+		}
+		breaches := e.settings.Breach
+		if *hibpFile != "" {
+			breaches.File, breaches.CheckOnAdd = *hibpFile, true
+		}
+		if breaches.CheckOnAdd {
+			if err := checkBreached(act.Pwd, breaches.File, e.settings.Strength.Strict); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Update the entry by its ID, which still names it if others were
+	// added or deleted while the password was prompted for.
+	updated, changed, err := handling.UpdateBy(store, current.Selector(), edit)
+	if err != nil {
+		return err
+	}
+
+	if len(changed) == 0 {
+		fmt.Printf("Entry %s unchanged.\n", entryName(updated))
+		return nil
+	}
+	fmt.Printf("Entry %s updated: %s.\n", entryName(updated), strings.Join(changed, ", "))
+	if generated != "" {
+		fmt.Println("Generated password:", generated)
+	}
+	return nil
+}
+
+// runRm deletes an entry.
+func runRm(e *env, args []string) error {
+	fs := e.flagSet("rm")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	// The entry is looked up and deleted in one step, so that another
+	// process cannot make the selector name a different entry in between.
+	entry, err := handling.DeleteBy(store, args[0])
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Entry %s deleted.\n", entryName(entry))
+	return nil
+}
+
+// runSearch prints the entries matching a keyword.
+func runSearch(e *env, args []string) error {
+	fs := e.flagSet("search")
+	concealed := fs.Bool("concealed", false, "Also search the concealed custom fields")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	search := handling.Search
+	if *concealed {
+		search = handling.SearchConcealed
+	}
+	matches, err := search(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	// No results found
+	if len(matches) == 0 && e.settings.Format != config.FormatJSON {
+		fmt.Println("No results found.")
+		return nil
+	}
+	return printShown(store, matches, e.settings.Format, e.settings.Mask)
+}
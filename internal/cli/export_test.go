@@ -0,0 +1,8 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+// TranslateLegacy exposes translateLegacy to the external tests.
+var TranslateLegacy = translateLegacy
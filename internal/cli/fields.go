@@ -0,0 +1,151 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/prompt"
+	"github.com/nullzeiger/pwdcli/internal/util"
+)
+
+// fieldFlags collects the optional fields shared by add and edit: the
+// custom fields given with the repeatable -field and -concealed-field
+// flags, the notes given with -notes or -notes-file, the folder and the
+// tags.
+type fieldFlags struct {
+	fields    []account.Field
+	notes     string
+	notesFile string
+	folder    string
+	tags      tagList
+	fs        *flag.FlagSet
+}
+
+// fieldValue is the flag.Value of -field and -concealed-field.
+type fieldValue struct {
+	ff        *fieldFlags
+	concealed bool
+}
+
+func (v fieldValue) String() string { return "" }
+
+// Set adds a field given as name=value.
+func (v fieldValue) Set(s string) error {
+	name, value, ok := strings.Cut(s, "=")
+	name = strings.TrimSpace(name)
+	if !ok || name == "" {
+		return errors.New("expected name=value")
+	}
+	v.ff.fields = append(v.ff.fields, account.Field{Name: name, Value: value, Concealed: v.concealed})
+	return nil
+}
+
+// register defines the custom field, notes, folder and tag flags on fs.
+func (ff *fieldFlags) register(fs *flag.FlagSet) {
+	ff.fs = fs
+	fs.Var(fieldValue{ff, false}, "field", "Custom field as `name=value`; repeat for more fields")
+	fs.Var(fieldValue{ff, true}, "concealed-field", "Concealed custom field as `name=value`, masked in listings; a value of - prompts for it")
+	fs.StringVar(&ff.notes, "notes", "", "Free-form notes")
+	fs.StringVar(&ff.notesFile, "notes-file", "", "Read the notes from a file")
+	fs.StringVar(&ff.folder, "folder", "", "Folder `path`, as in work/aws/prod")
+	fs.Var(&ff.tags, "tag", "Add a tag; repeat or separate with commas for more tags")
+}
+
+// edit returns the edit setting the collected custom fields, folder and
+// tags.
+func (ff *fieldFlags) edit() handling.Edit {
+	ed := handling.Edit{SetFields: ff.fields, AddTags: ff.tags}
+	if isSet(ff.fs, "folder") {
+		ed.Folder = &ff.folder
+	}
+	return ed
+}
+
+// notesSet reports whether notes were given, possibly empty.
+func (ff *fieldFlags) notesSet() bool {
+	return isSet(ff.fs, "notes") || isSet(ff.fs, "notes-file")
+}
+
+// readNotes returns the notes given with -notes or read from -notes-file.
+func (ff *fieldFlags) readNotes() (string, error) {
+	if isSet(ff.fs, "notes") && isSet(ff.fs, "notes-file") {
+		return "", usageError(ff.fs, "use either -notes or -notes-file, not both")
+	}
+	if !isSet(ff.fs, "notes-file") {
+		return ff.notes, nil
+	}
+	data, err := os.ReadFile(ff.notesFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\n"), nil
+}
+
+// promptConcealed asks for the values of the concealed fields given as
+// "-", which keeps them out of the shell history.
+func (ff *fieldFlags) promptConcealed() error {
+	for i, f := range ff.fields {
+		if !f.Concealed || f.Value != "-" {
+			continue
+		}
+		value, err := prompt.Default.Password(fmt.Sprintf("Value of %s: ", f.Name))
+		if err != nil {
+			return err
+		}
+		ff.fields[i].Value = string(value)
+	}
+	return nil
+}
+
+// stringList is a repeatable flag collecting its values.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+// Set adds a value.
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
+}
+
+// tagList is a repeatable flag collecting tags, which may also be given
+// separated by commas.
+type tagList []string
+
+func (l *tagList) String() string { return strings.Join(*l, ",") }
+
+// Set adds the tags in s.
+func (l *tagList) Set(s string) error {
+	for t := range strings.SplitSeq(s, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			*l = append(*l, t)
+		}
+	}
+	return nil
+}
+
+// ageValue is a flag holding an age such as 180d, as parsed by
+// util.ParseAge.
+type ageValue time.Duration
+
+func (a *ageValue) String() string { return time.Duration(*a).String() }
+
+// Set parses the age in s.
+func (a *ageValue) Set(s string) error {
+	d, err := util.ParseAge(s)
+	if err != nil {
+		return err
+	}
+	*a = ageValue(d)
+	return nil
+}
@@ -0,0 +1,186 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/config"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+)
+
+// runTree prints the entries as a tree of folders, from the top level or
+// from the given folder.
+func runTree(e *env, args []string) error {
+	fs := e.flagSet("tree")
+	var tags tagList
+	fs.Var(&tags, "tag", "Only show the entries with the tag; repeat or separate with commas to require more tags")
+	args, err := e.parse(fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	filter := handling.Filter{Tags: tags}
+	if len(args) > 0 {
+		filter.Folder = args[0]
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	entries, err := handling.List(store)
+	if err != nil {
+		return err
+	}
+	root, ok := handling.Tree(filter.Apply(entries)).Lookup(filter.Folder)
+	if !ok {
+		return fmt.Errorf("%w: %s", handling.ErrFolderNotFound, account.CleanFolder(filter.Folder))
+	}
+	return printTree(root, e.settings.Format)
+}
+
+// runFolder runs one of the folder actions: list, rename or mv.
+func runFolder(e *env, args []string) error {
+	fs := e.flagSet("folder")
+	args, err := e.parse(fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	action, args := args[0], args[1:]
+
+	want := map[string]int{"list": 0, "rename": 2, "mv": 2}
+	n, ok := want[action]
+	if !ok {
+		return usageError(fs, fmt.Sprintf("unknown action %q", action))
+	}
+	if len(args) != n {
+		return usageError(fs, fmt.Sprintf("%s takes %d arguments", action, n))
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	if action == "list" {
+		entries, err := handling.List(store)
+		if err != nil {
+			return err
+		}
+		listFolders(handling.Tree(entries))
+		return nil
+	}
+
+	// Renaming keeps the folder in its parent.
+	from, to := account.CleanFolder(args[0]), account.CleanFolder(args[1])
+	if action == "rename" {
+		if to == "" || path.Base(to) != to {
+			return usageError(fs, "the new name must not contain a slash")
+		}
+		if parent := path.Dir(from); parent != "." {
+			to = parent + "/" + to
+		}
+	}
+	moved, err := handling.MoveFolder(store, from, to)
+	if err != nil {
+		return fmt.Errorf("%w: %s", err, from)
+	}
+	if to == "" {
+		to = "the top level"
+	}
+	fmt.Printf("Folder %s moved to %s (%d entries).\n", from, to, moved)
+	return nil
+}
+
+// listFolders prints the path of every folder below f with the number of
+// entries in it and its subfolders.
+func listFolders(f *handling.Folder) {
+	for _, c := range f.Folders {
+		fmt.Printf("%s\t%d\n", c.Path, c.Count())
+		listFolders(c)
+	}
+}
+
+// jsonFolder is the JSON form of a folder in the tree. Its entries leave
+// out passwords and other secrets.
+type jsonFolder struct {
+	Name    string          `json:"name"`
+	Path    string          `json:"path"`
+	Entries []jsonTreeEntry `json:"entries,omitempty"`
+	Folders []jsonFolder    `json:"folders,omitempty"`
+}
+
+// jsonTreeEntry is the JSON form of an entry in the tree.
+type jsonTreeEntry struct {
+	Index    int      `json:"index"`
+	ID       string   `json:"id,omitempty"`
+	Website  string   `json:"website"`
+	Username string   `json:"username"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// toJSON converts the folder and its subfolders to their JSON form.
+func toJSON(f *handling.Folder) jsonFolder {
+	out := jsonFolder{Name: f.Name, Path: f.Path}
+	for _, e := range f.Entries {
+		out.Entries = append(out.Entries, jsonTreeEntry{
+			Index:    e.Index,
+			ID:       e.Account.ID,
+			Website:  e.Account.Website,
+			Username: e.Account.Username,
+			Tags:     e.Account.Tags,
+		})
+	}
+	for _, c := range f.Folders {
+		out.Folders = append(out.Folders, toJSON(c))
+	}
+	return out
+}
+
+// printTree writes the folder tree below root to standard output in the
+// given format. Entries show their website, username and short ID, never
+// their secrets.
+func printTree(root *handling.Folder, format string) error {
+	switch format {
+	case config.FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(toJSON(root))
+	case config.FormatText, "":
+		name := root.Path
+		if name == "" {
+			name = "."
+		}
+		fmt.Println(name)
+		printBranch(root, "")
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// printBranch prints the subfolders and then the entries of f, each line
+// starting with prefix and the branch drawing.
+func printBranch(f *handling.Folder, prefix string) {
+	n := len(f.Folders) + len(f.Entries)
+	item := func(i int, line string) string {
+		if i == n-1 {
+			fmt.Println(prefix + "└── " + line)
+			return prefix + "    "
+		}
+		fmt.Println(prefix + "├── " + line)
+		return prefix + "│   "
+	}
+
+	for i, c := range f.Folders {
+		printBranch(c, item(i, c.Name+"/"))
+	}
+	for i, e := range f.Entries {
+		item(len(f.Folders)+i, fmt.Sprintf("%s (%s) [%s]", e.Account.Website, e.Account.Username, e.ShortID()))
+	}
+}
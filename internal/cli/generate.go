@@ -0,0 +1,192 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+	"github.com/nullzeiger/pwdcli/internal/generate"
+)
+
+// generatorPolicy builds the generator policy from the configured settings
+// and the command-line overrides: a positive length, a comma-separated
+// list of character classes, a custom alphabet and the exclusion of
+// ambiguous characters. Empty overrides keep the configured values.
+func generatorPolicy(g config.ResolvedGenerator, length int, classes, alphabet string, excludeAmbiguous bool) (generate.Policy, error) {
+	p := generate.Policy{
+		Length:           g.Length,
+		Upper:            g.Upper,
+		Lower:            g.Lower,
+		Digits:           g.Digits,
+		Symbols:          g.Symbols,
+		ExcludeAmbiguous: g.ExcludeAmbiguous || excludeAmbiguous,
+		MinUpper:         g.MinUpper,
+		MinLower:         g.MinLower,
+		MinDigits:        g.MinDigits,
+		MinSymbols:       g.MinSymbols,
+		Alphabet:         g.Alphabet,
+	}
+	if length > 0 {
+		p.Length = length
+	}
+	if alphabet != "" {
+		p.Alphabet = alphabet
+	}
+
+	if classes != "" {
+		p.Upper, p.Lower, p.Digits, p.Symbols = false, false, false, false
+		for _, c := range strings.Split(classes, ",") {
+			switch strings.TrimSpace(c) {
+			case "upper":
+				p.Upper = true
+			case "lower":
+				p.Lower = true
+			case "digits":
+				p.Digits = true
+			case "symbols":
+				p.Symbols = true
+			default:
+				return p, fmt.Errorf("unknown character class %q (use upper, lower, digits, symbols)", c)
+			}
+		}
+
+		// Minimums of classes left out on the command line no longer apply.
+		if !p.Upper {
+			p.MinUpper = 0
+		}
+		if !p.Lower {
+			p.MinLower = 0
+		}
+		if !p.Digits {
+			p.MinDigits = 0
+		}
+		if !p.Symbols {
+			p.MinSymbols = 0
+		}
+	}
+	return p, nil
+}
+
+// passphrasePolicy builds the passphrase policy from the configured
+// settings and the command-line overrides: a positive number of words, a
+// separator if the -separator flag was given, and a capitalization mode.
+func passphrasePolicy(pp config.ResolvedPassphrase, words int, separator *string, capitalize string) generate.PassphrasePolicy {
+	p := generate.PassphrasePolicy{
+		Words:      pp.Words,
+		Separator:  pp.Separator,
+		Capitalize: generate.Capitalization(pp.Capitalize),
+		Digit:      pp.Digit,
+		Symbol:     pp.Symbol,
+	}
+	if words > 0 {
+		p.Words = words
+	}
+	if separator != nil {
+		p.Separator = *separator
+	}
+	if capitalize != "" {
+		p.Capitalize = generate.Capitalization(capitalize)
+	}
+	return p
+}
+
+// generatorFlags are the flags that override the configured password
+// generator.
+type generatorFlags struct {
+	length           int
+	charset          string
+	alphabet         string
+	excludeAmbiguous bool
+}
+
+// register defines the flags on fs.
+func (g *generatorFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&g.length, "length", 0, "Length of generated passwords (overrides the config file)")
+	fs.StringVar(&g.charset, "charset", "", "Character classes of generated passwords: upper,lower,digits,symbols")
+	fs.StringVar(&g.alphabet, "alphabet", "", "Custom alphabet for generated passwords")
+	fs.BoolVar(&g.excludeAmbiguous, "exclude-ambiguous", false, "Leave easily confused characters out of generated passwords")
+}
+
+// generate returns a random password following the configured policy
+// and the flags.
+func (g *generatorFlags) generate(settings config.ResolvedGenerator) (string, error) {
+	policy, err := generatorPolicy(settings, g.length, g.charset, g.alphabet, g.excludeAmbiguous)
+	if err != nil {
+		return "", err
+	}
+	return generate.Password(policy)
+}
+
+// passphraseFlags are the flags that override the configured passphrase
+// generator.
+type passphraseFlags struct {
+	words      int
+	separator  string
+	capitalize string
+
+	fs *flag.FlagSet
+}
+
+// register defines the flags on fs.
+func (p *passphraseFlags) register(fs *flag.FlagSet) {
+	fs.IntVar(&p.words, "words", 0, "Number of words of generated passphrases (overrides the config file)")
+	fs.StringVar(&p.separator, "separator", "", "Separator between the words of generated passphrases")
+	fs.StringVar(&p.capitalize, "capitalize", "", "Capitalization of passphrase words: none, first, all or random")
+	p.fs = fs
+}
+
+// generate returns a random passphrase following the configured policy
+// and the flags. Its entropy is reported on stderr so that stdout holds
+// only the passphrase.
+func (p *passphraseFlags) generate(settings config.ResolvedPassphrase) (string, error) {
+	var separator *string
+	if isSet(p.fs, "separator") {
+		separator = &p.separator
+	}
+	phrase, entropy, err := generate.Passphrase(passphrasePolicy(settings, p.words, separator, p.capitalize))
+	if err != nil {
+		return "", err
+	}
+	fmt.Fprintf(os.Stderr, "Entropy: %.1f bits\n", entropy)
+	return phrase, nil
+}
+
+// runGenerate prints a random password.
+func runGenerate(e *env, args []string) error {
+	fs := e.flagSet("generate")
+	var g generatorFlags
+	g.register(fs)
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	pwd, err := g.generate(e.settings.Generator)
+	if err != nil {
+		return err
+	}
+	fmt.Println(pwd)
+	return nil
+}
+
+// runPassphrase prints a random passphrase.
+func runPassphrase(e *env, args []string) error {
+	fs := e.flagSet("passphrase")
+	var p passphraseFlags
+	p.register(fs)
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+
+	phrase, err := p.generate(e.settings.Passphrase)
+	if err != nil {
+		return err
+	}
+	fmt.Println(phrase)
+	return nil
+}
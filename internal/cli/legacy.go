@@ -0,0 +1,168 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// legacyCommand maps a command flag of the single flag set used by earlier
+// versions, as in "pwdcli -delete 3", to its subcommand.
+type legacyCommand struct {
+	// flag is the name of the legacy flag.
+	flag string
+
+	// command is the subcommand, with the action of the profile and
+	// config commands.
+	command []string
+
+	// arg reports whether the value of the flag is the first argument of
+	// the subcommand. Boolean flags have none.
+	arg bool
+
+	// extra is the flag whose value, when given, becomes the next
+	// argument, as -field does for -copy.
+	extra string
+}
+
+// legacyCommands lists the command flags of earlier versions.
+var legacyCommands = []legacyCommand{
+	{flag: "all", command: []string{"list"}},
+	{flag: "add", command: []string{"add"}},
+	{flag: "delete", command: []string{"rm"}, arg: true},
+	{flag: "search", command: []string{"search"}, arg: true},
+	{flag: "copy", command: []string{"copy"}, arg: true, extra: "field"},
+	{flag: "otp", command: []string{"otp"}, arg: true},
+	{flag: "import-otp", command: []string{"import-otp"}, arg: true},
+	{flag: "generate", command: []string{"generate"}},
+	{flag: "passphrase", command: []string{"passphrase"}},
+	{flag: "audit", command: []string{"audit"}},
+	{flag: "breaches", command: []string{"breaches"}},
+	{flag: "passwd", command: []string{"passwd"}},
+	{flag: "rekey", command: []string{"rekey"}},
+	{flag: "migrate", command: []string{"migrate"}, arg: true},
+	{flag: "cp", command: []string{"cp"}, arg: true, extra: "to"},
+	{flag: "mv", command: []string{"mv"}, arg: true, extra: "to"},
+	{flag: "list-profiles", command: []string{"profile", "list"}},
+	{flag: "create-profile", command: []string{"profile", "create"}, arg: true},
+	{flag: "rename-profile", command: []string{"profile", "rename"}, arg: true, extra: "to"},
+	{flag: "remove-profile", command: []string{"profile", "remove"}, arg: true},
+	{flag: "config-list", command: []string{"config", "list"}},
+	{flag: "config-get", command: []string{"config", "get"}, arg: true},
+	{flag: "config-set", command: []string{"config", "set"}, arg: true},
+}
+
+// legacyBoolFlags are the legacy flags that take no value.
+var legacyBoolFlags = []string{
+	"all", "add", "audit", "breaches", "passwd", "rekey", "generate",
+	"exclude-ambiguous", "passphrase", "list-profiles", "config-list", "dry-run",
+}
+
+// legacyValueFlags are the legacy flags that take a value.
+var legacyValueFlags = []string{
+	"delete", "search", "copy", "field", "otp", "resync", "window",
+	"hibp-file", "import-otp", "kdf-time", "kdf-memory", "kdf-threads",
+	"migrate", "website", "username", "email", "pwd", "otp-uri", "length",
+	"charset", "alphabet", "words", "separator", "capitalize", "vault",
+	"profile", "create-profile", "rename-profile", "remove-profile", "cp",
+	"mv", "to", "format", "config-get", "config-set", "lock-timeout",
+}
+
+// legacyValue records the value of a legacy flag as it was given, leaving
+// its validation to the subcommand.
+type legacyValue struct {
+	value   string
+	boolean bool
+}
+
+func (v *legacyValue) String() string     { return v.value }
+func (v *legacyValue) Set(s string) error { v.value = s; return nil }
+func (v *legacyValue) IsBoolFlag() bool   { return v.boolean }
+
+// translateLegacy rewrites a command line written for the single flag set
+// of earlier versions into the equivalent subcommand, as in "-delete 3"
+// to "rm 3", and returns a deprecation warning for it. The other flags
+// are handed on to the subcommand, which rejects those it does not take:
+// "-all -website x" is an error instead of a silently ignored flag, and
+// so are two commands at once. Command lines that start with a
+// subcommand are returned unchanged.
+func translateLegacy(args []string) ([]string, string, error) {
+	if len(args) == 0 || !strings.HasPrefix(args[0], "-") {
+		return args, "", nil
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		return args, "", nil
+	}
+
+	fs := flag.NewFlagSet("pwdcli", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	values := map[string]*legacyValue{}
+	for _, name := range legacyBoolFlags {
+		values[name] = &legacyValue{boolean: true}
+		fs.Var(values[name], name, "")
+	}
+	for _, name := range legacyValueFlags {
+		values[name] = &legacyValue{}
+		fs.Var(values[name], name, "")
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return []string{"-h"}, "", nil
+		}
+		return nil, "", err
+	}
+	set := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	// -generate and -passphrase only choose the password of -add.
+	var found *legacyCommand
+	for i, c := range legacyCommands {
+		if !set[c.flag] || (set["add"] && (c.flag == "generate" || c.flag == "passphrase")) {
+			continue
+		}
+		if found != nil {
+			return nil, "", fmt.Errorf("conflicting commands: -%s and -%s", found.flag, c.flag)
+		}
+		found = &legacyCommands[i]
+	}
+
+	// Hand the remaining flags on without the ones the command consumed.
+	// Their values are never printed, since they may be secrets.
+	var forwarded []string
+	fs.Visit(func(f *flag.Flag) {
+		if found != nil && (f.Name == found.flag || f.Name == found.extra) {
+			return
+		}
+		forwarded = append(forwarded, "-"+f.Name+"="+values[f.Name].value)
+	})
+
+	// Global flags may come before a subcommand.
+	if found == nil {
+		rest := fs.Args()
+		if len(rest) == 0 {
+			return nil, "", errors.New("no command given")
+		}
+		return append(append([]string{rest[0]}, forwarded...), rest[1:]...), "", nil
+	}
+
+	out := append([]string{found.command[0]}, forwarded...)
+	out = append(out, "--")
+	out = append(out, found.command[1:]...)
+	if found.arg {
+		out = append(out, values[found.flag].value)
+	}
+	if found.extra != "" && set[found.extra] {
+		out = append(out, values[found.extra].value)
+	}
+	out = append(out, fs.Args()...)
+
+	warning := fmt.Sprintf("-%s is deprecated; use \"pwdcli %s\" instead.", found.flag, strings.Join(found.command, " "))
+	return out, warning, nil
+}
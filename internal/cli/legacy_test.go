@@ -0,0 +1,96 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package cli_test contains unit tests for the cli package.
+package cli_test
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/cli"
+)
+
+// TestTranslateLegacy verifies that the flags of earlier versions are
+// rewritten into the equivalent subcommands.
+func TestTranslateLegacy(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{"-all"}, []string{"list", "--"}},
+		{[]string{"-delete", "3"}, []string{"rm", "--", "3"}},
+		{[]string{"-search", "-x"}, []string{"search", "--", "-x"}},
+		{[]string{"-copy", "2", "-field", "user"}, []string{"copy", "--", "2", "user"}},
+		{[]string{"-otp", "1", "-resync", "123456"}, []string{"otp", "-resync=123456", "--", "1"}},
+		{[]string{"-vault", "v.json", "-add", "-website", "w", "-generate"},
+			[]string{"add", "-generate=true", "-vault=v.json", "-website=w", "--"}},
+		{[]string{"-generate", "-length", "20"}, []string{"generate", "-length=20", "--"}},
+		{[]string{"-cp", "0", "-to", "work"}, []string{"cp", "--", "0", "work"}},
+		{[]string{"-rename-profile", "a", "-to", "b"}, []string{"profile", "--", "rename", "a", "b"}},
+		{[]string{"-list-profiles"}, []string{"profile", "--", "list"}},
+		{[]string{"-config-set", "format=json"}, []string{"config", "--", "set", "format=json"}},
+	}
+
+	for _, tt := range tests {
+		got, warning, err := cli.TranslateLegacy(tt.args)
+		if err != nil {
+			t.Errorf("TranslateLegacy(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("TranslateLegacy(%q) = %q; want %q", tt.args, got, tt.want)
+		}
+
+		// Every legacy command is deprecated.
+		if !strings.Contains(warning, "deprecated") {
+			t.Errorf("TranslateLegacy(%q) warning = %q; want a deprecation", tt.args, warning)
+		}
+	}
+}
+
+// TestTranslateLegacyUnchanged verifies that subcommands, and global flags
+// before a subcommand, are left alone without a warning.
+func TestTranslateLegacyUnchanged(t *testing.T) {
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{[]string{}, []string{}},
+		{[]string{"list"}, []string{"list"}},
+		{[]string{"-h"}, []string{"-h"}},
+		{[]string{"-format", "json", "list", "-x"}, []string{"list", "-format=json", "-x"}},
+	}
+
+	for _, tt := range tests {
+		got, warning, err := cli.TranslateLegacy(tt.args)
+		if err != nil {
+			t.Errorf("TranslateLegacy(%q) failed: %v", tt.args, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) || warning != "" {
+			t.Errorf("TranslateLegacy(%q) = %q, %q; want %q without a warning", tt.args, got, warning, tt.want)
+		}
+	}
+}
+
+// TestTranslateLegacyConflict verifies that two commands at once are an
+// error rather than one of them being silently ignored.
+func TestTranslateLegacyConflict(t *testing.T) {
+	for _, args := range [][]string{
+		{"-add", "-delete", "0"},
+		{"-all", "-search", "x"},
+		{"-generate", "-passphrase"},
+	} {
+		if _, _, err := cli.TranslateLegacy(args); err == nil {
+			t.Errorf("TranslateLegacy(%q) succeeded; want a conflict", args)
+		}
+	}
+
+	// -generate only picks the password of -add.
+	if _, _, err := cli.TranslateLegacy([]string{"-add", "-generate"}); err != nil {
+		t.Errorf("TranslateLegacy(-add -generate) failed: %v", err)
+	}
+}
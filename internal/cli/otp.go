@@ -0,0 +1,148 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/otp"
+)
+
+// runOTP prints the current one-time password of an entry, or
+// resynchronises the counter of its HOTP key.
+func runOTP(e *env, args []string) error {
+	fs := e.flagSet("otp")
+	resync := fs.String("resync", "", "Resynchronise the HOTP counter with a code the server accepted")
+	window := fs.Int("window", otp.DefaultWindow, "How many HOTP codes -resync looks ahead")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+	entry, err := handling.Select(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	if *resync != "" {
+		skipped, err := handling.ResyncBy(store, entry.Selector(), *resync, *window)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Counter resynchronised, %d codes skipped.\n", skipped)
+		return nil
+	}
+
+	code, remaining, err := handling.CodeBy(store, entry.Selector(), time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Report the validity of TOTP codes on stderr so that stdout holds
+	// only the code. HOTP codes stay valid until used.
+	fmt.Println(code)
+	if remaining > 0 {
+		fmt.Fprintf(os.Stderr, "Valid for %d more seconds.\n", int(remaining.Seconds()))
+	}
+	return nil
+}
+
+// runImportOTP imports 2FA keys into the vault, attaching them to the
+// matching entries.
+func runImportOTP(e *env, args []string) error {
+	fs := e.flagSet("import-otp")
+	dryRun := fs.Bool("dry-run", false, "Show what the import would do without writing")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+
+	// Reject unreadable keys before asking for the master password.
+	keys, err := readOTPKeys(args[0])
+	if err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	plan, err := handling.ImportOTP(store, keys, *dryRun)
+	if err != nil {
+		return err
+	}
+	printImport(plan, *dryRun)
+	return nil
+}
+
+// readOTPKeys returns the keys of arg, which is either an otpauth:// or
+// otpauth-migration:// URI, or the path of a file holding such URIs one
+// per line, as saved from QR code scanners.
+func readOTPKeys(arg string) ([]otp.Key, error) {
+	uris := []string{arg}
+	if !strings.HasPrefix(strings.ToLower(arg), "otpauth") {
+		f, err := os.Open(arg)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		uris = nil
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(nil, 1<<20)
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				uris = append(uris, line)
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	}
+
+	keys := []otp.Key{}
+	for i, uri := range uris {
+		k, err := otp.Keys(uri)
+		if err != nil {
+			if len(uris) > 1 {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		keys = append(keys, k...)
+	}
+	return keys, nil
+}
+
+// printImport summarises an OTP import, one line per key. Secrets are
+// never shown.
+func printImport(plan []handling.Import, dryRun bool) {
+	counts := map[handling.ImportAction]int{}
+	for _, p := range plan {
+		counts[p.Action]++
+		kind := "TOTP"
+		if p.Key.Type == otp.TypeHOTP {
+			kind = "HOTP"
+		}
+		fmt.Printf("%-9s %s %s Website: %s Username: %s\n",
+			p.Action, kind, p.Entry.Label(), p.Entry.Account.Website, p.Entry.Account.Username)
+	}
+
+	summary := fmt.Sprintf("%d attached, %d replaced, %d created, %d unchanged",
+		counts[handling.ImportAttach], counts[handling.ImportReplace], counts[handling.ImportCreate], counts[handling.ImportUnchanged])
+	if dryRun {
+		fmt.Printf("Dry run: %s. Nothing was written.\n", summary)
+		return
+	}
+	fmt.Printf("Imported: %s.\n", summary)
+}
@@ -0,0 +1,136 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/config"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// jsonEntry is the JSON form of a listed entry.
+type jsonEntry struct {
+	Index int `json:"index"`
+	account.Account
+}
+
+// printEntries writes entries to standard output in the given format,
+// masking passwords if asked to. Concealed custom fields are always
+// masked. An empty text listing prints empty, which callers replace with
+// a message where it helps.
+func printEntries(entries []handling.Entry, format string, mask bool) error {
+	for i := range entries {
+		if mask {
+			entries[i] = entries[i].Masked()
+		} else {
+			entries[i] = entries[i].HideConcealed()
+		}
+	}
+
+	switch format {
+	case config.FormatJSON:
+		out := make([]jsonEntry, 0, len(entries))
+		for _, e := range entries {
+			out = append(out, jsonEntry{Index: e.Index, Account: e.Account})
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(out)
+	case config.FormatText, "":
+		for _, e := range entries {
+			fmt.Println(e)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// printShown prints entries read from store like printEntries and, when
+// their passwords are shown unmasked, records the access to each of them.
+func printShown(store storage.Store, entries []handling.Entry, format string, mask bool) error {
+	if err := printEntries(entries, format, mask); err != nil {
+		return err
+	}
+	if mask {
+		return nil
+	}
+	return handling.TouchAll(store, entries)
+}
+
+// printEntry writes every field of one entry to standard output, one per
+// line followed by the notes, or as a JSON object. Text output shows the
+// known timestamps in local time.
+func printEntry(e handling.Entry, format string) error {
+	switch format {
+	case config.FormatJSON:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(jsonEntry{Index: e.Index, Account: e.Account})
+	case config.FormatText, "":
+		fields := [][2]string{
+			{"Index", fmt.Sprint(e.Index)},
+			{"ID", e.Account.ID},
+			{"Website", e.Account.Website},
+			{"Username", e.Account.Username},
+			{"Email", e.Account.Email},
+			{"Password", e.Account.Pwd},
+		}
+		if e.Account.OTP != "" {
+			fields = append(fields, [2]string{"OTP", e.Account.OTP})
+		}
+		for _, f := range e.Account.Fields {
+			fields = append(fields, [2]string{f.Name, f.Value})
+		}
+		if e.Account.Folder != "" {
+			fields = append(fields, [2]string{"Folder", e.Account.Folder})
+		}
+		if len(e.Account.Tags) > 0 {
+			fields = append(fields, [2]string{"Tags", strings.Join(e.Account.Tags, ", ")})
+		}
+		for _, t := range []struct {
+			name string
+			at   time.Time
+		}{
+			{"Created", e.Account.Created},
+			{"Modified", e.Account.Modified},
+			{"Password changed", e.Account.PasswordChanged},
+			{"Accessed", e.Account.Accessed},
+		} {
+			if !t.at.IsZero() {
+				fields = append(fields, [2]string{t.name, t.at.Local().Format(time.DateTime)})
+			}
+		}
+
+		// Custom field names may be longer than the built-in ones.
+		width := 0
+		for _, f := range fields {
+			width = max(width, len(f[0])+1)
+		}
+		for _, f := range fields {
+			fmt.Printf("%-*s %s\n", width, f[0]+":", f[1])
+		}
+		if e.Account.Notes != "" {
+			fmt.Printf("\nNotes:\n%s\n", e.Account.Notes)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", format)
+	}
+}
+
+// entryName identifies an entry in messages by the short form of its ID
+// and its website. Unlike the index, the ID stays valid when other
+// entries are deleted.
+func entryName(e handling.Entry) string {
+	return fmt.Sprintf("%s (%s)", e.ShortID(), e.Account.Website)
+}
@@ -0,0 +1,96 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/nullzeiger/pwdcli/internal/prompt"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// unlock prompts for the master password and hands it to the storage
+// layer. When no vault exists yet, the password is asked twice so that
+// a typo cannot lock the user out of the new vault. A plaintext vault
+// left by an older version is encrypted on the spot.
+func unlock(store storage.Vault) error {
+	if fs, ok := store.(*storage.FileStore); ok {
+		legacy, err := fs.IsLegacy()
+		if err != nil {
+			return err
+		}
+		if legacy {
+			return migrate(fs)
+		}
+	}
+
+	if store.Exists() {
+		pwd, err := prompt.Default.Password("Master password: ")
+		if err != nil {
+			return err
+		}
+		store.Unlock(pwd)
+		return nil
+	}
+
+	pwd, err := newPassword()
+	if err != nil {
+		return err
+	}
+	store.Unlock(pwd)
+	return nil
+}
+
+// migrate asks for a new master password and encrypts the plaintext vault
+// with it, optionally keeping an encrypted backup.
+func migrate(store *storage.FileStore) error {
+	fmt.Fprintln(os.Stderr, "Found an unencrypted password file; it will be encrypted with a new master password.")
+
+	pwd, err := newPassword()
+	if err != nil {
+		return err
+	}
+
+	backup, err := prompt.Default.Confirm("Keep an encrypted backup?")
+	if err != nil {
+		return err
+	}
+
+	store.Unlock(pwd)
+	if err := store.Migrate(backup); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(os.Stderr, "Password file encrypted.")
+	return nil
+}
+
+// newPassword asks for a new master password twice and returns it if
+// both entries match.
+func newPassword() ([]byte, error) {
+	pwd, err := prompt.Default.NewPassword("New master password: ", "Confirm master password: ")
+	if errors.Is(err, prompt.ErrMismatch) {
+		return nil, errors.New("master passwords do not match")
+	}
+	return pwd, err
+}
+
+// entryPassword asks for the password of a new entry. At a terminal it
+// is typed twice without echo; a piped password is read once.
+func entryPassword(website string) (string, error) {
+	question := fmt.Sprintf("Password for %s: ", website)
+	if !prompt.Default.IsTerminal() {
+		pwd, err := prompt.Default.Password(question)
+		return string(pwd), err
+	}
+
+	pwd, err := prompt.Default.NewPassword(question, "Confirm password: ")
+	if errors.Is(err, prompt.ErrMismatch) {
+		return "", errors.New("passwords do not match")
+	}
+	return string(pwd), err
+}
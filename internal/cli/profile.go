@@ -0,0 +1,158 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/profile"
+	"github.com/nullzeiger/pwdcli/internal/prompt"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// runProfile runs one of the profile management actions: list, create,
+// rename or remove.
+func runProfile(e *env, args []string) error {
+	fs := e.flagSet("profile")
+	args, err := e.parse(fs, args, 1, 3)
+	if err != nil {
+		return err
+	}
+	action, args := args[0], args[1:]
+
+	want := map[string]int{"list": 0, "create": 1, "rename": 2, "remove": 1}
+	n, ok := want[action]
+	if !ok {
+		return usageError(fs, fmt.Sprintf("unknown action %q", action))
+	}
+	if len(args) != n {
+		return usageError(fs, fmt.Sprintf("%s takes %d arguments", action, n))
+	}
+
+	switch action {
+	case "list":
+		return listProfiles(e.cfg)
+	case "create":
+		return createProfile(e.cfg, args[0])
+	case "rename":
+		return renameProfile(e.cfg, args[0], args[1])
+	default:
+		return removeProfile(e.cfg, args[0])
+	}
+}
+
+// listProfiles prints the name and vault of every profile.
+func listProfiles(cfg config.Config) error {
+	profiles, err := profile.List(cfg)
+	if err != nil {
+		return err
+	}
+	if len(profiles) == 0 {
+		fmt.Println("No profiles.")
+		return nil
+	}
+	for _, p := range profiles {
+		fmt.Printf("%s\t%s\n", p.Name, p.Vault)
+	}
+	return nil
+}
+
+// createProfile creates a profile and its vault, asking for the master
+// password of the new vault.
+func createProfile(cfg config.Config, name string) error {
+	path, err := profile.Create(name, cfg)
+	if err != nil {
+		return err
+	}
+
+	pwd, err := newPassword()
+	if err != nil {
+		return err
+	}
+	store := storage.NewFileStore(path)
+	store.Unlock(pwd)
+	if err := store.Create(); err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile %s created.\n", name)
+	return nil
+}
+
+// renameProfile renames a profile and its vault.
+func renameProfile(cfg config.Config, oldName, newName string) error {
+	if err := profile.Rename(oldName, newName, cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile %s renamed to %s.\n", oldName, newName)
+	return nil
+}
+
+// removeProfile deletes a profile and its vault. Removing a vault cannot
+// be undone, so the name must be typed again.
+func removeProfile(cfg config.Config, name string) error {
+	fmt.Fprintf(os.Stderr, "This permanently deletes the vault of profile %s.\n", name)
+	answer, err := prompt.Default.Line("Type the profile name to confirm: ")
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(answer) != name {
+		return errors.New("profile name does not match, nothing removed")
+	}
+	if err := profile.Remove(name, cfg); err != nil {
+		return err
+	}
+
+	fmt.Printf("Profile %s removed.\n", name)
+	return nil
+}
+
+// openProfile opens and unlocks the vault of the named profile, asking for
+// its master password, and returns it with its path. The vault must
+// already exist.
+func openProfile(cfg config.Config, name string, lockTimeout time.Duration) (storage.Vault, string, error) {
+	path, err := vaultPath(cfg, "", name)
+	if err != nil {
+		return nil, "", err
+	}
+
+	v := storage.Open(path, lockTimeout)
+	if !v.Exists() {
+		return nil, "", fmt.Errorf("%w: %s", profile.ErrNotFound, name)
+	}
+
+	pwd, err := prompt.Default.Password(fmt.Sprintf("Master password for profile %s: ", name))
+	if err != nil {
+		return nil, "", err
+	}
+	v.Unlock(pwd)
+
+	// Verify the password now rather than halfway through a transfer.
+	if _, err := v.Load(); err != nil {
+		return nil, "", err
+	}
+	if _, err := handling.AssignIDs(v); err != nil {
+		return nil, "", err
+	}
+	return v, path, nil
+}
+
+// sameFile reports whether a and b name the same file.
+func sameFile(a, b string) bool {
+	fa, errA := os.Stat(a)
+	fb, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(fa, fb)
+}
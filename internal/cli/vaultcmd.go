@@ -0,0 +1,163 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	handling "github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// runPasswd changes the master password of the vault.
+func runPasswd(e *env, args []string) error {
+	fs := e.flagSet("passwd")
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	// Verify the current password before asking for a new one.
+	if _, err := store.Load(); err != nil {
+		return err
+	}
+
+	pwd, err := newPassword()
+	if err != nil {
+		return err
+	}
+	// Backups that could not be sealed with the new password still open
+	// with an older one, which the user should know about.
+	err = store.ChangePassword(pwd)
+	var stale *storage.StaleBackupsError
+	if err != nil && !errors.As(err, &stale) {
+		return err
+	}
+
+	fmt.Println("Master password changed.")
+	if stale != nil {
+		fmt.Fprintln(os.Stderr, "Warning: these backups do not open with the previous master password and were left as they are; delete them if an older password may be compromised:")
+		for _, p := range stale.Paths {
+			fmt.Fprintln(os.Stderr, "  "+p)
+		}
+	}
+	return nil
+}
+
+// runRekey re-encrypts the vault with new key derivation parameters.
+func runRekey(e *env, args []string) error {
+	fs := e.flagSet("rekey")
+	kdfTime := fs.Uint("kdf-time", uint(vault.DefaultParams.Time), "Argon2id passes")
+	kdfMemory := fs.Uint("kdf-memory", uint(vault.DefaultParams.Memory), "Argon2id memory in KiB")
+	kdfThreads := fs.Uint("kdf-threads", uint(vault.DefaultParams.Threads), "Argon2id parallelism")
+	if _, err := e.parse(fs, args, 0, 0); err != nil {
+		return err
+	}
+	limit := vault.MaxParams
+	if *kdfThreads > uint(limit.Threads) || *kdfTime > uint(limit.Time) || *kdfMemory > uint(limit.Memory) {
+		return usageError(fs, "KDF parameters out of range, the limit is "+limit.String())
+	}
+	params := vault.Params{
+		Time:    uint32(*kdfTime),
+		Memory:  uint32(*kdfMemory),
+		Threads: uint8(*kdfThreads),
+	}
+
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+	old, err := store.KDFParams()
+	if err != nil {
+		return err
+	}
+	if err := store.Rekey(params); err != nil {
+		return err
+	}
+
+	fmt.Printf("Vault re-encrypted: %s -> %s\n", old, params)
+	return nil
+}
+
+// runMigrate converts the vault to another storage backend.
+func runMigrate(e *env, args []string) error {
+	fs := e.flagSet("migrate")
+	args, err := e.parse(fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	if args[0] != "sqlite" {
+		return usageError(fs, "unsupported backend: "+args[0])
+	}
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+
+	fstore, ok := store.(*storage.FileStore)
+	if !ok {
+		fmt.Println("The vault is already a SQLite database.")
+		return nil
+	}
+	if err := storage.ConvertToSQLite(fstore); err != nil {
+		return err
+	}
+
+	fmt.Printf("Vault converted to SQLite; the previous file was kept as %s.\n", fstore.Path+storage.ConvertBackupSuffix)
+	return nil
+}
+
+// runCp copies an entry to the vault of another profile.
+func runCp(e *env, args []string) error {
+	return transfer(e, "cp", args, false)
+}
+
+// runMv moves an entry to the vault of another profile.
+func runMv(e *env, args []string) error {
+	return transfer(e, "mv", args, true)
+}
+
+// transfer copies or moves an entry to the vault of another profile,
+// asking for the master passwords of both vaults.
+func transfer(e *env, name string, args []string, move bool) error {
+	fs := e.flagSet(name)
+	args, err := e.parse(fs, args, 2, 2)
+	if err != nil {
+		return err
+	}
+	target := args[1]
+	store, err := e.open()
+	if err != nil {
+		return err
+	}
+	entry, err := handling.Select(store, args[0])
+	if err != nil {
+		return err
+	}
+
+	dst, dstPath, err := openProfile(e.cfg, target, e.lockTimeout)
+	if err != nil {
+		return err
+	}
+	if sameFile(e.path, dstPath) {
+		return errors.New("source and target are the same vault")
+	}
+	if err := handling.TransferBy(store, dst, entry.Selector(), move); err != nil {
+		return err
+	}
+
+	verb := "copied"
+	if move {
+		verb = "moved"
+	}
+	fmt.Printf("Entry %s %s to profile %s.\n", entryName(entry), verb, target)
+	return nil
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package clipboard puts secrets on the system clipboard, so that they
+// never have to be printed to the terminal, and takes them off again.
+// It drives the usual command-line tools (wl-copy, xclip, xsel, pbcopy)
+// and falls back on the OSC 52 escape sequence, which lets the terminal
+// emulator set the local clipboard even over SSH.
+package clipboard
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnavailable is returned when no clipboard can be reached.
+	ErrUnavailable = errors.New("no clipboard available: install wl-clipboard, xclip or xsel, or use a terminal that supports OSC 52")
+
+	// ErrUnreadable is returned by providers that can set the clipboard
+	// but not read it back.
+	ErrUnreadable = errors.New("clipboard cannot be read")
+)
+
+// Provider is a clipboard.
+type Provider interface {
+	// Name identifies the provider, for Lookup.
+	Name() string
+
+	// Copy replaces the contents of the clipboard with text.
+	Copy(text string) error
+
+	// Paste returns the contents of the clipboard, or ErrUnreadable.
+	Paste() (string, error)
+}
+
+// Command is a clipboard driven by external commands that take the text
+// on standard input and print it on standard output.
+type Command struct {
+	// ID is the name of the provider.
+	ID string
+
+	// CopyArgs is the command line that sets the clipboard.
+	CopyArgs []string
+
+	// PasteArgs is the command line that prints the clipboard.
+	PasteArgs []string
+}
+
+// Name returns the name of the provider.
+func (c Command) Name() string {
+	return c.ID
+}
+
+// copyWait is how long Copy waits for the standard error of the copy
+// command to close after the command exits.
+const copyWait = 200 * time.Millisecond
+
+// Copy runs the copy command with text on its standard input.
+//
+// wl-copy and xclip fork a process that keeps serving the clipboard and
+// may hold on to the standard error inherited from the command, so Copy
+// stops reading it copyWait after the command exits rather than waiting
+// for the clipboard to change owner.
+func (c Command) Copy(text string) error {
+	var stderr bytes.Buffer
+	cmd := exec.Command(c.CopyArgs[0], c.CopyArgs[1:]...)
+	cmd.Stdin = strings.NewReader(text)
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = copyWait
+	if err := cmd.Run(); err != nil && !errors.Is(err, exec.ErrWaitDelay) {
+		return fmt.Errorf("%s: %w: %s", c.CopyArgs[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// Paste runs the paste command and returns its output.
+func (c Command) Paste() (string, error) {
+	out, err := exec.Command(c.PasteArgs[0], c.PasteArgs[1:]...).Output()
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", c.PasteArgs[0], err)
+	}
+	return string(out), nil
+}
+
+// Known command-line clipboards, in order of preference.
+var (
+	WlClipboard = Command{"wl-copy", []string{"wl-copy"}, []string{"wl-paste", "--no-newline"}}
+	Xclip       = Command{"xclip", []string{"xclip", "-selection", "clipboard", "-in"}, []string{"xclip", "-selection", "clipboard", "-out"}}
+	Xsel        = Command{"xsel", []string{"xsel", "--clipboard", "--input"}, []string{"xsel", "--clipboard", "--output"}}
+	Pbcopy      = Command{"pbcopy", []string{"pbcopy"}, []string{"pbpaste"}}
+)
+
+// OSC52 sets the clipboard of the terminal emulator by writing the OSC 52
+// escape sequence to the terminal. The clipboard cannot be read back.
+type OSC52 struct {
+	// W is the terminal.
+	W io.Writer
+}
+
+// Name returns the name of the provider.
+func (o OSC52) Name() string {
+	return "osc52"
+}
+
+// Copy writes the escape sequence that sets the clipboard to text. An
+// empty text clears the clipboard.
+func (o OSC52) Copy(text string) error {
+	_, err := fmt.Fprintf(o.W, "\x1b]52;c;%s\a", base64.StdEncoding.EncodeToString([]byte(text)))
+	return err
+}
+
+// Paste always fails: terminals do not answer clipboard queries by
+// default.
+func (o OSC52) Paste() (string, error) {
+	return "", ErrUnreadable
+}
+
+// Detect returns the clipboard of the current session: wl-copy under
+// Wayland, xclip or xsel under X11, pbcopy on macOS, and OSC 52 on the
+// terminal tty when nothing else is available, as in SSH sessions.
+// tty may be nil if there is no terminal.
+func Detect(tty io.Writer) (Provider, error) {
+	candidates := []Command{}
+	switch {
+	case runtime.GOOS == "darwin":
+		candidates = append(candidates, Pbcopy)
+	case os.Getenv("WAYLAND_DISPLAY") != "":
+		candidates = append(candidates, WlClipboard, Xclip, Xsel)
+	case os.Getenv("DISPLAY") != "":
+		candidates = append(candidates, Xclip, Xsel)
+	}
+	for _, c := range candidates {
+		if _, err := exec.LookPath(c.CopyArgs[0]); err == nil {
+			return c, nil
+		}
+	}
+
+	if tty != nil {
+		return OSC52{W: tty}, nil
+	}
+	return nil, ErrUnavailable
+}
+
+// Lookup returns the provider named name, as returned by Name, so that
+// another process can clear what Detect chose.
+func Lookup(name string, tty io.Writer) (Provider, error) {
+	for _, c := range []Command{WlClipboard, Xclip, Xsel, Pbcopy} {
+		if c.ID == name {
+			return c, nil
+		}
+	}
+	if name == "osc52" && tty != nil {
+		return OSC52{W: tty}, nil
+	}
+	return nil, ErrUnavailable
+}
+
+// Digest returns the digest of a copied value, which is all Clear needs
+// to recognise it.
+func Digest(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// Clear empties the clipboard if it still holds the value with the given
+// digest, so that anything copied since is left alone. Providers that
+// cannot be read are cleared unconditionally, since leaving a secret
+// behind is worse. It reports whether the clipboard was cleared.
+func Clear(p Provider, digest string) (bool, error) {
+	current, err := p.Paste()
+	switch {
+	case errors.Is(err, ErrUnreadable):
+	case err != nil:
+		return false, err
+	case subtle.ConstantTimeCompare([]byte(Digest(current)), []byte(digest)) != 1:
+		return false, nil
+	}
+	return true, p.Copy("")
+}
@@ -0,0 +1,151 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package clipboard_test contains unit tests for the clipboard package.
+// These tests use a fake clipboard provider, so they neither need nor
+// touch the clipboard of the machine running them.
+package clipboard_test
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/clipboard"
+)
+
+// fakeClipboard is a Provider that keeps the clipboard in memory.
+type fakeClipboard struct {
+	text       string
+	unreadable bool
+	copies     int
+}
+
+// Name returns the name of the provider.
+func (f *fakeClipboard) Name() string {
+	return "fake"
+}
+
+// Copy stores text.
+func (f *fakeClipboard) Copy(text string) error {
+	f.text = text
+	f.copies++
+	return nil
+}
+
+// Paste returns the stored text, unless the clipboard is unreadable.
+func (f *fakeClipboard) Paste() (string, error) {
+	if f.unreadable {
+		return "", clipboard.ErrUnreadable
+	}
+	return f.text, nil
+}
+
+// TestClear verifies that the clipboard is cleared while it holds the
+// copied value.
+func TestClear(t *testing.T) {
+	cb := &fakeClipboard{}
+	if err := cb.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+
+	cleared, err := clipboard.Clear(cb, clipboard.Digest("s3cret"))
+	if err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if !cleared || cb.text != "" {
+		t.Errorf("Clear() = %v, clipboard %q; want true, empty", cleared, cb.text)
+	}
+}
+
+// TestClearChanged verifies that something copied since is left alone.
+func TestClearChanged(t *testing.T) {
+	cb := &fakeClipboard{text: "copied later"}
+
+	cleared, err := clipboard.Clear(cb, clipboard.Digest("s3cret"))
+	if err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if cleared || cb.text != "copied later" || cb.copies != 0 {
+		t.Errorf("Clear() = %v, clipboard %q; want false, unchanged", cleared, cb.text)
+	}
+}
+
+// TestClearUnreadable verifies that clipboards that cannot be read are
+// cleared anyway.
+func TestClearUnreadable(t *testing.T) {
+	cb := &fakeClipboard{text: "s3cret", unreadable: true}
+
+	cleared, err := clipboard.Clear(cb, clipboard.Digest("other"))
+	if err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if !cleared || cb.text != "" {
+		t.Errorf("Clear() = %v, clipboard %q; want true, empty", cleared, cb.text)
+	}
+}
+
+// TestOSC52 verifies the escape sequences written to the terminal.
+func TestOSC52(t *testing.T) {
+	var tty bytes.Buffer
+	p := clipboard.OSC52{W: &tty}
+
+	if err := p.Copy("hunter2"); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	if got, want := tty.String(), "\x1b]52;c;aHVudGVyMg==\a"; got != want {
+		t.Errorf("Copy(hunter2) wrote %q; want %q", got, want)
+	}
+
+	// Clearing sends an empty selection.
+	tty.Reset()
+	if _, err := clipboard.Clear(p, clipboard.Digest("hunter2")); err != nil {
+		t.Fatalf("Clear() failed: %v", err)
+	}
+	if got, want := tty.String(), "\x1b]52;c;\a"; got != want {
+		t.Errorf("Clear() wrote %q; want %q", got, want)
+	}
+}
+
+// TestLookup verifies that providers can be found again by name.
+func TestLookup(t *testing.T) {
+	var tty bytes.Buffer
+	for _, name := range []string{"wl-copy", "xclip", "xsel", "pbcopy", "osc52"} {
+		p, err := clipboard.Lookup(name, &tty)
+		if err != nil || p.Name() != name {
+			t.Errorf("Lookup(%s) = %v, %v; want the %s provider", name, p, err, name)
+		}
+	}
+
+	// OSC 52 needs a terminal.
+	if _, err := clipboard.Lookup("osc52", nil); err == nil {
+		t.Errorf("Lookup(osc52) without a terminal should fail")
+	}
+}
+
+// TestCommandCopy verifies that Command.Copy returns once the copy command
+// exits, even if a process it leaves behind keeps its output open, and
+// that failures report the command's standard error.
+func TestCommandCopy(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+
+	// Like wl-copy and xclip, leave a process holding stdout and stderr.
+	daemon := clipboard.Command{ID: "daemon", CopyArgs: []string{"sh", "-c", "cat >/dev/null; sleep 5 &"}}
+	start := time.Now()
+	if err := daemon.Copy("s3cret"); err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Copy() took %v; it waited for the background process", elapsed)
+	}
+
+	failing := clipboard.Command{ID: "failing", CopyArgs: []string{"sh", "-c", "echo no display >&2; exit 1"}}
+	if err := failing.Copy("s3cret"); err == nil || !strings.Contains(err.Error(), "no display") {
+		t.Errorf("Copy() error = %v; want the command's standard error", err)
+	}
+}
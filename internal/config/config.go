@@ -0,0 +1,439 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package config loads and saves the optional pwdcli configuration file,
+// a TOML document located in the configuration directory returned by
+// util.ConfigDir. The file sets persistent defaults, globally and per
+// profile; command-line flags still take precedence over it.
+//
+// A minimal file looks like this:
+//
+//	vault = "~/vaults/personal"
+//	format = "text"
+//	mask = true
+//	clipboard_timeout = "30s"
+//	backup_retention = 3
+//
+//	[generator]
+//	length = 24
+//	symbols = false
+//
+//	[passphrase]
+//	words = 5
+//	separator = " "
+//
+//	[profiles.work]
+//	vault = "/srv/team/vault"
+//	format = "json"
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/nullzeiger/pwdcli/internal/util"
+)
+
+// Filename is the name of the configuration file.
+const Filename = "config.toml"
+
+// Output formats accepted by the format setting.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+// Config holds the settings read from the configuration file.
+// A zero Config means no setting was given.
+type Config struct {
+	// Vault is the path of the vault file.
+	Vault string `toml:"vault,omitempty"`
+
+	// Settings are the global defaults.
+	Settings
+
+	// Profiles holds the settings of named profiles, keyed by name.
+	Profiles map[string]Profile `toml:"profiles,omitempty"`
+}
+
+// Profile holds the settings of a named profile.
+type Profile struct {
+	// Vault is the path of the profile's vault file. When empty, the
+	// profile uses a vault in its own data directory.
+	Vault string `toml:"vault,omitempty"`
+
+	// Settings override the global defaults for this profile.
+	Settings
+}
+
+// Settings are the defaults that can be set globally and overridden per
+// profile. A nil or empty field is unset and falls back to the next level.
+type Settings struct {
+	// Format is the output format of listings: "text" or "json".
+	Format string `toml:"format,omitempty"`
+
+	// Mask hides passwords in listings and search results.
+	Mask *bool `toml:"mask,omitempty"`
+
+	// ClipboardTimeout is how long a copied value stays on the clipboard.
+	ClipboardTimeout *Duration `toml:"clipboard_timeout,omitempty"`
+
+	// BackupRetention is how many previous versions of a file vault are
+	// kept next to it whenever it is saved.
+	BackupRetention *int `toml:"backup_retention,omitempty"`
+
+	// Generator is the default password generator policy.
+	Generator Generator `toml:"generator,omitempty"`
+
+	// Passphrase is the default passphrase generator policy.
+	Passphrase Passphrase `toml:"passphrase,omitempty"`
+
+	// Strength is the password strength policy.
+	Strength Strength `toml:"strength,omitempty"`
+
+	// Breach configures the offline breached-password check.
+	Breach Breach `toml:"breach,omitempty"`
+}
+
+// Generator holds the password generator policy.
+type Generator struct {
+	// Length is the number of characters of generated passwords.
+	Length *int `toml:"length,omitempty"`
+
+	// Upper, Lower, Digits and Symbols enable the character classes.
+	Upper   *bool `toml:"upper,omitempty"`
+	Lower   *bool `toml:"lower,omitempty"`
+	Digits  *bool `toml:"digits,omitempty"`
+	Symbols *bool `toml:"symbols,omitempty"`
+
+	// ExcludeAmbiguous leaves out characters that are easily confused,
+	// such as 0 and O.
+	ExcludeAmbiguous *bool `toml:"exclude_ambiguous,omitempty"`
+
+	// MinUpper, MinLower, MinDigits and MinSymbols are the minimum
+	// number of characters of each class.
+	MinUpper   *int `toml:"min_upper,omitempty"`
+	MinLower   *int `toml:"min_lower,omitempty"`
+	MinDigits  *int `toml:"min_digits,omitempty"`
+	MinSymbols *int `toml:"min_symbols,omitempty"`
+
+	// Alphabet, when set, replaces the character classes entirely.
+	Alphabet string `toml:"alphabet,omitempty"`
+}
+
+// Passphrase holds the passphrase generator policy.
+type Passphrase struct {
+	// Words is the number of words.
+	Words *int `toml:"words,omitempty"`
+
+	// Separator is placed between words. It may be empty.
+	Separator *string `toml:"separator,omitempty"`
+
+	// Capitalize is "none", "first", "all" or "random".
+	Capitalize string `toml:"capitalize,omitempty"`
+
+	// Digit and Symbol append a random digit or symbol to a word.
+	Digit  *bool `toml:"digit,omitempty"`
+	Symbol *bool `toml:"symbol,omitempty"`
+}
+
+// Strength holds the password strength policy applied when adding entries
+// and by audits.
+type Strength struct {
+	// MinScore is the lowest acceptable score, from 0 to 4.
+	MinScore *int `toml:"min_score,omitempty"`
+
+	// Strict refuses passwords below MinScore, and breached passwords,
+	// instead of warning.
+	Strict *bool `toml:"strict,omitempty"`
+}
+
+// Breach configures the offline breached-password check.
+type Breach struct {
+	// File is the path of a local Pwned Passwords hash file.
+	File string `toml:"file,omitempty"`
+
+	// CheckOnAdd looks up new passwords in File when adding entries.
+	CheckOnAdd *bool `toml:"check_on_add,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "45s".
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	d.Duration = v
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Path returns the location of the configuration file.
+func Path() (string, error) {
+	dir, err := util.ConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, Filename), nil
+}
+
+// Load reads the configuration file at path. A missing file is not an
+// error and yields the zero Config. Unknown keys and invalid values are
+// rejected with an error naming the offending key, so that typos do not
+// go unnoticed.
+func Load(path string) (Config, error) {
+	var cfg Config
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	md, err := toml.Decode(string(data), &cfg)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	if undecoded := md.Undecoded(); len(undecoded) > 0 {
+		return cfg, fmt.Errorf("%s: unknown key %q", path, undecoded[0].String())
+	}
+	if err := cfg.Validate(); err != nil {
+		return cfg, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes cfg to path, replacing the whole file. Comments in an
+// existing file are not preserved.
+func Save(path string, cfg Config) error {
+	var buf bytes.Buffer
+	enc := toml.NewEncoder(&buf)
+	enc.Indent = ""
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), util.DirPerm); err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Validate checks every setting, globally and per profile. The returned
+// error names the offending key, for example "profiles.work.format".
+func (c Config) Validate() error {
+	if err := c.Settings.validate(""); err != nil {
+		return err
+	}
+	for name, p := range c.Profiles {
+		if err := p.Settings.validate("profiles." + name + "."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// validate checks s; prefix is prepended to key names in errors.
+func (s Settings) validate(prefix string) error {
+	for _, k := range keys {
+		if v, ok := k.get(&s); ok {
+			if err := k.set(&Settings{}, v); err != nil {
+				return &KeyError{Key: prefix + k.name, Err: err}
+			}
+		}
+	}
+	return nil
+}
+
+// KeyError reports an invalid value for a configuration key.
+type KeyError struct {
+	// Key is the full dotted name of the key.
+	Key string
+
+	// Err describes what is wrong with the value.
+	Err error
+}
+
+// Error implements the error interface.
+func (e *KeyError) Error() string {
+	return e.Key + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *KeyError) Unwrap() error {
+	return e.Err
+}
+
+// Resolved holds the effective settings after applying defaults, global
+// settings and profile overrides.
+type Resolved struct {
+	Format           string
+	Mask             bool
+	ClipboardTimeout time.Duration
+	BackupRetention  int
+	Generator        ResolvedGenerator
+	Passphrase       ResolvedPassphrase
+	Strength         ResolvedStrength
+	Breach           ResolvedBreach
+}
+
+// ResolvedGenerator is the effective password generator policy.
+type ResolvedGenerator struct {
+	Length           int
+	Upper            bool
+	Lower            bool
+	Digits           bool
+	Symbols          bool
+	ExcludeAmbiguous bool
+	MinUpper         int
+	MinLower         int
+	MinDigits        int
+	MinSymbols       int
+	Alphabet         string
+}
+
+// ResolvedPassphrase is the effective passphrase generator policy.
+type ResolvedPassphrase struct {
+	Words      int
+	Separator  string
+	Capitalize string
+	Digit      bool
+	Symbol     bool
+}
+
+// ResolvedStrength is the effective password strength policy.
+type ResolvedStrength struct {
+	MinScore int
+	Strict   bool
+}
+
+// ResolvedBreach is the effective breached-password check configuration.
+type ResolvedBreach struct {
+	File       string
+	CheckOnAdd bool
+}
+
+// Defaults are the settings used when neither the configuration file nor
+// the profile sets a value. Every field is set.
+var Defaults = Settings{
+	Format:           FormatText,
+	Mask:             ptr(false),
+	ClipboardTimeout: &Duration{45 * time.Second},
+	BackupRetention:  ptr(0),
+	Generator: Generator{
+		Length:           ptr(20),
+		Upper:            ptr(true),
+		Lower:            ptr(true),
+		Digits:           ptr(true),
+		Symbols:          ptr(true),
+		ExcludeAmbiguous: ptr(false),
+		MinUpper:         ptr(0),
+		MinLower:         ptr(0),
+		MinDigits:        ptr(0),
+		MinSymbols:       ptr(0),
+	},
+	Passphrase: Passphrase{
+		Words:      ptr(6),
+		Separator:  ptr("-"),
+		Capitalize: "none",
+		Digit:      ptr(false),
+		Symbol:     ptr(false),
+	},
+	Strength: Strength{
+		MinScore: ptr(2),
+		Strict:   ptr(false),
+	},
+	Breach: Breach{
+		CheckOnAdd: ptr(false),
+	},
+}
+
+// Resolve returns the effective settings for the named profile: Defaults,
+// overridden by the global settings, overridden by the profile's own.
+// A profile that is not configured only gets the global settings.
+func (c Config) Resolve(profileName string) Resolved {
+	var r Resolved
+	r.apply(Defaults)
+	r.apply(c.Settings)
+	if p, ok := c.Profiles[profileName]; ok {
+		r.apply(p.Settings)
+	}
+	return r
+}
+
+// apply overrides r with every setting that is set in s.
+func (r *Resolved) apply(s Settings) {
+	if s.Format != "" {
+		r.Format = s.Format
+	}
+	setIf(&r.Mask, s.Mask)
+	if s.ClipboardTimeout != nil {
+		r.ClipboardTimeout = s.ClipboardTimeout.Duration
+	}
+	setIf(&r.BackupRetention, s.BackupRetention)
+
+	g := s.Generator
+	setIf(&r.Generator.Length, g.Length)
+	setIf(&r.Generator.Upper, g.Upper)
+	setIf(&r.Generator.Lower, g.Lower)
+	setIf(&r.Generator.Digits, g.Digits)
+	setIf(&r.Generator.Symbols, g.Symbols)
+	setIf(&r.Generator.ExcludeAmbiguous, g.ExcludeAmbiguous)
+	setIf(&r.Generator.MinUpper, g.MinUpper)
+	setIf(&r.Generator.MinLower, g.MinLower)
+	setIf(&r.Generator.MinDigits, g.MinDigits)
+	setIf(&r.Generator.MinSymbols, g.MinSymbols)
+	if g.Alphabet != "" {
+		r.Generator.Alphabet = g.Alphabet
+	}
+
+	pp := s.Passphrase
+	setIf(&r.Passphrase.Words, pp.Words)
+	setIf(&r.Passphrase.Separator, pp.Separator)
+	if pp.Capitalize != "" {
+		r.Passphrase.Capitalize = pp.Capitalize
+	}
+	setIf(&r.Passphrase.Digit, pp.Digit)
+	setIf(&r.Passphrase.Symbol, pp.Symbol)
+
+	setIf(&r.Strength.MinScore, s.Strength.MinScore)
+	setIf(&r.Strength.Strict, s.Strength.Strict)
+
+	if s.Breach.File != "" {
+		r.Breach.File = s.Breach.File
+	}
+	setIf(&r.Breach.CheckOnAdd, s.Breach.CheckOnAdd)
+}
+
+// ptr returns a pointer to a copy of v.
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// setIf stores *src in dst when src is not nil.
+func setIf[T any](dst *T, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package config_test contains unit tests for the config package.
+// These tests load configuration files from temporary directories.
+package config_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+)
+
+// writeConfig writes content to a configuration file in a temporary
+// directory and returns its path.
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), config.Filename)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+// TestLoadMissing verifies that a missing configuration file yields the
+// zero Config without error.
+func TestLoadMissing(t *testing.T) {
+	cfg, err := config.Load(filepath.Join(t.TempDir(), config.Filename))
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Vault != "" || len(cfg.Profiles) != 0 {
+		t.Fatalf("Load() = %+v; want zero Config", cfg)
+	}
+}
+
+// TestLoadVault verifies that the vault setting is read.
+func TestLoadVault(t *testing.T) {
+	path := writeConfig(t, `vault = "/srv/vault"`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Vault != "/srv/vault" {
+		t.Fatalf("Load().Vault = %q; want /srv/vault", cfg.Vault)
+	}
+}
+
+// TestLoadProfiles verifies that per-profile settings are read.
+func TestLoadProfiles(t *testing.T) {
+	path := writeConfig(t, "[profiles.work]\nvault = \"/srv/work\"\n")
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if cfg.Profiles["work"].Vault != "/srv/work" {
+		t.Fatalf("Load().Profiles = %+v; want work at /srv/work", cfg.Profiles)
+	}
+}
+
+// TestLoadUnknownKey verifies that a misspelled key is reported by name.
+func TestLoadUnknownKey(t *testing.T) {
+	path := writeConfig(t, `valut = "/srv/vault"`)
+
+	_, err := config.Load(path)
+	if err == nil || !strings.Contains(err.Error(), "valut") {
+		t.Fatalf("Load() error = %v; want it to name the key valut", err)
+	}
+}
+
+// TestLoadSettings verifies that global settings, generator policy and
+// profile overrides are read and resolved in order.
+func TestLoadSettings(t *testing.T) {
+	path := writeConfig(t, `
+format = "json"
+mask = true
+clipboard_timeout = "30s"
+backup_retention = 3
+
+[generator]
+length = 32
+symbols = false
+
+[profiles.work]
+format = "text"
+
+[profiles.work.generator]
+length = 16
+`)
+
+	cfg, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+
+	// Without a profile the global settings apply over the defaults.
+	r := cfg.Resolve("")
+	if r.Format != "json" || !r.Mask || r.ClipboardTimeout != 30*time.Second || r.BackupRetention != 3 {
+		t.Fatalf("Resolve(\"\") = %+v; want the global settings", r)
+	}
+	if r.Generator.Length != 32 || r.Generator.Symbols || !r.Generator.Upper {
+		t.Fatalf("Resolve(\"\").Generator = %+v; want length 32 without symbols", r.Generator)
+	}
+
+	// The profile overrides only what it sets.
+	r = cfg.Resolve("work")
+	if r.Format != "text" || !r.Mask || r.Generator.Length != 16 || r.Generator.Symbols {
+		t.Fatalf("Resolve(work) = %+v; want text, masked, length 16 without symbols", r)
+	}
+}
+
+// TestLoadDefaults verifies that an empty configuration resolves to the
+// defaults.
+func TestLoadDefaults(t *testing.T) {
+	r := config.Config{}.Resolve("")
+	if r.Format != config.FormatText || r.Mask || r.ClipboardTimeout != 45*time.Second || r.Generator.Length != 20 {
+		t.Fatalf("Resolve() = %+v; want the defaults", r)
+	}
+}
+
+// TestLoadInvalidValue verifies that invalid values are reported with the
+// full name of the offending key.
+func TestLoadInvalidValue(t *testing.T) {
+	tests := []struct {
+		content string
+		key     string
+	}{
+		{`format = "xml"`, "format"},
+		{`backup_retention = -1`, "backup_retention"},
+		{`clipboard_timeout = "soon"`, "clipboard_timeout"},
+		{"[generator]\nlength = 0\n", "generator.length"},
+		{"[profiles.work]\nformat = \"yaml\"\n", "profiles.work.format"},
+		{"[passphrase]\ncapitalize = \"title\"\n", "passphrase.capitalize"},
+		{"[strength]\nmin_score = 5\n", "strength.min_score"},
+		{"[profiles.work.generator]\ncolour = true\n", "profiles.work.generator.colour"},
+	}
+
+	for _, tt := range tests {
+		_, err := config.Load(writeConfig(t, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.key) {
+			t.Errorf("Load(%q) error = %v; want it to name %s", tt.content, err, tt.key)
+		}
+	}
+}
+
+// TestGetSet verifies that keys can be set, read back, unset and saved.
+func TestGetSet(t *testing.T) {
+	var cfg config.Config
+
+	// Set a global key and a profile key, including a dotted profile name.
+	for key, value := range map[string]string{
+		"mask":                           "true",
+		"generator.length":               "24",
+		"profiles.work.format":           "json",
+		"profiles.my.team.vault":         "/srv/team",
+		"profiles.work.generator.digits": "false",
+	} {
+		if err := cfg.Set(key, value); err != nil {
+			t.Fatalf("Set(%s) failed: %v", key, err)
+		}
+	}
+	if cfg.Profiles["my.team"].Vault != "/srv/team" {
+		t.Fatalf("Profiles = %+v; want my.team at /srv/team", cfg.Profiles)
+	}
+
+	// A profile key falls back to the global setting, then the default.
+	for key, want := range map[string]string{
+		"mask":                           "true",
+		"profiles.work.mask":             "true",
+		"profiles.work.format":           "json",
+		"profiles.work.generator.length": "24",
+		"profiles.work.generator.digits": "false",
+		"format":                         "text",
+	} {
+		if got, err := cfg.Get(key); err != nil || got != want {
+			t.Errorf("Get(%s) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+
+	// Invalid values and unknown keys are rejected.
+	var keyErr *config.KeyError
+	if err := cfg.Set("profiles.work.mask", "maybe"); !errors.As(err, &keyErr) || keyErr.Key != "profiles.work.mask" {
+		t.Errorf("Set(mask=maybe) error = %v; want a KeyError for profiles.work.mask", err)
+	}
+	if err := cfg.Set("colour", "red"); !errors.Is(err, config.ErrUnknownKey) {
+		t.Errorf("Set(colour) error = %v; want ErrUnknownKey", err)
+	}
+
+	// Numbers above the upper bounds are rejected.
+	for key, value := range map[string]string{
+		"generator.length":               "1025",
+		"generator.min_digits":           "1025",
+		"passphrase.words":               "65",
+		"backup_retention":               "101",
+		"profiles.work.backup_retention": "1000000",
+	} {
+		if err := cfg.Set(key, value); !errors.As(err, &keyErr) || keyErr.Key != key {
+			t.Errorf("Set(%s=%s) error = %v; want a KeyError for %s", key, value, err, key)
+		}
+	}
+	for key, value := range map[string]string{"generator.length": "1024", "passphrase.words": "64", "backup_retention": "100"} {
+		if err := cfg.Set("profiles.max."+key, value); err != nil {
+			t.Errorf("Set(%s=%s) failed: %v", key, value, err)
+		}
+	}
+
+	// The passphrase separator can be set to the empty string.
+	if err := cfg.Set("passphrase.separator", `""`); err != nil {
+		t.Fatalf("Set(passphrase.separator) failed: %v", err)
+	}
+	if sep := cfg.Resolve("").Passphrase.Separator; sep != "" {
+		t.Errorf("Resolve().Passphrase.Separator = %q; want empty", sep)
+	}
+
+	// Unsetting falls back to the default again.
+	if err := cfg.Set("mask", ""); err != nil {
+		t.Fatalf("Set(mask=) failed: %v", err)
+	}
+	if got, _ := cfg.Get("mask"); got != "false" {
+		t.Errorf("Get(mask) after unset = %q; want false", got)
+	}
+
+	// The saved file loads back to the same configuration.
+	path := filepath.Join(t.TempDir(), "pwdcli", config.Filename)
+	if err := config.Save(path, cfg); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	loaded, err := config.Load(path)
+	if err != nil {
+		t.Fatalf("Load() after Save() failed: %v", err)
+	}
+	if !reflect.DeepEqual(loaded.List(), cfg.List()) {
+		t.Fatalf("Load() after Save() = %+v; want %+v", loaded.List(), cfg.List())
+	}
+}
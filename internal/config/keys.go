@@ -0,0 +1,371 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnknownKey is returned by Get and Set for a key that does not exist.
+var ErrUnknownKey = errors.New("unknown key")
+
+// Upper bounds of the numeric settings, so that a stray extra digit cannot
+// make pwdcli generate megabytes of password or keep thousands of backups.
+const (
+	maxBackups = 100
+	maxLength  = 1024
+	maxWords   = 64
+)
+
+// key describes a setting that can be read and written by its dotted name.
+type key struct {
+	// name is the dotted name of the key, such as "generator.length".
+	name string
+
+	// get returns the value of the key in s, and false if it is unset.
+	get func(s *Settings) (string, bool)
+
+	// set parses and validates value and stores it in s. An empty value
+	// unsets the key.
+	set func(s *Settings, value string) error
+}
+
+// keys lists the settings available globally and per profile.
+var keys = []key{
+	{
+		name: "format",
+		get: func(s *Settings) (string, bool) {
+			return s.Format, s.Format != ""
+		},
+		set: func(s *Settings, value string) error {
+			if value != "" && value != FormatText && value != FormatJSON {
+				return fmt.Errorf("must be %q or %q, not %q", FormatText, FormatJSON, value)
+			}
+			s.Format = value
+			return nil
+		},
+	},
+	boolKey("mask", func(s *Settings) **bool { return &s.Mask }),
+	{
+		name: "clipboard_timeout",
+		get: func(s *Settings) (string, bool) {
+			if s.ClipboardTimeout == nil {
+				return "", false
+			}
+			return s.ClipboardTimeout.String(), true
+		},
+		set: func(s *Settings, value string) error {
+			if value == "" {
+				s.ClipboardTimeout = nil
+				return nil
+			}
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("must be a duration such as 45s, not %q", value)
+			}
+			if d < 0 {
+				return errors.New("must not be negative")
+			}
+			s.ClipboardTimeout = &Duration{d}
+			return nil
+		},
+	},
+	intKey("backup_retention", 0, maxBackups, func(s *Settings) **int { return &s.BackupRetention }),
+	intKey("generator.length", 1, maxLength, func(s *Settings) **int { return &s.Generator.Length }),
+	boolKey("generator.upper", func(s *Settings) **bool { return &s.Generator.Upper }),
+	boolKey("generator.lower", func(s *Settings) **bool { return &s.Generator.Lower }),
+	boolKey("generator.digits", func(s *Settings) **bool { return &s.Generator.Digits }),
+	boolKey("generator.symbols", func(s *Settings) **bool { return &s.Generator.Symbols }),
+	boolKey("generator.exclude_ambiguous", func(s *Settings) **bool { return &s.Generator.ExcludeAmbiguous }),
+	intKey("generator.min_upper", 0, maxLength, func(s *Settings) **int { return &s.Generator.MinUpper }),
+	intKey("generator.min_lower", 0, maxLength, func(s *Settings) **int { return &s.Generator.MinLower }),
+	intKey("generator.min_digits", 0, maxLength, func(s *Settings) **int { return &s.Generator.MinDigits }),
+	intKey("generator.min_symbols", 0, maxLength, func(s *Settings) **int { return &s.Generator.MinSymbols }),
+	{
+		name: "generator.alphabet",
+		get: func(s *Settings) (string, bool) {
+			return s.Generator.Alphabet, s.Generator.Alphabet != ""
+		},
+		set: func(s *Settings, value string) error {
+			if value != "" && len([]rune(value)) < 2 {
+				return errors.New("must contain at least two characters")
+			}
+			s.Generator.Alphabet = value
+			return nil
+		},
+	},
+	intKey("passphrase.words", 1, maxWords, func(s *Settings) **int { return &s.Passphrase.Words }),
+	{
+		// The separator may be empty, so its value is quoted: an empty
+		// value unsets it while "" sets the empty separator.
+		name: "passphrase.separator",
+		get: func(s *Settings) (string, bool) {
+			if s.Passphrase.Separator == nil {
+				return "", false
+			}
+			return strconv.Quote(*s.Passphrase.Separator), true
+		},
+		set: func(s *Settings, value string) error {
+			if value == "" {
+				s.Passphrase.Separator = nil
+				return nil
+			}
+			sep, err := strconv.Unquote(value)
+			if err != nil {
+				sep = value
+			}
+			s.Passphrase.Separator = &sep
+			return nil
+		},
+	},
+	{
+		name: "passphrase.capitalize",
+		get: func(s *Settings) (string, bool) {
+			return s.Passphrase.Capitalize, s.Passphrase.Capitalize != ""
+		},
+		set: func(s *Settings, value string) error {
+			switch value {
+			case "", "none", "first", "all", "random":
+				s.Passphrase.Capitalize = value
+				return nil
+			}
+			return fmt.Errorf("must be none, first, all or random, not %q", value)
+		},
+	},
+	boolKey("passphrase.digit", func(s *Settings) **bool { return &s.Passphrase.Digit }),
+	boolKey("passphrase.symbol", func(s *Settings) **bool { return &s.Passphrase.Symbol }),
+	intKey("strength.min_score", 0, 4, func(s *Settings) **int { return &s.Strength.MinScore }),
+	boolKey("strength.strict", func(s *Settings) **bool { return &s.Strength.Strict }),
+	{
+		name: "breach.file",
+		get: func(s *Settings) (string, bool) {
+			return s.Breach.File, s.Breach.File != ""
+		},
+		set: func(s *Settings, value string) error {
+			s.Breach.File = value
+			return nil
+		},
+	},
+	boolKey("breach.check_on_add", func(s *Settings) **bool { return &s.Breach.CheckOnAdd }),
+}
+
+// boolKey returns a key for the boolean setting selected by field.
+func boolKey(name string, field func(*Settings) **bool) key {
+	return key{
+		name: name,
+		get: func(s *Settings) (string, bool) {
+			p := *field(s)
+			if p == nil {
+				return "", false
+			}
+			return strconv.FormatBool(*p), true
+		},
+		set: func(s *Settings, value string) error {
+			if value == "" {
+				*field(s) = nil
+				return nil
+			}
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("must be true or false, not %q", value)
+			}
+			*field(s) = &b
+			return nil
+		},
+	}
+}
+
+// intKey returns a key for the integer setting selected by field, which
+// must lie between minimum and maximum.
+func intKey(name string, minimum, maximum int, field func(*Settings) **int) key {
+	return key{
+		name: name,
+		get: func(s *Settings) (string, bool) {
+			p := *field(s)
+			if p == nil {
+				return "", false
+			}
+			return strconv.Itoa(*p), true
+		},
+		set: func(s *Settings, value string) error {
+			if value == "" {
+				*field(s) = nil
+				return nil
+			}
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("must be a whole number, not %q", value)
+			}
+			if n < minimum {
+				return fmt.Errorf("must be at least %d", minimum)
+			}
+			if n > maximum {
+				return fmt.Errorf("must be at most %d", maximum)
+			}
+			*field(s) = &n
+			return nil
+		},
+	}
+}
+
+// lookupKey returns the setting with the given name.
+func lookupKey(name string) (key, bool) {
+	for _, k := range keys {
+		if k.name == name {
+			return k, true
+		}
+	}
+	return key{}, false
+}
+
+// splitKey splits a dotted key into the profile it belongs to, empty for
+// a global key, and the setting name. Profile names may contain dots, so
+// the setting is matched as the longest known suffix.
+func splitKey(full string) (profileName, name string, err error) {
+	rest, ok := strings.CutPrefix(full, "profiles.")
+	if !ok {
+		if full == "vault" {
+			return "", full, nil
+		}
+		if _, ok := lookupKey(full); ok {
+			return "", full, nil
+		}
+		return "", "", fmt.Errorf("%w %q", ErrUnknownKey, full)
+	}
+
+	names := []string{"vault"}
+	for _, k := range keys {
+		names = append(names, k.name)
+	}
+	sort.Slice(names, func(i, j int) bool { return len(names[i]) > len(names[j]) })
+	for _, n := range names {
+		if p, ok := strings.CutSuffix(rest, "."+n); ok && p != "" {
+			return p, n, nil
+		}
+	}
+	return "", "", fmt.Errorf("%w %q", ErrUnknownKey, full)
+}
+
+// Get returns the effective value of the dotted key, such as "format" or
+// "profiles.work.generator.length". A profile key that is not set falls
+// back to the global setting and then to Defaults, like Resolve does.
+// An unset vault yields the empty string.
+func (c Config) Get(full string) (string, error) {
+	profileName, name, err := splitKey(full)
+	if err != nil {
+		return "", err
+	}
+
+	if name == "vault" {
+		if profileName != "" {
+			return c.Profiles[profileName].Vault, nil
+		}
+		return c.Vault, nil
+	}
+
+	k, _ := lookupKey(name)
+	levels := []Settings{c.Settings, Defaults}
+	if p, ok := c.Profiles[profileName]; ok {
+		levels = append([]Settings{p.Settings}, levels...)
+	}
+	for i := range levels {
+		if v, ok := k.get(&levels[i]); ok {
+			return v, nil
+		}
+	}
+	return "", nil
+}
+
+// Set parses value and stores it under the dotted key. An empty value
+// unsets the key. Invalid values are rejected with a *KeyError naming
+// the key.
+func (c *Config) Set(full, value string) error {
+	profileName, name, err := splitKey(full)
+	if err != nil {
+		return err
+	}
+
+	if profileName == "" {
+		if name == "vault" {
+			c.Vault = value
+			return nil
+		}
+		k, _ := lookupKey(name)
+		if err := k.set(&c.Settings, value); err != nil {
+			return &KeyError{Key: full, Err: err}
+		}
+		return nil
+	}
+
+	p := c.Profiles[profileName]
+	if name == "vault" {
+		p.Vault = value
+	} else {
+		k, _ := lookupKey(name)
+		if err := k.set(&p.Settings, value); err != nil {
+			return &KeyError{Key: full, Err: err}
+		}
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]Profile{}
+	}
+	c.Profiles[profileName] = p
+	return nil
+}
+
+// Setting is a key and its value as reported by List.
+type Setting struct {
+	// Key is the dotted name of the setting.
+	Key string
+
+	// Value is the value in the form accepted by Set.
+	Value string
+
+	// Default reports whether the value comes from Defaults because the
+	// configuration file does not set it.
+	Default bool
+}
+
+// List returns every global setting with its effective value, followed by
+// the vault and the settings that each profile sets, sorted by profile.
+func (c Config) List() []Setting {
+	var list []Setting
+	if c.Vault != "" {
+		list = append(list, Setting{Key: "vault", Value: c.Vault})
+	}
+	for _, k := range keys {
+		if v, ok := k.get(&c.Settings); ok {
+			list = append(list, Setting{Key: k.name, Value: v})
+		} else {
+			v, _ := k.get(&Defaults)
+			list = append(list, Setting{Key: k.name, Value: v, Default: true})
+		}
+	}
+
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		p := c.Profiles[name]
+		prefix := "profiles." + name + "."
+		if p.Vault != "" {
+			list = append(list, Setting{Key: prefix + "vault", Value: p.Vault})
+		}
+		for _, k := range keys {
+			if v, ok := k.get(&p.Settings); ok {
+				list = append(list, Setting{Key: prefix + k.name, Value: v})
+			}
+		}
+	}
+	return list
+}
@@ -0,0 +1,249 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package generate produces random passwords from crypto/rand according to
+// a Policy: a length, the character classes to draw from, characters to
+// leave out, a minimum count per class and an optional custom alphabet.
+//
+// Every character is drawn uniformly from its pool by rejection sampling,
+// so no character is more likely than another, and characters placed to
+// satisfy the minimums are moved to uniformly random positions.
+//
+// Passphrase builds memorable passphrases from the embedded EFF large
+// wordlist instead, and reports their entropy in bits.
+package generate
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+// Character classes.
+const (
+	Upper   = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	Lower   = "abcdefghijklmnopqrstuvwxyz"
+	Digits  = "0123456789"
+	Symbols = "!#$%&()*+,-./:;<=>?@[]^_{|}~"
+)
+
+// Ambiguous lists the characters left out by Policy.ExcludeAmbiguous
+// because they are easily confused with one another.
+const Ambiguous = "0OoIl1|"
+
+var (
+	// ErrLength is returned when the length is not positive or is too short
+	// for the required minimums.
+	ErrLength = errors.New("password length too short for the policy")
+
+	// ErrEmptyAlphabet is returned when the policy leaves no character to
+	// choose from.
+	ErrEmptyAlphabet = errors.New("no characters to generate a password from")
+)
+
+// Policy describes the passwords to generate.
+type Policy struct {
+	// Length is the number of characters.
+	Length int
+
+	// Upper, Lower, Digits and Symbols enable the character classes.
+	Upper   bool
+	Lower   bool
+	Digits  bool
+	Symbols bool
+
+	// ExcludeAmbiguous leaves out the characters in Ambiguous.
+	ExcludeAmbiguous bool
+
+	// MinUpper, MinLower, MinDigits and MinSymbols are the minimum number
+	// of characters of each class.
+	MinUpper   int
+	MinLower   int
+	MinDigits  int
+	MinSymbols int
+
+	// Alphabet, when set, replaces the character classes: every character
+	// is drawn from it. The minimums then count the characters of the
+	// alphabet that belong to each class.
+	Alphabet string
+}
+
+// DefaultPolicy generates 20 characters from all classes.
+var DefaultPolicy = Policy{
+	Length:  20,
+	Upper:   true,
+	Lower:   true,
+	Digits:  true,
+	Symbols: true,
+}
+
+// class is a pool of characters with a minimum count.
+type class struct {
+	name  string
+	chars []rune
+	min   int
+}
+
+// Password returns a new password following p, drawn from crypto/rand.
+func Password(p Policy) (string, error) {
+	return PasswordFrom(rand.Reader, p)
+}
+
+// PasswordFrom is like Password but reads randomness from r.
+func PasswordFrom(r io.Reader, p Policy) (string, error) {
+	pool, classes, err := p.pools()
+	if err != nil {
+		return "", err
+	}
+
+	required := 0
+	for _, c := range classes {
+		required += c.min
+	}
+	if p.Length < 1 || p.Length < required {
+		return "", fmt.Errorf("%w: length %d, minimums %d", ErrLength, p.Length, required)
+	}
+
+	// Satisfy the minimums first, then fill up from the whole pool.
+	out := make([]rune, 0, p.Length)
+	for _, c := range classes {
+		for range c.min {
+			ch, err := pick(r, c.chars)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, ch)
+		}
+	}
+	for len(out) < p.Length {
+		ch, err := pick(r, pool)
+		if err != nil {
+			return "", err
+		}
+		out = append(out, ch)
+	}
+
+	// Fisher-Yates shuffle, so required characters can be anywhere.
+	for i := len(out) - 1; i > 0; i-- {
+		j, err := Intn(r, i+1)
+		if err != nil {
+			return "", err
+		}
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out), nil
+}
+
+// Chars returns every character a password following p may contain,
+// in a stable order.
+func (p Policy) Chars() (string, error) {
+	pool, _, err := p.pools()
+	return string(pool), err
+}
+
+// pools returns every character p may use and the classes with a minimum.
+func (p Policy) pools() ([]rune, []class, error) {
+	all := []class{
+		{"upper", []rune(Upper), p.MinUpper},
+		{"lower", []rune(Lower), p.MinLower},
+		{"digits", []rune(Digits), p.MinDigits},
+		{"symbols", []rune(Symbols), p.MinSymbols},
+	}
+	enabled := []bool{p.Upper, p.Lower, p.Digits, p.Symbols}
+
+	var pool []rune
+	if p.Alphabet != "" {
+		pool = unique(p.Alphabet)
+	} else {
+		for i, c := range all {
+			if enabled[i] {
+				pool = append(pool, c.chars...)
+			}
+		}
+	}
+	if p.ExcludeAmbiguous {
+		pool = without(pool, Ambiguous)
+	}
+	if len(pool) == 0 {
+		return nil, nil, ErrEmptyAlphabet
+	}
+
+	// Restrict the classes to the pool and keep those with a minimum.
+	var classes []class
+	for _, c := range all {
+		if c.min < 0 {
+			return nil, nil, fmt.Errorf("negative minimum for %s", c.name)
+		}
+		if c.min == 0 {
+			continue
+		}
+		c.chars = intersect(c.chars, pool)
+		if len(c.chars) == 0 {
+			return nil, nil, fmt.Errorf("a minimum of %d %s is required, but none are allowed", c.min, c.name)
+		}
+		classes = append(classes, c)
+	}
+	return pool, classes, nil
+}
+
+// Intn returns a uniformly distributed integer in [0, n) read from r.
+// It uses rejection sampling, so it has no modulo bias.
+func Intn(r io.Reader, n int) (int, error) {
+	v, err := rand.Int(r, big.NewInt(int64(n)))
+	if err != nil {
+		return 0, err
+	}
+	return int(v.Int64()), nil
+}
+
+// pick returns a uniformly chosen element of chars.
+func pick(r io.Reader, chars []rune) (rune, error) {
+	i, err := Intn(r, len(chars))
+	if err != nil {
+		return 0, err
+	}
+	return chars[i], nil
+}
+
+// unique returns the characters of s without duplicates, in order, so
+// that repeating a character in a custom alphabet does not favour it.
+func unique(s string) []rune {
+	seen := map[rune]bool{}
+	var out []rune
+	for _, ch := range s {
+		if !seen[ch] {
+			seen[ch] = true
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// without returns chars minus the characters in exclude.
+func without(chars []rune, exclude string) []rune {
+	var out []rune
+	for _, ch := range chars {
+		if !strings.ContainsRune(exclude, ch) {
+			out = append(out, ch)
+		}
+	}
+	return out
+}
+
+// intersect returns the characters of chars that are also in pool.
+func intersect(chars, pool []rune) []rune {
+	var out []rune
+	for _, ch := range chars {
+		for _, p := range pool {
+			if ch == p {
+				out = append(out, ch)
+				break
+			}
+		}
+	}
+	return out
+}
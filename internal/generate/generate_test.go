@@ -0,0 +1,233 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package generate_test contains unit tests for the generate package.
+// Besides checking the policy rules, they use chi-square tests to check
+// that characters and positions are uniformly distributed.
+package generate_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/generate"
+)
+
+// chiSquare returns the chi-square statistic of counts against a uniform
+// distribution.
+func chiSquare(counts []int) float64 {
+	total := 0
+	for _, c := range counts {
+		total += c
+	}
+	expected := float64(total) / float64(len(counts))
+
+	var stat float64
+	for _, c := range counts {
+		d := float64(c) - expected
+		stat += d * d / expected
+	}
+	return stat
+}
+
+// critical returns the chi-square value that a uniform source exceeds with
+// a probability of about one in a million for df degrees of freedom, using
+// the Wilson-Hilferty approximation. The threshold is this strict so that
+// the tests practically never fail by chance.
+func critical(df int) float64 {
+	const z = 4.75
+	k := float64(df)
+	v := 1 - 2/(9*k) + z*math.Sqrt(2/(9*k))
+	return k * v * v * v
+}
+
+// TestIntnUniform verifies that Intn is uniform for a range that is not a
+// power of two, where modulo reduction would be biased.
+func TestIntnUniform(t *testing.T) {
+	const n, draws = 62, 62 * 2000
+
+	counts := make([]int, n)
+	for range draws {
+		v, err := generate.Intn(rand.Reader, n)
+		if err != nil {
+			t.Fatalf("Intn() failed: %v", err)
+		}
+		counts[v]++
+	}
+
+	if stat := chiSquare(counts); stat > critical(n-1) {
+		t.Fatalf("chi-square = %.1f > %.1f; Intn is not uniform", stat, critical(n-1))
+	}
+}
+
+// TestChiSquareDetectsModuloBias verifies that the statistical test is
+// sensitive enough to catch the bias of reducing a random byte modulo n.
+func TestChiSquareDetectsModuloBias(t *testing.T) {
+	const n, draws = 62, 62 * 2000
+
+	buf := make([]byte, draws)
+	if _, err := rand.Read(buf); err != nil {
+		t.Fatalf("rand.Read() failed: %v", err)
+	}
+	counts := make([]int, n)
+	for _, b := range buf {
+		counts[int(b)%n]++
+	}
+
+	if stat := chiSquare(counts); stat <= critical(n-1) {
+		t.Fatalf("chi-square = %.1f <= %.1f; the biased source was not detected", stat, critical(n-1))
+	}
+}
+
+// TestPasswordCharactersUniform verifies that every allowed character is
+// equally likely in generated passwords.
+func TestPasswordCharactersUniform(t *testing.T) {
+	chars, err := generate.DefaultPolicy.Chars()
+	if err != nil {
+		t.Fatalf("Chars() failed: %v", err)
+	}
+
+	// Count every character of 5000 passwords.
+	index := map[rune]int{}
+	for i, ch := range chars {
+		index[ch] = i
+	}
+	counts := make([]int, len(index))
+	for range 5000 {
+		pwd, err := generate.Password(generate.DefaultPolicy)
+		if err != nil {
+			t.Fatalf("Password() failed: %v", err)
+		}
+		for _, ch := range pwd {
+			i, ok := index[ch]
+			if !ok {
+				t.Fatalf("Password() = %q contains %q outside the policy", pwd, ch)
+			}
+			counts[i]++
+		}
+	}
+
+	if stat := chiSquare(counts); stat > critical(len(counts)-1) {
+		t.Fatalf("chi-square = %.1f > %.1f; characters are not uniform", stat, critical(len(counts)-1))
+	}
+}
+
+// TestPasswordPositionsUniform verifies that characters added to satisfy a
+// minimum are not kept at predictable positions.
+func TestPasswordPositionsUniform(t *testing.T) {
+	p := generate.Policy{Length: 8, MinDigits: 1, Alphabet: generate.Upper + "7"}
+
+	// With one required digit among uppercase letters, the digit should
+	// appear at every position equally often.
+	counts := make([]int, p.Length)
+	for range 20000 {
+		pwd, err := generate.Password(p)
+		if err != nil {
+			t.Fatalf("Password() failed: %v", err)
+		}
+		for i, ch := range pwd {
+			if ch == '7' {
+				counts[i]++
+			}
+		}
+	}
+
+	if stat := chiSquare(counts); stat > critical(len(counts)-1) {
+		t.Fatalf("chi-square = %.1f > %.1f; digit positions are not uniform: %v", stat, critical(len(counts)-1), counts)
+	}
+}
+
+// TestPasswordPolicy verifies length, classes, minimums, exclusions and
+// custom alphabets.
+func TestPasswordPolicy(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy generate.Policy
+		check  func(pwd string) bool
+	}{
+		{
+			name:   "digits only",
+			policy: generate.Policy{Length: 12, Digits: true},
+			check: func(pwd string) bool {
+				return strings.Trim(pwd, generate.Digits) == ""
+			},
+		},
+		{
+			name:   "minimums",
+			policy: generate.Policy{Length: 6, Upper: true, Lower: true, Digits: true, Symbols: true, MinUpper: 2, MinDigits: 2, MinSymbols: 2},
+			check: func(pwd string) bool {
+				return count(pwd, generate.Upper) >= 2 && count(pwd, generate.Digits) >= 2 && count(pwd, generate.Symbols) >= 2
+			},
+		},
+		{
+			name:   "exclude ambiguous",
+			policy: generate.Policy{Length: 200, Upper: true, Lower: true, Digits: true, Symbols: true, ExcludeAmbiguous: true},
+			check: func(pwd string) bool {
+				return !strings.ContainsAny(pwd, generate.Ambiguous)
+			},
+		},
+		{
+			name:   "custom alphabet",
+			policy: generate.Policy{Length: 30, Alphabet: "αβγ", Symbols: true},
+			check: func(pwd string) bool {
+				return strings.Trim(pwd, "αβγ") == ""
+			},
+		},
+		{
+			name:   "custom alphabet with minimum",
+			policy: generate.Policy{Length: 4, Alphabet: "abcdef0", MinDigits: 3},
+			check: func(pwd string) bool {
+				return strings.Count(pwd, "0") >= 3
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for range 50 {
+			pwd, err := generate.Password(tt.policy)
+			if err != nil {
+				t.Fatalf("%s: Password() failed: %v", tt.name, err)
+			}
+			if n := len([]rune(pwd)); n != tt.policy.Length {
+				t.Fatalf("%s: Password() = %q has %d characters; want %d", tt.name, pwd, n, tt.policy.Length)
+			}
+			if !tt.check(pwd) {
+				t.Fatalf("%s: Password() = %q violates the policy", tt.name, pwd)
+			}
+		}
+	}
+}
+
+// TestPasswordInvalidPolicy verifies that policies that cannot be met are
+// rejected.
+func TestPasswordInvalidPolicy(t *testing.T) {
+	// Minimums longer than the password.
+	if _, err := generate.Password(generate.Policy{Length: 3, Upper: true, MinUpper: 4}); !errors.Is(err, generate.ErrLength) {
+		t.Errorf("Password(too short) error = %v; want ErrLength", err)
+	}
+
+	// No class enabled.
+	if _, err := generate.Password(generate.Policy{Length: 8}); !errors.Is(err, generate.ErrEmptyAlphabet) {
+		t.Errorf("Password(no classes) error = %v; want ErrEmptyAlphabet", err)
+	}
+
+	// A minimum for a class that is not allowed.
+	if _, err := generate.Password(generate.Policy{Length: 8, Lower: true, MinDigits: 1}); err == nil {
+		t.Error("Password(minimum for a disabled class) succeeded; want an error")
+	}
+}
+
+// count returns how many characters of s are in chars.
+func count(s, chars string) int {
+	n := 0
+	for _, ch := range s {
+		if strings.ContainsRune(chars, ch) {
+			n++
+		}
+	}
+	return n
+}
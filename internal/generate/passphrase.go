@@ -0,0 +1,198 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generate
+
+import (
+	"crypto/rand"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// effLarge is the EFF large wordlist of 7776 words, one per line after the
+// five dice rolls that select it. It is published by the Electronic
+// Frontier Foundation under the CC BY 3.0 license:
+// https://www.eff.org/deeplinks/2016/07/new-wordlists-random-passphrases
+//
+//go:embed eff_large_wordlist.txt
+var effLarge string
+
+// EFFWords returns the words of the EFF large wordlist.
+var EFFWords = sync.OnceValue(func() []string {
+	var words []string
+	for line := range strings.Lines(effLarge) {
+		if _, word, ok := strings.Cut(strings.TrimSpace(line), "\t"); ok {
+			words = append(words, word)
+		}
+	}
+	return words
+})
+
+// Capitalization selects how the words of a passphrase are capitalized.
+type Capitalization string
+
+// Capitalization modes.
+const (
+	// CapNone keeps the words in lower case.
+	CapNone Capitalization = "none"
+
+	// CapFirst capitalizes the first letter of every word.
+	CapFirst Capitalization = "first"
+
+	// CapAll writes every word in upper case.
+	CapAll Capitalization = "all"
+
+	// CapRandom capitalizes the first letter of each word with a
+	// probability of one half, adding one bit of entropy per word.
+	CapRandom Capitalization = "random"
+)
+
+// ErrWords is returned when a passphrase policy asks for no words or the
+// wordlist is too short.
+var ErrWords = errors.New("a passphrase needs at least one word from a list of two or more")
+
+// PassphrasePolicy describes the passphrases to generate.
+type PassphrasePolicy struct {
+	// Words is the number of words.
+	Words int
+
+	// Separator is placed between words.
+	Separator string
+
+	// Capitalize selects how words are capitalized; empty means CapNone.
+	Capitalize Capitalization
+
+	// Digit appends a random digit to a randomly chosen word.
+	Digit bool
+
+	// Symbol appends a random character of Symbols to a randomly chosen
+	// word, after the digit if both land on the same word.
+	Symbol bool
+
+	// Wordlist is the list to draw words from; nil means EFFWords.
+	Wordlist []string
+}
+
+// DefaultPassphrasePolicy generates six words separated by dashes, about
+// 77 bits of entropy.
+var DefaultPassphrasePolicy = PassphrasePolicy{
+	Words:      6,
+	Separator:  "-",
+	Capitalize: CapNone,
+}
+
+// Passphrase returns a new passphrase following p, drawn from crypto/rand,
+// and its entropy in bits.
+func Passphrase(p PassphrasePolicy) (string, float64, error) {
+	return PassphraseFrom(rand.Reader, p)
+}
+
+// PassphraseFrom is like Passphrase but reads randomness from r.
+func PassphraseFrom(r io.Reader, p PassphrasePolicy) (string, float64, error) {
+	entropy, err := p.Entropy()
+	if err != nil {
+		return "", 0, err
+	}
+
+	list := p.Wordlist
+	if list == nil {
+		list = EFFWords()
+	}
+
+	words := make([]string, p.Words)
+	for i := range words {
+		n, err := Intn(r, len(list))
+		if err != nil {
+			return "", 0, err
+		}
+		words[i], err = capitalize(r, list[n], p.Capitalize)
+		if err != nil {
+			return "", 0, err
+		}
+	}
+
+	// Append the extra characters to randomly chosen words.
+	extras := []struct {
+		enabled bool
+		chars   string
+	}{{p.Digit, Digits}, {p.Symbol, Symbols}}
+	for _, e := range extras {
+		if !e.enabled {
+			continue
+		}
+		w, err := Intn(r, len(words))
+		if err != nil {
+			return "", 0, err
+		}
+		ch, err := pick(r, []rune(e.chars))
+		if err != nil {
+			return "", 0, err
+		}
+		words[w] += string(ch)
+	}
+
+	return strings.Join(words, p.Separator), entropy, nil
+}
+
+// Entropy returns the entropy in bits of passphrases generated with p,
+// assuming an attacker knows the wordlist and the policy.
+func (p PassphrasePolicy) Entropy() (float64, error) {
+	size := len(p.Wordlist)
+	if p.Wordlist == nil {
+		size = len(EFFWords())
+	}
+	if p.Words < 1 || size < 2 {
+		return 0, ErrWords
+	}
+
+	bits := float64(p.Words) * math.Log2(float64(size))
+	switch p.Capitalize {
+	case "", CapNone, CapFirst, CapAll:
+	case CapRandom:
+		bits += float64(p.Words)
+	default:
+		return 0, fmt.Errorf("unknown capitalization %q", p.Capitalize)
+	}
+
+	// Each extra character adds its own choices and the word it lands on.
+	if p.Digit {
+		bits += math.Log2(float64(len(Digits))) + math.Log2(float64(p.Words))
+	}
+	if p.Symbol {
+		bits += math.Log2(float64(utf8.RuneCountInString(Symbols))) + math.Log2(float64(p.Words))
+	}
+	return bits, nil
+}
+
+// capitalize applies mode to word, reading a random bit from r for
+// CapRandom.
+func capitalize(r io.Reader, word string, mode Capitalization) (string, error) {
+	switch mode {
+	case CapFirst:
+		return upperFirst(word), nil
+	case CapAll:
+		return strings.ToUpper(word), nil
+	case CapRandom:
+		bit, err := Intn(r, 2)
+		if err != nil || bit == 0 {
+			return word, err
+		}
+		return upperFirst(word), nil
+	default:
+		return word, nil
+	}
+}
+
+// upperFirst returns word with its first letter in upper case.
+func upperFirst(word string) string {
+	first, size := utf8.DecodeRuneInString(word)
+	return string(unicode.ToUpper(first)) + word[size:]
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package generate_test
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+	"unicode"
+
+	"github.com/nullzeiger/pwdcli/internal/generate"
+)
+
+// TestEFFWords verifies that the embedded wordlist is complete: 7776
+// distinct lower-case words, one for every roll of five dice.
+func TestEFFWords(t *testing.T) {
+	words := generate.EFFWords()
+	if len(words) != 7776 {
+		t.Fatalf("len(EFFWords()) = %d; want 7776", len(words))
+	}
+	if words[0] != "abacus" || words[len(words)-1] != "zoom" {
+		t.Fatalf("EFFWords() runs from %q to %q; want abacus to zoom", words[0], words[len(words)-1])
+	}
+
+	seen := map[string]bool{}
+	for _, w := range words {
+		if seen[w] || strings.ToLower(w) != w {
+			t.Fatalf("EFFWords() contains %q twice or not in lower case", w)
+		}
+		seen[w] = true
+	}
+}
+
+// TestPassphrase verifies the word count, separator, capitalization and
+// extra characters of generated passphrases.
+func TestPassphrase(t *testing.T) {
+	p := generate.PassphrasePolicy{
+		Words:      5,
+		Separator:  " ",
+		Capitalize: generate.CapFirst,
+		Digit:      true,
+		Symbol:     true,
+	}
+
+	for range 50 {
+		phrase, _, err := generate.Passphrase(p)
+		if err != nil {
+			t.Fatalf("Passphrase() failed: %v", err)
+		}
+
+		words := strings.Split(phrase, " ")
+		if len(words) != 5 {
+			t.Fatalf("Passphrase() = %q has %d words; want 5", phrase, len(words))
+		}
+		for _, w := range words {
+			if !unicode.IsUpper([]rune(w)[0]) {
+				t.Fatalf("Passphrase() = %q has an uncapitalized word %q", phrase, w)
+			}
+		}
+		if !strings.ContainsAny(phrase, generate.Digits) || !strings.ContainsAny(phrase, generate.Symbols) {
+			t.Fatalf("Passphrase() = %q lacks a digit or a symbol", phrase)
+		}
+	}
+}
+
+// TestPassphraseEntropy verifies the entropy reported for several
+// policies.
+func TestPassphraseEntropy(t *testing.T) {
+	perWord := math.Log2(7776)
+	tests := []struct {
+		policy generate.PassphrasePolicy
+		want   float64
+	}{
+		{generate.DefaultPassphrasePolicy, 6 * perWord},
+		{generate.PassphrasePolicy{Words: 4, Capitalize: generate.CapAll}, 4 * perWord},
+		{generate.PassphrasePolicy{Words: 4, Capitalize: generate.CapRandom}, 4*perWord + 4},
+		{generate.PassphrasePolicy{Words: 4, Digit: true}, 4*perWord + math.Log2(10) + 2},
+		{generate.PassphrasePolicy{Words: 3, Wordlist: []string{"a", "b", "c", "d"}}, 6},
+	}
+
+	for _, tt := range tests {
+		_, got, err := generate.Passphrase(tt.policy)
+		if err != nil {
+			t.Fatalf("Passphrase(%+v) failed: %v", tt.policy, err)
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Passphrase(%+v) entropy = %.2f; want %.2f", tt.policy, got, tt.want)
+		}
+	}
+
+	// Invalid policies are rejected.
+	if _, _, err := generate.Passphrase(generate.PassphrasePolicy{}); !errors.Is(err, generate.ErrWords) {
+		t.Errorf("Passphrase(no words) error = %v; want ErrWords", err)
+	}
+	if _, _, err := generate.Passphrase(generate.PassphrasePolicy{Words: 3, Capitalize: "title"}); err == nil {
+		t.Error("Passphrase(unknown capitalization) succeeded; want an error")
+	}
+}
+
+// TestPassphraseWordsUniform verifies that every word of the list is
+// equally likely.
+func TestPassphraseWordsUniform(t *testing.T) {
+	list := []string{"ant", "bee", "cat", "dog", "eel", "fox", "gnu"}
+	index := map[string]int{}
+	for i, w := range list {
+		index[w] = i
+	}
+
+	counts := make([]int, len(list))
+	for range 5000 {
+		phrase, _, err := generate.Passphrase(generate.PassphrasePolicy{Words: 4, Separator: " ", Wordlist: list})
+		if err != nil {
+			t.Fatalf("Passphrase() failed: %v", err)
+		}
+		for _, w := range strings.Fields(phrase) {
+			counts[index[w]]++
+		}
+	}
+
+	if stat := chiSquare(counts); stat > critical(len(counts)-1) {
+		t.Fatalf("chi-square = %.1f > %.1f; words are not uniform: %v", stat, critical(len(counts)-1), counts)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"sort"
+
+	"github.com/nullzeiger/pwdcli/internal/storage"
+	"github.com/nullzeiger/pwdcli/internal/strength"
+)
+
+// Finding is the audit result of an entry with a weak or reused password.
+type Finding struct {
+	Entry
+
+	// Strength is the strength estimate of the password.
+	Strength strength.Result
+
+	// ReusedBy lists the indexes of the other entries with the same
+	// password.
+	ReusedBy []int
+}
+
+// PasswordStrength estimates the strength of the account's password,
+// recognising passwords derived from its website, username or email.
+func PasswordStrength(act Act) strength.Result {
+	return strength.Estimate(act.Pwd, act.Website, act.Username, act.Email)
+}
+
+// Audit estimates the strength of every stored password and returns the
+// entries that score below minScore or share their password with another
+// entry, weakest first. Entries without a password, such as TOTP-only or
+// note entries, are skipped: they are neither weak nor reused.
+func Audit(s storage.Store, minScore int) ([]Finding, error) {
+	entries, err := List(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Group the entries by password to find reuse.
+	byPassword := map[string][]int{}
+	for _, e := range entries {
+		if e.Account.Pwd != "" {
+			byPassword[e.Account.Pwd] = append(byPassword[e.Account.Pwd], e.Index)
+		}
+	}
+
+	findings := []Finding{}
+	for _, e := range entries {
+		if e.Account.Pwd == "" {
+			continue
+		}
+		f := Finding{Entry: e, Strength: PasswordStrength(e.Account)}
+		for _, i := range byPassword[e.Account.Pwd] {
+			if i != e.Index {
+				f.ReusedBy = append(f.ReusedBy, i)
+			}
+		}
+		if f.Strength.Score < minScore || len(f.ReusedBy) > 0 {
+			findings = append(findings, f)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Strength.Guesses < findings[j].Strength.Guesses
+	})
+	return findings, nil
+}
+
+// BreachChecker reports how many times a password appears in a corpus of
+// breached passwords. *breach.File implements it.
+type BreachChecker interface {
+	Count(password string) (int, error)
+}
+
+// Breach is an entry whose password appears in a breach corpus.
+type Breach struct {
+	Entry
+
+	// Count is how many times the password appears in the corpus.
+	Count int
+}
+
+// Breached looks up every stored password with c and returns the entries
+// whose password was found, most frequent first.
+func Breached(s storage.Store, c BreachChecker) ([]Breach, error) {
+	entries, err := List(s)
+	if err != nil {
+		return nil, err
+	}
+
+	breaches := []Breach{}
+	for _, e := range entries {
+		n, err := c.Count(e.Account.Pwd)
+		if err != nil {
+			return nil, err
+		}
+		if n > 0 {
+			breaches = append(breaches, Breach{Entry: e, Count: n})
+		}
+	}
+
+	sort.SliceStable(breaches, func(i, j int) bool {
+		return breaches[i].Count > breaches[j].Count
+	})
+	return breaches, nil
+}
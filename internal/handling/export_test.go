@@ -0,0 +1,18 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"testing"
+	"time"
+)
+
+// SetNow makes the package record t as the current time until the test
+// ends.
+func SetNow(tb testing.TB, t time.Time) {
+	old := now
+	now = func() time.Time { return t }
+	tb.Cleanup(func() { now = old })
+}
@@ -0,0 +1,194 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// ErrFolderNotFound is returned when no entry is filed in a folder.
+var ErrFolderNotFound = errors.New("no entries in folder")
+
+// Filter selects entries by folder, tags and age. The zero Filter selects
+// every entry.
+type Filter struct {
+	// Folder selects the entries filed in the folder or its subfolders.
+	Folder string
+
+	// Tags selects the entries that have all of them.
+	Tags []string
+
+	// ChangedBefore, unless zero, selects the entries whose password
+	// was last changed before it, or at an unknown time.
+	ChangedBefore time.Time
+
+	// AccessedBefore, unless zero, selects the entries last accessed
+	// before it, or never since access times were recorded.
+	AccessedBefore time.Time
+}
+
+// Match reports whether the filter selects acc.
+func (f Filter) Match(acc Act) bool {
+	if !acc.InFolder(f.Folder) {
+		return false
+	}
+	if !f.ChangedBefore.IsZero() && !acc.PasswordChanged.Before(f.ChangedBefore) {
+		return false
+	}
+	if !f.AccessedBefore.IsZero() && !acc.Accessed.Before(f.AccessedBefore) {
+		return false
+	}
+	for _, t := range f.Tags {
+		if !acc.HasTag(t) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply returns the entries the filter selects.
+func (f Filter) Apply(entries []Entry) []Entry {
+	selected := []Entry{}
+	for _, e := range entries {
+		if f.Match(e.Account) {
+			selected = append(selected, e)
+		}
+	}
+	return selected
+}
+
+// Folder is a node of the folder tree built by Tree.
+type Folder struct {
+	// Name is the last name in the path, empty for the top level.
+	Name string
+
+	// Path is the full path of the folder.
+	Path string
+
+	// Folders are the subfolders, sorted by name.
+	Folders []*Folder
+
+	// Entries are the entries filed directly in the folder, in index
+	// order.
+	Entries []Entry
+}
+
+// Tree files entries into a tree of folders, whose root is the top level.
+// Only folders holding entries, directly or below them, appear.
+func Tree(entries []Entry) *Folder {
+	root := &Folder{}
+	for _, e := range entries {
+		f := root
+		if e.Account.Folder != "" {
+			for name := range strings.SplitSeq(e.Account.Folder, "/") {
+				f = f.child(name)
+			}
+		}
+		f.Entries = append(f.Entries, e)
+	}
+	root.sort()
+	return root
+}
+
+// child returns the subfolder called name, adding it if needed.
+func (f *Folder) child(name string) *Folder {
+	for _, c := range f.Folders {
+		if c.Name == name {
+			return c
+		}
+	}
+	path := name
+	if f.Path != "" {
+		path = f.Path + "/" + name
+	}
+	c := &Folder{Name: name, Path: path}
+	f.Folders = append(f.Folders, c)
+	return c
+}
+
+// sort orders the subfolders of f and below by name.
+func (f *Folder) sort() {
+	slices.SortFunc(f.Folders, func(a, b *Folder) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	})
+	for _, c := range f.Folders {
+		c.sort()
+	}
+}
+
+// Lookup returns the subfolder of f at the path relative to f.
+func (f *Folder) Lookup(path string) (*Folder, bool) {
+	path = account.CleanFolder(path)
+	if path == "" {
+		return f, true
+	}
+	for name := range strings.SplitSeq(path, "/") {
+		i := slices.IndexFunc(f.Folders, func(c *Folder) bool { return c.Name == name })
+		if i < 0 {
+			return nil, false
+		}
+		f = f.Folders[i]
+	}
+	return f, true
+}
+
+// Count returns the number of entries in the folder and its subfolders.
+func (f *Folder) Count() int {
+	n := len(f.Entries)
+	for _, c := range f.Folders {
+		n += c.Count()
+	}
+	return n
+}
+
+// MoveFolder moves the folder at from, with its subfolders, to the path
+// to, by refiling every entry below it: with from "work/aws" and to
+// "clients/aws", an entry in "work/aws/prod" ends up in
+// "clients/aws/prod". An empty to moves the contents to the top level.
+// Renaming a folder is moving it within its parent. It returns the number
+// of entries moved.
+func MoveFolder(s storage.Store, from, to string) (int, error) {
+	from, to = account.CleanFolder(from), account.CleanFolder(to)
+	if from == "" {
+		return 0, errors.New("the top level cannot be moved")
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	t := now()
+	for i, acc := range accounts {
+		if !acc.InFolder(from) {
+			continue
+		}
+		rest := strings.TrimPrefix(strings.TrimPrefix(acc.Folder, from), "/")
+		if folder := account.CleanFolder(to + "/" + rest); folder != acc.Folder {
+			accounts[i].Folder, accounts[i].Modified = folder, t
+		}
+		n++
+	}
+	if n == 0 {
+		return 0, ErrFolderNotFound
+	}
+	if from == to {
+		return n, nil
+	}
+	return n, s.Save(accounts)
+}
@@ -0,0 +1,124 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// folderStore returns a store with entries filed in a few folders.
+func folderStore() *storage.MemStore {
+	return storage.NewMemStore(
+		handling.Act{Website: "aws-prod", Folder: "work/aws/prod", Tags: []string{"prod", "cloud"}},
+		handling.Act{Website: "aws-dev", Folder: "work/aws/dev", Tags: []string{"cloud"}},
+		handling.Act{Website: "jira", Folder: "work"},
+		handling.Act{Website: "bank", Tags: []string{"Prod"}},
+		handling.Act{Website: "aws-personal", Folder: "personal/aws"},
+	)
+}
+
+// websites returns the websites of entries, in order.
+func websites(entries []handling.Entry) []string {
+	names := []string{}
+	for _, e := range entries {
+		names = append(names, e.Account.Website)
+	}
+	return names
+}
+
+// TestFilter verifies that entries are selected by folder, including
+// subfolders, and by having every given tag.
+func TestFilter(t *testing.T) {
+	entries, _ := handling.List(folderStore())
+
+	tests := []struct {
+		filter handling.Filter
+		want   []string
+	}{
+		{handling.Filter{}, []string{"aws-prod", "aws-dev", "jira", "bank", "aws-personal"}},
+		{handling.Filter{Folder: "work/aws"}, []string{"aws-prod", "aws-dev"}},
+		{handling.Filter{Folder: "/work/"}, []string{"aws-prod", "aws-dev", "jira"}},
+		{handling.Filter{Tags: []string{"PROD"}}, []string{"aws-prod", "bank"}},
+		{handling.Filter{Tags: []string{"prod", "cloud"}}, []string{"aws-prod"}},
+		{handling.Filter{Folder: "work", Tags: []string{"cloud"}}, []string{"aws-prod", "aws-dev"}},
+		{handling.Filter{Folder: "aws"}, []string{}},
+	}
+	for _, tt := range tests {
+		if got := websites(tt.filter.Apply(entries)); !slices.Equal(got, tt.want) {
+			t.Errorf("%+v.Apply() = %v; want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// TestTree verifies that entries are filed into a sorted tree of folders.
+func TestTree(t *testing.T) {
+	entries, _ := handling.List(folderStore())
+	root := handling.Tree(entries)
+
+	if root.Count() != 5 || websites(root.Entries)[0] != "bank" {
+		t.Fatalf("Tree() root has %d entries, %v at the top; want 5 and bank", root.Count(), websites(root.Entries))
+	}
+	if len(root.Folders) != 2 || root.Folders[0].Name != "personal" || root.Folders[1].Name != "work" {
+		t.Fatalf("Tree() top folders = %v; want personal and work", root.Folders)
+	}
+
+	work := root.Folders[1]
+	if work.Count() != 3 || len(work.Folders) != 1 {
+		t.Fatalf("work holds %d entries in %d folders; want 3 in 1", work.Count(), len(work.Folders))
+	}
+	aws := work.Folders[0]
+	if aws.Path != "work/aws" || len(aws.Folders) != 2 || aws.Folders[0].Path != "work/aws/dev" {
+		t.Errorf("work/aws = %+v; want the subfolders dev and prod", aws)
+	}
+
+	// Folders are looked up by path.
+	if f, ok := root.Lookup("/work/aws/prod"); !ok || f.Path != "work/aws/prod" || len(f.Entries) != 1 {
+		t.Errorf("Lookup(/work/aws/prod) = %+v, %v; want the prod folder", f, ok)
+	}
+	if _, ok := root.Lookup("work/gcp"); ok {
+		t.Errorf("Lookup(work/gcp) found a folder; want none")
+	}
+}
+
+// TestMoveFolder verifies that moving a folder refiles the entries in it
+// and its subfolders, and nothing else.
+func TestMoveFolder(t *testing.T) {
+	s := folderStore()
+
+	n, err := handling.MoveFolder(s, "work/aws", "clients/amazon")
+	if err != nil || n != 2 {
+		t.Fatalf("MoveFolder() = %d, %v; want 2 entries moved", n, err)
+	}
+	accounts, _ := s.Load()
+	folders := []string{}
+	for _, acc := range accounts {
+		folders = append(folders, acc.Folder)
+	}
+	want := []string{"clients/amazon/prod", "clients/amazon/dev", "work", "", "personal/aws"}
+	if !slices.Equal(folders, want) {
+		t.Errorf("After MoveFolder(), folders = %v; want %v", folders, want)
+	}
+
+	// Moving to the top level drops the folder from the paths.
+	if _, err := handling.MoveFolder(s, "clients", ""); err != nil {
+		t.Fatalf("MoveFolder(clients, top level) failed: %v", err)
+	}
+	if accounts, _ := s.Load(); accounts[0].Folder != "amazon/prod" {
+		t.Errorf("After moving to the top level, folder = %q; want amazon/prod", accounts[0].Folder)
+	}
+
+	// Folders are matched by whole names.
+	if _, err := handling.MoveFolder(s, "wor", "x"); !errors.Is(err, handling.ErrFolderNotFound) {
+		t.Errorf("MoveFolder(wor) error = %v; want ErrFolderNotFound", err)
+	}
+	if _, err := handling.MoveFolder(s, "/", "x"); err == nil {
+		t.Errorf("MoveFolder(/) succeeded; want an error")
+	}
+}
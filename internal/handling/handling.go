@@ -4,12 +4,14 @@
 
 // Package handling provides higher-level business logic for managing
 // password entries. It sits between the CLI layer and the storage layer,
-// offering operations such as listing, creating, deleting, and searching
-// account entries.
+// offering operations such as listing, creating, updating, deleting, searching and
+// auditing account entries. Every operation works on the storage.Store passed to it,
+// so callers decide where the accounts are kept.
 package handling
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/nullzeiger/pwdcli/internal/account"
@@ -19,84 +21,428 @@ import (
 // Act is an alias to account.Account for convenience within this package.
 type Act = account.Account
 
+// Mask replaces passwords in masked listings. Its length is fixed so that
+// it does not reveal the length of the password.
+const Mask = "********"
+
+// Entry is an account together with its index in the store.
+type Entry struct {
+	Index   int
+	Account Act
+}
+
+// String formats the entry as shown by the CLI listing, with the short
+// form of its ID. The ID and the OTP key are only shown for entries that
+// have one, followed by the custom fields, where concealed values are
+// always masked, the folder and the tags. Notes are left out, since they
+// may span lines.
+func (e Entry) String() string {
+	s := fmt.Sprintf("%s Website: %s Username: %s Email: %s Password: %s",
+		e.Label(), e.Account.Website, e.Account.Username, e.Account.Email, e.Account.Pwd)
+	if e.Account.OTP != "" {
+		s += " OTP: " + e.Account.OTP
+	}
+	for _, f := range e.HideConcealed().Account.Fields {
+		s += " " + f.Name + ": " + f.Value
+	}
+	if e.Account.Folder != "" {
+		s += " Folder: " + e.Account.Folder
+	}
+	if len(e.Account.Tags) > 0 {
+		s += " Tags: " + strings.Join(e.Account.Tags, ", ")
+	}
+	return s
+}
+
+// Label is the start of the entry's line in listings: its index and, if
+// it has one, the short form of its ID.
+func (e Entry) Label() string {
+	if e.Account.ID == "" {
+		return fmt.Sprintf("[%d]", e.Index)
+	}
+	return fmt.Sprintf("[%d] ID: %s", e.Index, e.ShortID())
+}
+
+// Masked returns a copy of the entry with the password, the OTP key if
+// there is one and the concealed custom fields replaced by Mask.
+func (e Entry) Masked() Entry {
+	e = e.HideConcealed()
+	e.Account.Pwd = Mask
+	if e.Account.OTP != "" {
+		e.Account.OTP = Mask
+	}
+	return e
+}
+
+// HideConcealed returns a copy of the entry with the values of its
+// concealed custom fields replaced by Mask.
+func (e Entry) HideConcealed() Entry {
+	e.Account.Fields = slices.Clone(e.Account.Fields)
+	for i, f := range e.Account.Fields {
+		if f.Concealed {
+			e.Account.Fields[i].Value = Mask
+		}
+	}
+	return e
+}
+
+// List retrieves all stored accounts together with their indexes.
+func List(s storage.Store) ([]Entry, error) {
+	accounts, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, 0, len(accounts))
+	for i, acc := range accounts {
+		entries = append(entries, Entry{Index: i, Account: acc})
+	}
+	return entries, nil
+}
+
+// Get returns the account at index.
+func Get(s storage.Store, index int) (Entry, error) {
+	accounts, err := s.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	if index < 0 || index >= len(accounts) {
+		return Entry{}, storage.ErrIndexOutOfRange
+	}
+	return Entry{Index: index, Account: accounts[index]}, nil
+}
+
 // All retrieves all stored accounts and returns them formatted as strings,
 // each containing index and field details. It is used primarily by the CLI
 // when listing entries.
-func All() ([]string, error) {
-	accounts, err := storage.Read()
+func All(s storage.Store) ([]string, error) {
+	list, err := List(s)
 	if err != nil {
 		return nil, err
 	}
 
 	entries := []string{}
-	for i, acc := range accounts {
+	for _, e := range list {
 		// Format each account as a readable CLI entry.
-		entries = append(entries,
-			fmt.Sprintf("[%d] Website: %s Username: %s Email: %s Password: %s",
-				i, acc.Website, acc.Username, acc.Email, acc.Pwd))
+		entries = append(entries, e.String())
 	}
 	return entries, nil
 }
 
-// Create appends a new account entry to the storage file.
+// Create appends a new account entry to the store, giving it a new ID
+// and recording the current time as when it was created, modified and had
+// its password set.
 // It performs no validation—validation should be done at the CLI or higher layer.
-func Create(act Act) error {
-	return storage.Append(act)
+func Create(s storage.Store, act Act) error {
+	act.ID = account.NewID()
+	t := now()
+	act.Created, act.Modified, act.PasswordChanged = t, t, t
+	return s.Append(act)
 }
 
 // Delete removes an account by its index. It returns true if the operation
 // succeeds, and an error if the index is invalid or storage access fails.
-func Delete(index int) (bool, error) {
-	accounts, err := storage.Read()
-	if err != nil {
+func Delete(s storage.Store, index int) (bool, error) {
+	if _, err := remove(s, atIndex(index)); err != nil {
 		return false, err
 	}
+	return true, nil
+}
 
-	// Validate index bounds
-	if index < 0 || index >= len(accounts) {
-		return false, fmt.Errorf("index out of range")
+// DeleteBy removes the account named by selector, as described by Select,
+// and returns it.
+func DeleteBy(s storage.Store, selector string) (Entry, error) {
+	return remove(s, bySelector(selector))
+}
+
+// remove implements Delete and DeleteBy.
+func remove(s storage.Store, locate locator) (Entry, error) {
+	// Keep other processes out until the updated list is written.
+	unlock, err := s.Lock()
+	if err != nil {
+		return Entry{}, err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	index, err := locate(accounts)
+	if err != nil {
+		return Entry{}, err
+	}
+	entry := Entry{Index: index, Account: accounts[index]}
+
+	// Stores with row-level deletes avoid rewriting the whole vault.
+	if d, ok := s.(storage.Deleter); ok {
+		return entry, d.Delete(index)
 	}
 
 	// Remove the entry using slice manipulation.
 	accounts = append(accounts[:index], accounts[index+1:]...)
+	return entry, s.Save(accounts)
+}
+
+// Edit lists the fields Update changes. Nil fields are left as they are,
+// so that an empty value can still clear a field.
+type Edit struct {
+	Website  *string
+	Username *string
+	Email    *string
+	Pwd      *string
+	OTP      *string
+	Notes    *string
+
+	// SetFields replaces the custom fields with the same names, ignoring
+	// case, or adds them after the others.
+	SetFields []account.Field
+
+	// RemoveFields lists the names of custom fields to remove.
+	RemoveFields []string
+
+	// Folder is the new folder path, cleaned with account.CleanFolder.
+	Folder *string
+
+	// AddTags and RemoveTags list tags to add and remove.
+	AddTags    []string
+	RemoveTags []string
+}
+
+// IsZero reports whether the edit changes nothing.
+func (ed Edit) IsZero() bool {
+	return ed.Website == nil && ed.Username == nil && ed.Email == nil && ed.Pwd == nil &&
+		ed.OTP == nil && ed.Notes == nil && len(ed.SetFields) == 0 && len(ed.RemoveFields) == 0 &&
+		ed.Folder == nil && len(ed.AddTags) == 0 && len(ed.RemoveTags) == 0
+}
+
+// Apply returns acc with the fields given in the edit set, together with
+// the names of those whose value changed, as accepted by "pwdcli copy".
+// Custom fields are named by their name as it is after the edit.
+func (ed Edit) Apply(acc Act) (Act, []string) {
+	changed := []string{}
+	set := func(name string, field, value *string) {
+		if value != nil && *field != *value {
+			*field = *value
+			changed = append(changed, name)
+		}
+	}
+	set("website", &acc.Website, ed.Website)
+	set("username", &acc.Username, ed.Username)
+	set("email", &acc.Email, ed.Email)
+	set("pwd", &acc.Pwd, ed.Pwd)
+	set("otp", &acc.OTP, ed.OTP)
+	set("notes", &acc.Notes, ed.Notes)
+
+	// Leave the caller's fields alone.
+	acc.Fields = slices.Clone(acc.Fields)
+	for _, name := range ed.RemoveFields {
+		for i, f := range acc.Fields {
+			if strings.EqualFold(f.Name, name) {
+				acc.Fields = slices.Delete(acc.Fields, i, i+1)
+				changed = append(changed, f.Name)
+				break
+			}
+		}
+	}
+	for _, f := range ed.SetFields {
+		i := slices.IndexFunc(acc.Fields, func(old account.Field) bool {
+			return strings.EqualFold(old.Name, f.Name)
+		})
+		switch {
+		case i < 0:
+			acc.Fields = append(acc.Fields, f)
+		case acc.Fields[i] != f:
+			acc.Fields[i] = f
+		default:
+			continue
+		}
+		changed = append(changed, f.Name)
+	}
+	if len(acc.Fields) == 0 {
+		acc.Fields = nil
+	}
+
+	if ed.Folder != nil {
+		folder := account.CleanFolder(*ed.Folder)
+		set("folder", &acc.Folder, &folder)
+	}
+
+	// Tags are reported together.
+	tags := slices.Clone(acc.Tags)
+	tags = slices.DeleteFunc(tags, func(t string) bool {
+		return slices.ContainsFunc(ed.RemoveTags, func(r string) bool {
+			return strings.EqualFold(t, strings.TrimSpace(r))
+		})
+	})
+	for _, t := range ed.AddTags {
+		t = strings.TrimSpace(t)
+		if t != "" && !slices.ContainsFunc(tags, func(old string) bool { return strings.EqualFold(old, t) }) {
+			tags = append(tags, t)
+		}
+	}
+	if !slices.Equal(tags, acc.Tags) {
+		acc.Tags = tags
+		if len(tags) == 0 {
+			acc.Tags = nil
+		}
+		changed = append(changed, "tags")
+	}
+	return acc, changed
+}
 
-	return true, storage.Write(accounts)
+// Update changes the fields given in edit of the account at index, which
+// keeps its position, and returns the updated entry together with the
+// names of the fields whose value changed. The modification time, and the
+// password change time when the password changes, are set to the current
+// time. The store is left untouched when nothing changes.
+func Update(s storage.Store, index int, edit Edit) (Entry, []string, error) {
+	return update(s, atIndex(index), edit)
+}
+
+// UpdateBy is like Update for the account named by selector, as described
+// by Select.
+func UpdateBy(s storage.Store, selector string, edit Edit) (Entry, []string, error) {
+	return update(s, bySelector(selector), edit)
+}
+
+// update implements Update and UpdateBy.
+func update(s storage.Store, locate locator, edit Edit) (Entry, []string, error) {
+	unlock, err := s.Lock()
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return Entry{}, nil, err
+	}
+	index, err := locate(accounts)
+	if err != nil {
+		return Entry{}, nil, err
+	}
+
+	acc, changed := edit.Apply(accounts[index])
+	if len(changed) == 0 {
+		return Entry{Index: index, Account: acc}, changed, nil
+	}
+	acc.Modified = now()
+	if slices.Contains(changed, "pwd") {
+		acc.PasswordChanged = acc.Modified
+	}
+
+	accounts[index] = acc
+	if err := put(s, accounts, index); err != nil {
+		return Entry{}, nil, err
+	}
+	return Entry{Index: index, Account: acc}, changed, nil
+}
+
+// put writes the account at index of accounts, which were loaded from s,
+// back to s.
+func put(s storage.Store, accounts []Act, index int) error {
+	// Stores with row-level updates avoid rewriting the whole vault.
+	if u, ok := s.(storage.Updater); ok {
+		return u.Update(index, accounts[index])
+	}
+	return s.Save(accounts)
+}
+
+// Transfer copies the account at index from src to dst, appending it after
+// the accounts already in dst. If move is true, the account is then
+// deleted from src and keeps its ID; a copy is a new entry with an ID of
+// its own. Both keep the timestamps, so the age of the password carries
+// over. The copy is written before the original is deleted,
+// so an interruption can leave a duplicate but never lose the entry.
+func Transfer(src, dst storage.Store, index int, move bool) error {
+	return transfer(src, dst, atIndex(index), move)
+}
+
+// TransferBy is like Transfer for the account named by selector, as
+// described by Select.
+func TransferBy(src, dst storage.Store, selector string, move bool) error {
+	return transfer(src, dst, bySelector(selector), move)
+}
+
+// transfer implements Transfer and TransferBy.
+func transfer(src, dst storage.Store, locate locator, move bool) error {
+	unlock, err := src.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accounts, err := src.Load()
+	if err != nil {
+		return err
+	}
+	index, err := locate(accounts)
+	if err != nil {
+		return err
+	}
+
+	acc := accounts[index]
+	if !move || acc.ID == "" {
+		acc.ID = account.NewID()
+	}
+	if err := dst.Append(acc); err != nil {
+		return err
+	}
+	if !move {
+		return nil
+	}
+
+	if d, ok := src.(storage.Deleter); ok {
+		return d.Delete(index)
+	}
+	return src.Save(append(accounts[:index], accounts[index+1:]...))
 }
 
 // Search scans all stored accounts and returns those matching the given
 // keyword (case-insensitive). It compares the keyword with the website,
-// username, email, and password fields.
+// username, email, password, notes, folder and tags, and with the custom
+// fields that are not concealed.
 //
-// The result is a slice of structs containing both the index of the match
-// and a copy of the corresponding account.
-func Search(key string) ([]struct {
-	Index   int
-	Account Act
-}, error) {
+// Each result holds both the index of the match and a copy of the
+// corresponding account.
+func Search(s storage.Store, key string) ([]Entry, error) {
+	return search(s, key, false)
+}
+
+// SearchConcealed is like Search but also compares the keyword with the
+// concealed custom fields.
+func SearchConcealed(s storage.Store, key string) ([]Entry, error) {
+	return search(s, key, true)
+}
 
-	accounts, err := storage.Read()
+// search implements Search and SearchConcealed.
+func search(s storage.Store, key string, concealed bool) ([]Entry, error) {
+	accounts, err := s.Load()
 	if err != nil {
 		return nil, err
 	}
 
 	key = strings.ToLower(key)
+	contains := func(s string) bool {
+		return strings.Contains(strings.ToLower(s), key)
+	}
 
-	results := []struct {
-		Index   int
-		Account Act
-	}{}
+	results := []Entry{}
 
 	// Match accounts based on any field containing the keyword.
 	for i, acc := range accounts {
-		if strings.Contains(strings.ToLower(acc.Website), key) ||
-			strings.Contains(strings.ToLower(acc.Username), key) ||
-			strings.Contains(strings.ToLower(acc.Email), key) ||
-			strings.Contains(strings.ToLower(acc.Pwd), key) {
-
-			results = append(results, struct {
-				Index   int
-				Account Act
-			}{Index: i, Account: acc})
+		match := contains(acc.Website) || contains(acc.Username) ||
+			contains(acc.Email) || contains(acc.Pwd) || contains(acc.Notes) ||
+			contains(acc.Folder) || slices.ContainsFunc(acc.Tags, contains)
+		for _, f := range acc.Fields {
+			if !f.Concealed || concealed {
+				match = match || contains(f.Name) || contains(f.Value)
+			}
+		}
+
+		if match {
+			results = append(results, Entry{Index: i, Account: acc})
 		}
 	}
 
@@ -8,43 +8,33 @@
 package handling_test
 
 import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/nullzeiger/pwdcli/internal/account"
 	"github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/otp"
 	"github.com/nullzeiger/pwdcli/internal/storage"
-	"github.com/nullzeiger/pwdcli/internal/util"
 )
 
-// setupTempStorage prepares a temporary environment for tests.
-// It overrides HOME to a temp directory and ensures the storage file exists.
-// Returns the expected storage file path.
-func setupTempStorage(t *testing.T) string {
-	t.Helper() // Marks this as a helper; errors point to the caller
-	tmpDir := t.TempDir()
-	t.Setenv("HOME", tmpDir)
-
-	// Initialize the storage file
-	if err := storage.Create(); err != nil {
-		t.Fatalf("storage.Create() failed: %v", err)
-	}
-
-	return util.FilePath()
-}
-
 // TestCreateAndAll verifies that creating an account via handling.Create
 // works correctly and that handling.All returns the correct formatted output.
 func TestCreateAndAll(t *testing.T) {
-	setupTempStorage(t)
+	s := storage.NewMemStore()
 
 	acc := handling.Act{Website: "example.com", Username: "user", Email: "a@b.com", Pwd: "123"}
 
 	// Create a new account
-	if err := handling.Create(acc); err != nil {
+	if err := handling.Create(s, acc); err != nil {
 		t.Fatalf("Create() failed: %v", err)
 	}
 
 	// Retrieve all entries
-	entries, err := handling.All()
+	entries, err := handling.All(s)
 	if err != nil {
 		t.Fatalf("All() failed: %v", err)
 	}
@@ -54,56 +44,163 @@ func TestCreateAndAll(t *testing.T) {
 		t.Fatalf("All() returned %d entries; want 1", len(entries))
 	}
 
+	// Create gives the account an ID, shown in its short form.
+	accounts, _ := s.Load()
+	if len(accounts[0].ID) != 16 {
+		t.Fatalf("Create() assigned ID %q; want 16 characters", accounts[0].ID)
+	}
+
 	// Verify the formatted string
-	expectedSubstring := "Website: example.com Username: user Email: a@b.com Password: 123"
-	if entries[0] != "[0] "+expectedSubstring {
-		t.Fatalf("All()[0] = %s; want %s", entries[0], "[0] "+expectedSubstring)
+	expected := "[0] ID: " + accounts[0].ID[:handling.ShortIDLen] + " Website: example.com Username: user Email: a@b.com Password: 123"
+	if entries[0] != expected {
+		t.Fatalf("All()[0] = %s; want %s", entries[0], expected)
+	}
+}
+
+// TestListMasked verifies that handling.List returns entries with their
+// indexes and that masking hides the password behind a fixed-length mask.
+func TestListMasked(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "a.com", Pwd: "short"},
+		handling.Act{Website: "b.com", Pwd: "a much longer password"},
+	)
+
+	entries, err := handling.List(s)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if len(entries) != 2 || entries[1].Index != 1 || entries[1].Account.Website != "b.com" {
+		t.Fatalf("List() = %+v; want a.com at 0 and b.com at 1", entries)
+	}
+
+	// Every masked password looks the same.
+	for _, e := range entries {
+		if m := e.Masked(); m.Account.Pwd != handling.Mask {
+			t.Errorf("Masked().Account.Pwd = %q; want %q", m.Account.Pwd, handling.Mask)
+		}
+	}
+
+	// Masking returns a copy and leaves the entry untouched.
+	if entries[0].Account.Pwd != "short" {
+		t.Errorf("entries[0].Account.Pwd = %q after Masked(); want short", entries[0].Account.Pwd)
+	}
+}
+
+// TestGet verifies that handling.Get returns the entry at an index and
+// rejects indexes out of range.
+func TestGet(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "a.com"},
+		handling.Act{Website: "b.com"},
+	)
+
+	e, err := handling.Get(s, 1)
+	if err != nil {
+		t.Fatalf("Get(1) failed: %v", err)
+	}
+	if e.Index != 1 || e.Account.Website != "b.com" {
+		t.Errorf("Get(1) = %+v; want b.com at 1", e)
+	}
+
+	if _, err := handling.Get(s, 2); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Errorf("Get(2) error = %v; want ErrIndexOutOfRange", err)
 	}
 }
 
 // TestDelete verifies that handling.Delete removes accounts correctly
 // and handles invalid indices properly.
 func TestDelete(t *testing.T) {
-	setupTempStorage(t)
+	s := storage.NewMemStore()
 
 	acc1 := handling.Act{Website: "site1", Username: "u1", Email: "e1", Pwd: "p1"}
 	acc2 := handling.Act{Website: "site2", Username: "u2", Email: "e2", Pwd: "p2"}
 
-	handling.Create(acc1)
-	handling.Create(acc2)
+	handling.Create(s, acc1)
+	handling.Create(s, acc2)
 
 	// Delete the second account
-	ok, err := handling.Delete(1)
+	ok, err := handling.Delete(s, 1)
 	if err != nil || !ok {
 		t.Fatalf("Delete(1) failed: %v", err)
 	}
 
 	// Verify remaining accounts
-	accounts, _ := storage.Read()
+	accounts, _ := s.Load()
 	if len(accounts) != 1 || accounts[0].Website != "site1" {
 		t.Fatalf("After delete, remaining accounts = %v; want only site1", accounts)
 	}
 
 	// Attempt deletion with an invalid index
-	ok, err = handling.Delete(10)
+	ok, err = handling.Delete(s, 10)
 	if err == nil || ok {
 		t.Fatalf("Delete(10) should fail for invalid index")
 	}
 }
 
+// TestUpdate verifies that handling.Update changes only the given fields,
+// keeps the entry in place and reports what changed.
+func TestUpdate(t *testing.T) {
+	s := storage.NewMemStore()
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	handling.SetNow(t, created)
+
+	handling.Create(s, handling.Act{Website: "site1", Username: "u1", Email: "e1", Pwd: "p1"})
+	handling.Create(s, handling.Act{Website: "site2", Username: "u2", Email: "e2", Pwd: "p2", OTP: "otpauth://totp/x?secret=AA"})
+	handling.Create(s, handling.Act{Website: "site3", Username: "u3", Email: "e3", Pwd: "p3"})
+
+	before, _ := s.Load()
+	edited := created.Add(time.Hour)
+	handling.SetNow(t, edited)
+
+	// Rotate the password, clear the OTP key and set the email to its
+	// current value, which is not a change.
+	pwd, empty, email := "new", "", "e2"
+	entry, changed, err := handling.Update(s, 1, handling.Edit{Pwd: &pwd, OTP: &empty, Email: &email})
+	if err != nil {
+		t.Fatalf("Update(1) failed: %v", err)
+	}
+	if want := []string{"pwd", "otp"}; !slices.Equal(changed, want) {
+		t.Errorf("Update(1) changed %v; want %v", changed, want)
+	}
+
+	// The entry keeps its ID and creation time, and records the change
+	// of its password.
+	want := handling.Act{ID: before[1].ID, Website: "site2", Username: "u2", Email: "e2", Pwd: "new",
+		Created: created, Modified: edited, PasswordChanged: edited}
+	if entry.Index != 1 || !reflect.DeepEqual(entry.Account, want) {
+		t.Errorf("Update(1) = %+v; want [1] %+v", entry, want)
+	}
+
+	// The entry keeps its position between the others.
+	accounts, _ := s.Load()
+	if len(accounts) != 3 || accounts[0].Website != "site1" || !reflect.DeepEqual(accounts[1], want) || accounts[2].Website != "site3" {
+		t.Fatalf("After update, accounts = %v", accounts)
+	}
+
+	// An edit without changes reports none.
+	if _, changed, err := handling.Update(s, 1, handling.Edit{Pwd: &pwd}); err != nil || len(changed) != 0 {
+		t.Errorf("Update(1) with the same password = %v, %v; want no changes", changed, err)
+	}
+
+	// Attempt an update with an invalid index
+	if _, _, err := handling.Update(s, 10, handling.Edit{Pwd: &pwd}); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Errorf("Update(10) error = %v; want ErrIndexOutOfRange", err)
+	}
+}
+
 // TestSearch verifies that handling.Search correctly finds accounts
 // based on a keyword and performs case-insensitive matching.
 func TestSearch(t *testing.T) {
-	setupTempStorage(t)
+	s := storage.NewMemStore()
 
 	acc1 := handling.Act{Website: "google.com", Username: "user1", Email: "a@b.com", Pwd: "pass1"}
 	acc2 := handling.Act{Website: "example.com", Username: "user2", Email: "c@d.com", Pwd: "pass2"}
 
-	handling.Create(acc1)
-	handling.Create(acc2)
+	handling.Create(s, acc1)
+	handling.Create(s, acc2)
 
 	// Search by website keyword
-	results, err := handling.Search("google")
+	results, err := handling.Search(s, "google")
 	if err != nil {
 		t.Fatalf("Search() failed: %v", err)
 	}
@@ -112,14 +209,594 @@ func TestSearch(t *testing.T) {
 	}
 
 	// Case-insensitive search
-	results, _ = handling.Search("EXAMPLE")
+	results, _ = handling.Search(s, "EXAMPLE")
 	if len(results) != 1 || results[0].Account.Website != "example.com" {
 		t.Fatalf("Case-insensitive search failed: %v", results)
 	}
 
 	// Search for non-existing keyword should return 0 results
-	results, _ = handling.Search("notfound")
+	results, _ = handling.Search(s, "notfound")
 	if len(results) != 0 {
 		t.Fatalf("Search for 'notfound' should return 0 results, got %d", len(results))
 	}
 }
+
+// TestCustomFields verifies that concealed custom fields are masked in
+// listings and only searched on request, and that notes are searched.
+func TestCustomFields(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "bank.com", Notes: "Branch: Via Roma\nOpened 2019", Fields: []account.Field{
+			{Name: "Account number", Value: "IT60X0542811101000000123456"},
+			{Name: "PIN", Value: "4921", Concealed: true},
+		}},
+		handling.Act{Website: "other.com"},
+	)
+
+	// Listings show the plain field and mask the concealed one.
+	entries, _ := handling.List(s)
+	line := entries[0].String()
+	if !strings.Contains(line, "Account number: IT60X0542811101000000123456") || strings.Contains(line, "4921") {
+		t.Errorf("String() = %q; want the account number and a masked PIN", line)
+	}
+	if hidden := entries[0].HideConcealed(); hidden.Account.Fields[1].Value != handling.Mask || hidden.Account.Fields[0].Value == handling.Mask {
+		t.Errorf("HideConcealed().Account.Fields = %v; want only the PIN masked", hidden.Account.Fields)
+	}
+	if masked := entries[0].Masked(); masked.Account.Fields[1].Value != handling.Mask {
+		t.Errorf("Masked().Account.Fields = %v; want the PIN masked", masked.Account.Fields)
+	}
+
+	// Masking works on a copy.
+	if entries[0].Account.Fields[1].Value != "4921" {
+		t.Errorf("Fields[1].Value = %q after masking; want 4921", entries[0].Account.Fields[1].Value)
+	}
+
+	// Notes and plain fields are searched, concealed ones only on request.
+	for _, key := range []string{"via roma", "IT60X"} {
+		if results, _ := handling.Search(s, key); len(results) != 1 {
+			t.Errorf("Search(%q) returned %d results; want 1", key, len(results))
+		}
+	}
+	if results, _ := handling.Search(s, "4921"); len(results) != 0 {
+		t.Errorf("Search(4921) returned %d results; want the concealed PIN ignored", len(results))
+	}
+	if results, _ := handling.SearchConcealed(s, "4921"); len(results) != 1 {
+		t.Errorf("SearchConcealed(4921) returned %d results; want 1", len(results))
+	}
+}
+
+// TestUpdateFields verifies that Update sets, replaces and removes custom
+// fields and notes.
+func TestUpdateFields(t *testing.T) {
+	s := storage.NewMemStore(handling.Act{Website: "bank.com", Fields: []account.Field{
+		{Name: "PIN", Value: "4921", Concealed: true},
+		{Name: "Question", Value: "Pet?"},
+	}})
+
+	notes := "Call before travelling"
+	edit := handling.Edit{
+		Notes:        &notes,
+		SetFields:    []account.Field{{Name: "pin", Value: "1111", Concealed: true}, {Name: "Customer ID", Value: "42"}},
+		RemoveFields: []string{"question", "missing"},
+	}
+	_, changed, err := handling.Update(s, 0, edit)
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if want := []string{"notes", "Question", "pin", "Customer ID"}; !slices.Equal(changed, want) {
+		t.Errorf("Update() changed %v; want %v", changed, want)
+	}
+
+	accounts, _ := s.Load()
+	want := []account.Field{{Name: "pin", Value: "1111", Concealed: true}, {Name: "Customer ID", Value: "42"}}
+	if !reflect.DeepEqual(accounts[0].Fields, want) || accounts[0].Notes != notes {
+		t.Errorf("After Update(), account = %+v; want fields %v and notes", accounts[0], want)
+	}
+
+	// Folders are cleaned and tags are compared ignoring case.
+	folder := "/work//bank/"
+	edit = handling.Edit{Folder: &folder, AddTags: []string{"finance", " Personal "}}
+	if _, changed, err := handling.Update(s, 0, edit); err != nil || !slices.Equal(changed, []string{"folder", "tags"}) {
+		t.Errorf("Update() with folder and tags changed %v, %v; want folder and tags", changed, err)
+	}
+	edit = handling.Edit{AddTags: []string{"FINANCE", "travel"}, RemoveTags: []string{"personal"}}
+	if _, _, err := handling.Update(s, 0, edit); err != nil {
+		t.Fatalf("Update() with tags failed: %v", err)
+	}
+	accounts, _ = s.Load()
+	if accounts[0].Folder != "work/bank" || !slices.Equal(accounts[0].Tags, []string{"finance", "travel"}) {
+		t.Errorf("After Update(), folder %q, tags %v; want work/bank and finance, travel", accounts[0].Folder, accounts[0].Tags)
+	}
+
+	// Setting a field to its current value is not a change.
+	if _, changed, _ := handling.Update(s, 0, handling.Edit{SetFields: want[1:]}); len(changed) != 0 {
+		t.Errorf("Update() with the same field changed %v; want nothing", changed)
+	}
+}
+
+// TestTransfer verifies that handling.Transfer copies an entry to another
+// store and, when moving, removes it from the source.
+func TestTransfer(t *testing.T) {
+	src := storage.NewMemStore(
+		handling.Act{ID: "aaaaaaaaaaaaaaaa", Website: "site1"},
+		handling.Act{ID: "bbbbbbbbbbbbbbbb", Website: "site2"},
+	)
+	dst := storage.NewMemStore(handling.Act{Website: "existing"})
+
+	// Copy leaves the source untouched
+	if err := handling.Transfer(src, dst, 0, false); err != nil {
+		t.Fatalf("Transfer(copy) failed: %v", err)
+	}
+	if accounts, _ := src.Load(); len(accounts) != 2 {
+		t.Fatalf("After copy, source has %d accounts; want 2", len(accounts))
+	}
+
+	// Move removes the entry from the source
+	if err := handling.Transfer(src, dst, 1, true); err != nil {
+		t.Fatalf("Transfer(move) failed: %v", err)
+	}
+	if accounts, _ := src.Load(); len(accounts) != 1 || accounts[0].Website != "site1" {
+		t.Fatalf("After move, source = %v; want only site1", accounts)
+	}
+
+	accounts, _ := dst.Load()
+	if len(accounts) != 3 || accounts[1].Website != "site1" || accounts[2].Website != "site2" {
+		t.Fatalf("Destination = %v; want existing, site1, site2", accounts)
+	}
+
+	// A copy is a new entry, while a moved entry keeps its ID.
+	if accounts[1].ID == "" || accounts[1].ID == "aaaaaaaaaaaaaaaa" {
+		t.Errorf("Copied entry has ID %q; want a new one", accounts[1].ID)
+	}
+	if accounts[2].ID != "bbbbbbbbbbbbbbbb" {
+		t.Errorf("Moved entry has ID %q; want bbbbbbbbbbbbbbbb", accounts[2].ID)
+	}
+
+	// Invalid index
+	if err := handling.Transfer(src, dst, 5, false); err == nil {
+		t.Fatalf("Transfer(5) should fail for invalid index")
+	}
+}
+
+// TestSelect verifies that entries are selected by index, ID, ID prefix
+// and website/username, and that ambiguous selectors are refused.
+func TestSelect(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{ID: "k7q2mx4aaaaaaaaa", Website: "github.com", Username: "alice", Email: "alice@example.com"},
+		handling.Act{ID: "k7q2zzzzbbbbbbbb", Website: "github.com", Username: "bob"},
+		handling.Act{ID: "m3n4cccccccccccc", Website: "https://example.com/login", Username: "carol"},
+		handling.Act{ID: "2345dddddddddddd", Website: "deep"},
+		handling.Act{ID: "gitlabeeeeeeeeee", Website: "bitbucket.org"},
+	)
+
+	tests := []struct {
+		selector string
+		want     int
+	}{
+		{"1", 1},
+		{"k7q2zzzzbbbbbbbb", 1},
+		{"K7Q2MX4A", 0},
+		{"m3n4", 2},
+		{"id:2345", 3},
+		{"deep", 3},
+		{"GitHub.com/bob", 1},
+		{"github.com/alice@example.com", 0},
+		{"https://example.com/login", 2},
+		{"https://example.com/login/carol", 2},
+		{"id:gitlab", 4},
+	}
+	for _, tt := range tests {
+		e, err := handling.Select(s, tt.selector)
+		if err != nil {
+			t.Errorf("Select(%q) failed: %v", tt.selector, err)
+			continue
+		}
+		if e.Index != tt.want {
+			t.Errorf("Select(%q) = [%d]; want [%d]", tt.selector, e.Index, tt.want)
+		}
+	}
+
+	// Selectors naming several entries list them without secrets.
+	for _, selector := range []string{"github.com", "k7q2"} {
+		var ambiguous *handling.AmbiguousError
+		if _, err := handling.Select(s, selector); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 {
+			t.Errorf("Select(%q) error = %v; want an AmbiguousError with 2 matches", selector, err)
+		}
+	}
+
+	// Selectors naming nothing, including ID prefixes that are too short.
+	for _, selector := range []string{"gitlab.com", "github.com/carol", "k7q", "id:zzzz", ""} {
+		if _, err := handling.Select(s, selector); !errors.Is(err, handling.ErrNotFound) {
+			t.Errorf("Select(%q) error = %v; want ErrNotFound", selector, err)
+		}
+	}
+	// A website-looking selector must not fall through to an ID prefix.
+	if _, err := handling.Select(s, "gitlab"); !errors.Is(err, handling.ErrNotFound) || !errors.Is(err, handling.ErrWordPrefix) {
+		t.Errorf("Select(gitlab) error = %v; want ErrNotFound and ErrWordPrefix", err)
+	}
+	if _, err := handling.Select(s, "9"); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Errorf("Select(9) error = %v; want ErrIndexOutOfRange", err)
+	}
+}
+
+// findStore is a MemStore with a lookup index, which counts how often the
+// whole store is loaded.
+type findStore struct {
+	*storage.MemStore
+	loads int
+}
+
+func (s *findStore) Load() ([]handling.Act, error) {
+	s.loads++
+	return s.MemStore.Load()
+}
+
+// Find implements storage.Finder like SQLStore, without counting as a load.
+func (s *findStore) Find(website, username string) ([]storage.Match, error) {
+	accounts, _ := s.MemStore.Load()
+	var matches []storage.Match
+	for i, acc := range accounts {
+		if strings.EqualFold(acc.Website, website) && (username == "" || strings.EqualFold(acc.Username, username)) {
+			matches = append(matches, storage.Match{Index: i, Account: acc})
+		}
+	}
+	return matches, nil
+}
+
+// TestSelectFinder verifies that Select looks websites up through the
+// index of a storage.Finder without loading the store, and still loads
+// it for the selectors the index cannot answer.
+func TestSelectFinder(t *testing.T) {
+	s := &findStore{MemStore: storage.NewMemStore(
+		handling.Act{ID: "k7q2mx4aaaaaaaaa", Website: "github.com", Username: "alice", Email: "alice@example.com"},
+		handling.Act{ID: "k7q2zzzzbbbbbbbb", Website: "github.com", Username: "bob"},
+		handling.Act{ID: "m3n4cccccccccccc", Website: "deep"},
+	)}
+
+	tests := []struct {
+		selector string
+		want     int
+		loads    int
+	}{
+		{"DEEP", 2, 0},
+		{"github.com/Bob", 1, 0},
+		{"github.com/alice@example.com", 0, 1},
+		{"m3n4", 2, 1},
+		{"k7q2zzzzbbbbbbbb", 1, 1},
+		{"0", 0, 1},
+	}
+	for _, tt := range tests {
+		s.loads = 0
+		e, err := handling.Select(s, tt.selector)
+		if err != nil || e.Index != tt.want || s.loads != tt.loads {
+			t.Errorf("Select(%q) = [%d], %v after %d loads; want [%d] after %d", tt.selector, e.Index, err, s.loads, tt.want, tt.loads)
+		}
+	}
+
+	// Ambiguous websites are reported from the index as well.
+	s.loads = 0
+	var ambiguous *handling.AmbiguousError
+	if _, err := handling.Select(s, "github.com"); !errors.As(err, &ambiguous) || len(ambiguous.Matches) != 2 || s.loads != 0 {
+		t.Errorf("Select(github.com) error = %v after %d loads; want an AmbiguousError without loading", err, s.loads)
+	}
+}
+
+// TestAssignIDs verifies that accounts saved without an ID get one and
+// that existing IDs are kept.
+func TestAssignIDs(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "a.com"},
+		handling.Act{ID: "aaaaaaaaaaaaaaaa", Website: "b.com"},
+		handling.Act{Website: "c.com"},
+	)
+
+	n, err := handling.AssignIDs(s)
+	if err != nil || n != 2 {
+		t.Fatalf("AssignIDs() = %d, %v; want 2", n, err)
+	}
+	accounts, _ := s.Load()
+	if accounts[0].ID == "" || accounts[2].ID == "" || accounts[0].ID == accounts[2].ID || accounts[1].ID != "aaaaaaaaaaaaaaaa" {
+		t.Fatalf("After AssignIDs(), accounts = %v", accounts)
+	}
+
+	// A second run has nothing to do.
+	if n, err := handling.AssignIDs(s); err != nil || n != 0 {
+		t.Errorf("AssignIDs() again = %d, %v; want 0", n, err)
+	}
+}
+
+// TestDeleteBy verifies that an entry selected before the store changes
+// is still the one deleted, updated or touched through its selector, even
+// though its index has moved.
+func TestDeleteBy(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{ID: "aaaaaaaaaaaaaaaa", Website: "a.com"},
+		handling.Act{ID: "bbbbbbbbbbbbbbbb", Website: "b.com"},
+		handling.Act{ID: "cccccccccccccccc", Website: "c.com"},
+	)
+
+	// Select c.com at index 2, then let another process delete a.com,
+	// which moves c.com to index 1.
+	c, err := handling.Select(s, "c.com")
+	if err != nil {
+		t.Fatalf("Select(c.com) failed: %v", err)
+	}
+	if _, err := handling.DeleteBy(s, "a.com"); err != nil {
+		t.Fatalf("DeleteBy(a.com) failed: %v", err)
+	}
+
+	// The selector still names c.com, which is updated and touched.
+	notes := "moved"
+	if entry, _, err := handling.UpdateBy(s, c.Selector(), handling.Edit{Notes: &notes}); err != nil || entry.Account.Website != "c.com" || entry.Index != 1 {
+		t.Fatalf("UpdateBy(%s) = %v, %v; want c.com at [1]", c.Selector(), entry, err)
+	}
+	if err := handling.TouchBy(s, c.Selector()); err != nil {
+		t.Fatalf("TouchBy(%s) failed: %v", c.Selector(), err)
+	}
+
+	// Deleting it removes c.com and leaves b.com alone, where its stale
+	// index would have pointed past the end.
+	deleted, err := handling.DeleteBy(s, c.Selector())
+	if err != nil || deleted.Account.Website != "c.com" {
+		t.Fatalf("DeleteBy(%s) = %v, %v; want c.com", c.Selector(), deleted, err)
+	}
+	accounts, _ := s.Load()
+	if len(accounts) != 1 || accounts[0].Website != "b.com" {
+		t.Fatalf("After DeleteBy, accounts = %v; want only b.com", accounts)
+	}
+
+	// A selector whose entry is gone names nothing.
+	if _, err := handling.DeleteBy(s, c.Selector()); !errors.Is(err, handling.ErrNotFound) {
+		t.Errorf("DeleteBy(%s) again error = %v; want ErrNotFound", c.Selector(), err)
+	}
+}
+
+// TestAudit verifies that handling.Audit reports weak and reused passwords,
+// weakest first, and leaves out strong unique ones.
+func TestAudit(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "strong.com", Pwd: "9C|n))6)UoepVa7}-un8"},
+		handling.Act{Website: "weak.com", Pwd: "password"},
+		handling.Act{Website: "a.com", Pwd: "retinal-reversing-triumph"},
+		handling.Act{Website: "b.com", Pwd: "retinal-reversing-triumph"},
+		handling.Act{Website: "c.com", Pwd: "monkey7"},
+	)
+
+	findings, err := handling.Audit(s, 2)
+	if err != nil {
+		t.Fatalf("Audit() failed: %v", err)
+	}
+
+	// The weakest password comes first; the strong one is not reported.
+	got := []int{}
+	for _, f := range findings {
+		got = append(got, f.Index)
+	}
+	if len(got) != 4 || got[0] != 1 || got[1] != 4 {
+		t.Fatalf("Audit() reported entries %v; want 1, 4, then 2 and 3", got)
+	}
+
+	// Reused passwords name each other.
+	for _, f := range findings[2:] {
+		if len(f.ReusedBy) != 1 || f.ReusedBy[0] != 5-f.Index {
+			t.Errorf("Audit() entry %d reused by %v; want [%d]", f.Index, f.ReusedBy, 5-f.Index)
+		}
+	}
+}
+
+// TestAuditSkipsEmpty verifies that entries without a password are
+// neither scored as weak nor reported as reusing each other's password.
+func TestAuditSkipsEmpty(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "totp.com", OTP: "otpauth://totp/totp.com?secret=JBSWY3DPEHPK3PXP"},
+		handling.Act{Website: "note.com"},
+	)
+
+	findings, err := handling.Audit(s, 2)
+	if err != nil {
+		t.Fatalf("Audit() failed: %v", err)
+	}
+	if len(findings) != 0 {
+		t.Errorf("Audit() reported %d entries without a password; want none", len(findings))
+	}
+}
+
+// fakeBreaches is a BreachChecker backed by a map.
+type fakeBreaches map[string]int
+
+// Count returns the count of password in the map.
+func (f fakeBreaches) Count(password string) (int, error) {
+	return f[password], nil
+}
+
+// TestBreached verifies that handling.Breached reports the entries whose
+// password is in the corpus, most frequent first.
+func TestBreached(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "a.com", Pwd: "hunter2"},
+		handling.Act{Website: "b.com", Pwd: "9C|n))6)UoepVa7}-un8"},
+		handling.Act{Website: "c.com", Pwd: "password"},
+	)
+	corpus := fakeBreaches{"password": 9545824, "hunter2": 17043}
+
+	breaches, err := handling.Breached(s, corpus)
+	if err != nil {
+		t.Fatalf("Breached() failed: %v", err)
+	}
+	if len(breaches) != 2 || breaches[0].Index != 2 || breaches[1].Index != 0 {
+		t.Fatalf("Breached() = %+v; want entries 2 and 0", breaches)
+	}
+	if breaches[0].Count != 9545824 {
+		t.Errorf("Breached()[0].Count = %d; want 9545824", breaches[0].Count)
+	}
+}
+
+// TestCode verifies that handling.Code computes the current one-time
+// password of an entry and rejects entries without an OTP key.
+func TestCode(t *testing.T) {
+	// The secret is the RFC 6238 SHA-1 seed, base32 encoded.
+	s := storage.NewMemStore(
+		handling.Act{Website: "a.com", Pwd: "x"},
+		handling.Act{Website: "b.com", Pwd: "y", OTP: "otpauth://totp/b.com?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&digits=8"},
+	)
+
+	code, remaining, err := handling.Code(s, 1, time.Unix(59, 0))
+	if err != nil {
+		t.Fatalf("Code() failed: %v", err)
+	}
+	if code != "94287082" || remaining != time.Second {
+		t.Errorf("Code() = %s, %v; want 94287082, 1s", code, remaining)
+	}
+
+	// Entries without a key and invalid indexes are errors.
+	if _, _, err := handling.Code(s, 0, time.Now()); !errors.Is(err, handling.ErrNoOTP) {
+		t.Errorf("Code(0) error = %v; want ErrNoOTP", err)
+	}
+	if _, _, err := handling.Code(s, 2, time.Now()); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Errorf("Code(2) error = %v; want ErrIndexOutOfRange", err)
+	}
+}
+
+// TestCodeHOTP verifies that every HOTP code is saved as used before it
+// is returned, and that Resync catches up with a token that ran ahead.
+func TestCodeHOTP(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "vpn", Pwd: "x", OTP: "otpauth://hotp/vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0"},
+	)
+
+	// Each call hands out the next code.
+	for _, want := range []string{"755224", "287082"} {
+		code, _, err := handling.Code(s, 0, time.Now())
+		if err != nil {
+			t.Fatalf("Code() failed: %v", err)
+		}
+		if code != want {
+			t.Errorf("Code() = %s; want %s", code, want)
+		}
+	}
+
+	// The server accepted the code of counter 5 (254676).
+	skipped, err := handling.Resync(s, 0, "254676", 10)
+	if err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	if skipped != 3 {
+		t.Errorf("Resync() skipped %d codes; want 3", skipped)
+	}
+	if code, _, _ := handling.Code(s, 0, time.Now()); code != "287922" {
+		t.Errorf("Code() after Resync() = %s; want 287922", code)
+	}
+
+	// Unknown codes are reported.
+	if _, err := handling.Resync(s, 0, "000000", 10); !errors.Is(err, handling.ErrNoMatch) {
+		t.Errorf("Resync(000000) error = %v; want ErrNoMatch", err)
+	}
+}
+
+// rowStore is a MemStore with row-level updates, which counts how often
+// the whole store is saved.
+type rowStore struct {
+	*storage.MemStore
+	saves int
+}
+
+func (s *rowStore) Save(accounts []handling.Act) error {
+	s.saves++
+	return s.MemStore.Save(accounts)
+}
+
+// Update implements storage.Updater without counting as a save.
+func (s *rowStore) Update(index int, acc handling.Act) error {
+	accounts, _ := s.MemStore.Load()
+	accounts[index] = acc
+	return s.MemStore.Save(accounts)
+}
+
+// TestCodeHOTPUpdater verifies that advancing and resynchronising a HOTP
+// counter writes the single account on stores with row-level updates.
+func TestCodeHOTPUpdater(t *testing.T) {
+	s := &rowStore{MemStore: storage.NewMemStore(
+		handling.Act{Website: "other"},
+		handling.Act{Website: "vpn", OTP: "otpauth://hotp/vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0"},
+	)}
+
+	if code, _, err := handling.Code(s, 1, time.Now()); err != nil || code != "755224" {
+		t.Fatalf("Code() = %s, %v; want 755224", code, err)
+	}
+	if _, err := handling.Resync(s, 1, "254676", 10); err != nil {
+		t.Fatalf("Resync() failed: %v", err)
+	}
+	if code, _, _ := handling.Code(s, 1, time.Now()); code != "287922" {
+		t.Errorf("Code() after Resync() = %s; want 287922", code)
+	}
+	if s.saves != 0 {
+		t.Errorf("Code() and Resync() saved the whole store %d times; want row-level updates only", s.saves)
+	}
+}
+
+// TestImportOTP verifies that imported keys are attached to the matching
+// entries, that new entries are created for the others, and that a dry
+// run leaves the vault untouched.
+func TestImportOTP(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{Website: "github.com", Username: "alice", Pwd: "x"},
+		handling.Act{Website: "https://www.google.com/", Username: "bob", Email: "bob@gmail.com", Pwd: "y"},
+		handling.Act{Website: "google.com", Username: "carol", Email: "carol@gmail.com", Pwd: "z"},
+	)
+	keys := []otp.Key{}
+	for _, uri := range []string{
+		"otpauth://totp/GitHub:alice?secret=JBSWY3DPEHPK3PXP&issuer=GitHub",
+		"otpauth://totp/Google:carol@gmail.com?secret=GEZDGNBVGY3TQOJQ",
+		"otpauth://totp/Google:dave@gmail.com?secret=MFRGGZDFMZTWQ2LK",
+		"otpauth://totp/vpn?secret=KRSXG5A",
+	} {
+		k, err := otp.Parse(uri)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", uri, err)
+		}
+		keys = append(keys, k)
+	}
+
+	// A dry run plans the import without saving it.
+	plan, err := handling.ImportOTP(s, keys, true)
+	if err != nil {
+		t.Fatalf("ImportOTP(dry run) failed: %v", err)
+	}
+	want := []struct {
+		action handling.ImportAction
+		index  int
+	}{
+		{handling.ImportAttach, 0},
+		{handling.ImportAttach, 2},
+		{handling.ImportAttach, 1},
+		{handling.ImportCreate, 3},
+	}
+	for i, w := range want {
+		if plan[i].Action != w.action || plan[i].Entry.Index != w.index {
+			t.Errorf("plan[%d] = %s entry %d; want %s entry %d", i, plan[i].Action, plan[i].Entry.Index, w.action, w.index)
+		}
+	}
+	if accounts, _ := s.Load(); len(accounts) != 3 || accounts[0].OTP != "" {
+		t.Fatalf("dry run changed the vault: %+v", accounts)
+	}
+
+	// The real import writes the plan.
+	if _, err := handling.ImportOTP(s, keys, false); err != nil {
+		t.Fatalf("ImportOTP() failed: %v", err)
+	}
+	accounts, _ := s.Load()
+	if len(accounts) != 4 || accounts[3].Website != "vpn" || accounts[0].OTP == "" || accounts[2].OTP == "" {
+		t.Fatalf("ImportOTP() left %+v", accounts)
+	}
+
+	// Importing again changes nothing, and the key of another account is
+	// not replaced.
+	plan, err = handling.ImportOTP(s, keys[:3], false)
+	if err != nil {
+		t.Fatalf("ImportOTP() again failed: %v", err)
+	}
+	for i, p := range plan {
+		if p.Action != handling.ImportUnchanged {
+			t.Errorf("plan[%d].Action = %s on a second import; want unchanged", i, p.Action)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/otp"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// ImportAction is what importing a key does to the vault.
+type ImportAction string
+
+// Import actions.
+const (
+	// ImportAttach adds the key to an existing entry without one.
+	ImportAttach ImportAction = "attach"
+
+	// ImportReplace replaces the different key of an existing entry.
+	ImportReplace ImportAction = "replace"
+
+	// ImportCreate creates a new entry for the key.
+	ImportCreate ImportAction = "create"
+
+	// ImportUnchanged leaves an entry that already has the key alone.
+	ImportUnchanged ImportAction = "unchanged"
+)
+
+// Import describes what importing one key does.
+type Import struct {
+	// Key is the imported key.
+	Key otp.Key
+
+	// Action is what happens to the vault.
+	Action ImportAction
+
+	// Entry is the entry the key goes to, as it is after the import.
+	// New entries have the index they are created at.
+	Entry Entry
+}
+
+// ImportOTP matches each key to an existing entry and attaches the key to
+// it, or creates a new entry when none matches. A key matches the entries
+// whose website names its issuer and that already hold the same key,
+// have the key's account name as username or email, or have no key yet,
+// in that order of preference. The key of another entry is thus only
+// replaced when the account names agree. With dryRun, the vault is left
+// untouched and the returned plan only describes what an import would do.
+func ImportOTP(s storage.Store, keys []otp.Key, dryRun bool) ([]Import, error) {
+	unlock, err := s.Lock()
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := make([]Import, 0, len(keys))
+	changed := false
+	t := now()
+	for _, k := range keys {
+		issuer, name := keyNames(k)
+		uri := k.URI()
+
+		index := matchKey(accounts, k, issuer, name)
+		action := ImportCreate
+		switch {
+		case index < 0:
+			index = len(accounts)
+			acc := Act{ID: account.NewID(), Website: issuer, Username: name, OTP: uri, Created: t}
+			if strings.Contains(name, "@") {
+				acc.Email = name
+			}
+			accounts = append(accounts, acc)
+		case accounts[index].OTP == "":
+			action = ImportAttach
+		case sameSecret(accounts[index].OTP, k):
+			action = ImportUnchanged
+		default:
+			action = ImportReplace
+		}
+		if action == ImportAttach || action == ImportReplace {
+			accounts[index].OTP = uri
+		}
+		if action != ImportUnchanged {
+			accounts[index].Modified = t
+		}
+		changed = changed || action != ImportUnchanged
+
+		plan = append(plan, Import{Key: k, Action: action, Entry: Entry{Index: index, Account: accounts[index]}})
+	}
+
+	if dryRun || !changed {
+		return plan, nil
+	}
+	return plan, s.Save(accounts)
+}
+
+// keyNames returns the issuer and account name of a key. Labels have the
+// form "Issuer:account", where the issuer is optional and the issuer
+// parameter takes precedence.
+func keyNames(k otp.Key) (issuer, name string) {
+	name = k.Label
+	if i := strings.Index(name, ":"); i >= 0 {
+		issuer, name = name[:i], name[i+1:]
+	}
+	if k.Issuer != "" {
+		issuer = k.Issuer
+	}
+	issuer, name = strings.TrimSpace(issuer), strings.TrimSpace(name)
+	if issuer == "" {
+		issuer = name
+	}
+	return issuer, name
+}
+
+// matchKey returns the index of the account k, with the given issuer and
+// account name, belongs to, or -1.
+func matchKey(accounts []Act, k otp.Key, issuer, name string) int {
+	best, bestRank := -1, 0
+	for i, acc := range accounts {
+		if !sameService(acc.Website, issuer) {
+			continue
+		}
+
+		rank := 0
+		switch {
+		case acc.OTP != "" && sameSecret(acc.OTP, k):
+			rank = 3
+		case name != "" && (strings.EqualFold(acc.Username, name) || strings.EqualFold(acc.Email, name)):
+			rank = 2
+		case acc.OTP == "":
+			rank = 1
+		}
+		if rank > bestRank {
+			best, bestRank = i, rank
+		}
+	}
+	return best
+}
+
+// sameService reports whether website names the service of issuer. The
+// website may be a bare name or a domain: "GitHub" matches "github.com",
+// "www.github.com" and "https://github.com/login".
+func sameService(website, issuer string) bool {
+	normalize := func(s string) string {
+		return strings.ReplaceAll(strings.ToLower(strings.TrimSpace(s)), " ", "")
+	}
+	website, issuer = normalize(website), normalize(issuer)
+	if website == "" || issuer == "" {
+		return false
+	}
+	if website == issuer {
+		return true
+	}
+
+	// Reduce a URL or domain to the name before its suffix.
+	if _, rest, ok := strings.Cut(website, "://"); ok {
+		website = rest
+	}
+	website, _, _ = strings.Cut(website, "/")
+	website = strings.TrimPrefix(website, "www.")
+	name, _, _ := strings.Cut(website, ".")
+	return website == issuer || name == issuer
+}
+
+// sameSecret reports whether uri holds the secret of k.
+func sameSecret(uri string, k otp.Key) bool {
+	existing, err := otp.Parse(uri)
+	return err == nil && bytes.Equal(existing.Secret, k.Secret)
+}
@@ -0,0 +1,129 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"errors"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/otp"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+var (
+	// ErrNoOTP is returned when a one-time password is asked for an
+	// entry that has no OTP key.
+	ErrNoOTP = errors.New("entry has no OTP key")
+
+	// ErrNotHOTP is returned when a TOTP key is resynchronised.
+	ErrNotHOTP = errors.New("only HOTP keys can be resynchronised")
+
+	// ErrNoMatch is returned when Resync does not find the code.
+	ErrNoMatch = errors.New("code not found within the look-ahead window")
+)
+
+// Code returns the one-time password of the account at index. For a TOTP
+// key it is the code valid at now, returned with how long it remains
+// valid. For a HOTP key it is the code of the stored counter, and the
+// incremented counter is saved before the code is returned, so that no
+// code is ever handed out twice; the validity is then zero. The access
+// time of the account is recorded in the same write, which, like Touch,
+// does not count as a new version of the vault.
+func Code(s storage.Store, index int, now time.Time) (string, time.Duration, error) {
+	return keyCode(s, atIndex(index), now)
+}
+
+// CodeBy is like Code for the account named by selector, as described by
+// Select.
+func CodeBy(s storage.Store, selector string, now time.Time) (string, time.Duration, error) {
+	return keyCode(s, bySelector(selector), now)
+}
+
+// keyCode implements Code and CodeBy.
+func keyCode(s storage.Store, locate locator, at time.Time) (string, time.Duration, error) {
+	// Keep other processes out until the counter is written.
+	unlock, err := s.Lock()
+	if err != nil {
+		return "", 0, err
+	}
+	defer unlock()
+
+	accounts, index, k, err := loadKey(s, locate)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var code string
+	var remaining time.Duration
+	if k.Type == otp.TypeHOTP {
+		code = k.Next()
+		accounts[index].OTP = k.URI()
+	} else {
+		code, remaining = k.Code(at)
+	}
+	accounts[index].Accessed = now()
+	if err := record(s, accounts, index); err != nil {
+		return "", 0, err
+	}
+	return code, remaining, nil
+}
+
+// Resync moves the counter of the HOTP key at index past code, a code the
+// server accepted, looking at most window codes ahead. It returns how
+// many codes were skipped.
+func Resync(s storage.Store, index int, code string, window int) (int, error) {
+	return resyncKey(s, atIndex(index), code, window)
+}
+
+// ResyncBy is like Resync for the account named by selector, as described
+// by Select.
+func ResyncBy(s storage.Store, selector string, code string, window int) (int, error) {
+	return resyncKey(s, bySelector(selector), code, window)
+}
+
+// resyncKey implements Resync and ResyncBy.
+func resyncKey(s storage.Store, locate locator, code string, window int) (int, error) {
+	unlock, err := s.Lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	accounts, index, k, err := loadKey(s, locate)
+	if err != nil {
+		return 0, err
+	}
+	if k.Type != otp.TypeHOTP {
+		return 0, ErrNotHOTP
+	}
+
+	skipped, ok := k.Resync(code, window)
+	if !ok {
+		return 0, ErrNoMatch
+	}
+	accounts[index].OTP = k.URI()
+	return skipped, put(s, accounts, index)
+}
+
+// loadKey loads the accounts, locates one and parses its OTP key.
+func loadKey(s storage.Store, locate locator) ([]Act, int, otp.Key, error) {
+	accounts, err := s.Load()
+	if err != nil {
+		return nil, 0, otp.Key{}, err
+	}
+	index, err := locate(accounts)
+	if err != nil {
+		return nil, 0, otp.Key{}, err
+	}
+	if accounts[index].OTP == "" {
+		return nil, 0, otp.Key{}, ErrNoOTP
+	}
+
+	k, err := otp.Parse(accounts[index].OTP)
+	if err != nil {
+		return nil, 0, otp.Key{}, err
+	}
+	return accounts, index, k, nil
+}
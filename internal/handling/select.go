@@ -0,0 +1,273 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// ErrNotFound is returned when a selector names no entry.
+var ErrNotFound = errors.New("no entry matches")
+
+// ShortIDLen is the length of the ID prefix shown in listings.
+const ShortIDLen = 8
+
+// MinIDPrefix is the shortest ID prefix a selector may use, so that a
+// mistyped website cannot select an unrelated entry by chance.
+const MinIDPrefix = 4
+
+// ErrWordPrefix is returned for a bare selector of letters only that names
+// no website but starts an ID. Such a selector is more likely a mistyped
+// website than an ID, so it must be written with "id:".
+var ErrWordPrefix = errors.New(`selector could be a website; prefix it with "id:" to select by ID`)
+
+// AmbiguousError is returned when a selector names more than one entry.
+type AmbiguousError struct {
+	// Selector is the ambiguous selector.
+	Selector string
+
+	// Matches are the entries it names.
+	Matches []Entry
+}
+
+// Error lists the matching entries by ID, website and username, leaving
+// out their secrets.
+func (e *AmbiguousError) Error() string {
+	names := make([]string, 0, len(e.Matches))
+	for _, m := range e.Matches {
+		names = append(names, fmt.Sprintf("%s (%s, %s)", m.ShortID(), m.Account.Website, m.Account.Username))
+	}
+	return fmt.Sprintf("%q matches %d entries: %s", e.Selector, len(e.Matches), strings.Join(names, "; "))
+}
+
+// ShortID returns the prefix of the entry's ID shown in listings.
+func (e Entry) ShortID() string {
+	if len(e.Account.ID) > ShortIDLen {
+		return e.Account.ID[:ShortIDLen]
+	}
+	return e.Account.ID
+}
+
+// Selector returns a selector naming the entry for as long as it exists:
+// its ID, or its index for an entry without one. Commands that show an
+// entry before changing it pass the selector on, so that the change
+// applies to the same entry even if others were added or deleted since.
+func (e Entry) Selector() string {
+	if e.Account.ID == "" {
+		return strconv.Itoa(e.Index)
+	}
+	return "id:" + e.Account.ID
+}
+
+// locator finds the account an operation works on among the loaded
+// accounts. Operations call it under the store's lock, so that the account
+// cannot change position between being found and being written.
+type locator func(accounts []Act) (int, error)
+
+// atIndex locates the account at index.
+func atIndex(index int) locator {
+	return func(accounts []Act) (int, error) {
+		if index < 0 || index >= len(accounts) {
+			return 0, storage.ErrIndexOutOfRange
+		}
+		return index, nil
+	}
+}
+
+// bySelector locates the account named by selector, as described by
+// Select.
+func bySelector(selector string) locator {
+	return func(accounts []Act) (int, error) {
+		return selectIndex(accounts, selector)
+	}
+}
+
+// Select returns the entry named by selector, which is one of, in order
+// of precedence:
+//
+//   - an index, as shown in listings, for compatibility with older
+//     scripts; indexes change when entries are deleted;
+//   - "id:" followed by an ID or ID prefix;
+//   - a full ID;
+//   - a website, or website/username, matched ignoring case, where the
+//     username may also be the email;
+//   - an ID prefix of at least MinIDPrefix characters containing a digit;
+//     prefixes of letters only look like websites and need "id:".
+//
+// A selector that names several entries returns an *AmbiguousError.
+// The operations ending in By take a selector as well and resolve it under
+// the store's lock.
+func Select(s storage.Store, selector string) (Entry, error) {
+	// Stores with a lookup index find websites without loading every
+	// account.
+	if f, ok := s.(storage.Finder); ok {
+		if e, found, err := find(f, selector); found || err != nil {
+			return e, err
+		}
+	}
+
+	accounts, err := s.Load()
+	if err != nil {
+		return Entry{}, err
+	}
+	index, err := selectIndex(accounts, selector)
+	if err != nil {
+		return Entry{}, err
+	}
+	return Entry{Index: index, Account: accounts[index]}, nil
+}
+
+// find looks selector up as a website, or website/username, through the
+// lookup index of f. It reports found as false, leaving Select to load the
+// accounts, when the selector may be an index or an ID, which take
+// precedence over websites, and when the index has no match, since the
+// selector may still name an email or an ID prefix.
+func find(f storage.Finder, selector string) (e Entry, found bool, err error) {
+	selector = strings.TrimSpace(selector)
+	if _, err := strconv.Atoi(selector); err == nil || selector == "" || strings.HasPrefix(selector, "id:") {
+		return Entry{}, false, nil
+	}
+	if len(selector) == account.IDLen && account.IsID(strings.ToLower(selector)) {
+		return Entry{}, false, nil
+	}
+
+	matches, err := f.Find(selector, "")
+	if website, username, ok := cutLast(selector, "/"); err == nil && len(matches) == 0 && ok {
+		matches, err = f.Find(website, username)
+	}
+	if err != nil || len(matches) == 0 {
+		return Entry{}, false, err
+	}
+
+	entries := make([]Entry, 0, len(matches))
+	for _, m := range matches {
+		entries = append(entries, Entry{Index: m.Index, Account: m.Account})
+	}
+	if len(entries) > 1 {
+		return Entry{}, true, &AmbiguousError{Selector: selector, Matches: entries}
+	}
+	return entries[0], true, nil
+}
+
+// selectIndex returns the index of the account named by selector, as
+// described by Select.
+func selectIndex(accounts []Act, selector string) (int, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return 0, ErrNotFound
+	}
+
+	// An index.
+	if i, err := strconv.Atoi(selector); err == nil {
+		if i < 0 || i >= len(accounts) {
+			return 0, storage.ErrIndexOutOfRange
+		}
+		return i, nil
+	}
+
+	// An explicit ID prefix.
+	if prefix, ok := strings.CutPrefix(selector, "id:"); ok {
+		return unique(accounts, selector, func(acc Act) bool {
+			return prefix != "" && strings.HasPrefix(acc.ID, strings.ToLower(prefix))
+		})
+	}
+
+	// A full ID.
+	for i, acc := range accounts {
+		if acc.ID != "" && acc.ID == strings.ToLower(selector) {
+			return i, nil
+		}
+	}
+
+	// A website, or website/username. Websites may contain slashes
+	// themselves, so the whole selector is tried as a website first.
+	i, err := unique(accounts, selector, func(acc Act) bool {
+		return strings.EqualFold(acc.Website, selector)
+	})
+	if website, username, ok := cutLast(selector, "/"); errors.Is(err, ErrNotFound) && ok {
+		i, err = unique(accounts, selector, func(acc Act) bool {
+			return strings.EqualFold(acc.Website, website) &&
+				(strings.EqualFold(acc.Username, username) || strings.EqualFold(acc.Email, username))
+		})
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return i, err
+	}
+
+	// An ID prefix.
+	prefix := strings.ToLower(selector)
+	if len(prefix) < MinIDPrefix || !account.IsID(prefix) {
+		return 0, fmt.Errorf("%w %q", ErrNotFound, selector)
+	}
+	i, err = unique(accounts, selector, func(acc Act) bool {
+		return strings.HasPrefix(acc.ID, prefix)
+	})
+	if err == nil && !strings.ContainsAny(prefix, "234567") {
+		// Words like "gitlab" or "mail" are valid ID prefixes; do not let
+		// a mistyped website select an unrelated entry.
+		return 0, fmt.Errorf("%w %q: %w", ErrNotFound, selector, ErrWordPrefix)
+	}
+	return i, err
+}
+
+// unique returns the index of the only account for which match is true.
+func unique(accounts []Act, selector string, match func(Act) bool) (int, error) {
+	var matches []Entry
+	for i, acc := range accounts {
+		if match(acc) {
+			matches = append(matches, Entry{Index: i, Account: acc})
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return 0, fmt.Errorf("%w %q", ErrNotFound, selector)
+	case 1:
+		return matches[0].Index, nil
+	default:
+		return 0, &AmbiguousError{Selector: selector, Matches: matches}
+	}
+}
+
+// cutLast slices s around the last instance of sep.
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+// AssignIDs gives an ID to every account that has none, as the accounts
+// saved by older versions, and returns how many it assigned. The store is
+// only written when some account was missing an ID.
+func AssignIDs(s storage.Store) (int, error) {
+	unlock, err := s.Lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for i := range accounts {
+		if accounts[i].ID == "" {
+			accounts[i].ID = account.NewID()
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	return n, s.Save(accounts)
+}
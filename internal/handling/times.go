@@ -0,0 +1,149 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/storage"
+)
+
+// now returns the time recorded in the account timestamps: the current
+// time in UTC, to the second. Tests replace it to control the clock.
+var now = func() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+// Touch records the current time as when the account at index was last
+// accessed. Callers touch an account whenever they show or copy its
+// password or another of its secrets; Code touches the account itself.
+// The write does not count as a new version of the vault, so it never
+// rotates backups.
+func Touch(s storage.Store, index int) error {
+	return touch(s, atIndex(index))
+}
+
+// TouchBy is like Touch for the account named by selector, as described
+// by Select.
+func TouchBy(s storage.Store, selector string) error {
+	return touch(s, bySelector(selector))
+}
+
+// touch implements Touch and TouchBy.
+func touch(s storage.Store, locate locator) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return err
+	}
+	index, err := locate(accounts)
+	if err != nil {
+		return err
+	}
+
+	accounts[index].Accessed = now()
+	return record(s, accounts, index)
+}
+
+// TouchAll is like Touch for every entry of entries, such as those a
+// listing has just shown, in a single write. Entries deleted since they
+// were listed are skipped.
+func TouchAll(s storage.Store, entries []Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return err
+	}
+	at := now()
+	indexes := make([]int, 0, len(entries))
+	for _, e := range entries {
+		index, err := bySelector(e.Selector())(accounts)
+		if errors.Is(err, ErrNotFound) || errors.Is(err, storage.ErrIndexOutOfRange) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		accounts[index].Accessed = at
+		indexes = append(indexes, index)
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+	return record(s, accounts, indexes...)
+}
+
+// record writes the accounts at indexes of accounts, which were loaded
+// from s, back to s like put, for a change that only keeps records, such
+// as an access time. Stores that keep backups do not count it as a new
+// version.
+func record(s storage.Store, accounts []Act, indexes ...int) error {
+	if u, ok := s.(storage.Updater); ok {
+		for _, i := range indexes {
+			if err := u.Update(i, accounts[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if r, ok := s.(storage.Recorder); ok {
+		return r.Record(accounts)
+	}
+	return s.Save(accounts)
+}
+
+// SortKeys lists the keys accepted by SortEntries: the index, the website,
+// and the creation, modification, password change and access times.
+var SortKeys = []string{"index", "website", "created", "modified", "changed", "accessed"}
+
+// SortEntries orders entries by the given key, one of SortKeys. Timestamps
+// sort oldest first, with the unknown ones before all others, and websites
+// alphabetically ignoring case. Entries with the same key stay in index
+// order.
+func SortEntries(entries []Entry, key string) error {
+	var compare func(a, b Act) int
+	switch key {
+	case "index":
+		compare = func(a, b Act) int { return 0 }
+	case "website":
+		compare = func(a, b Act) int {
+			return strings.Compare(strings.ToLower(a.Website), strings.ToLower(b.Website))
+		}
+	case "created":
+		compare = func(a, b Act) int { return a.Created.Compare(b.Created) }
+	case "modified":
+		compare = func(a, b Act) int { return a.Modified.Compare(b.Modified) }
+	case "changed":
+		compare = func(a, b Act) int { return a.PasswordChanged.Compare(b.PasswordChanged) }
+	case "accessed":
+		compare = func(a, b Act) int { return a.Accessed.Compare(b.Accessed) }
+	default:
+		return fmt.Errorf("unknown sort key %q: use one of %s", key, strings.Join(SortKeys, ", "))
+	}
+
+	slices.SortFunc(entries, func(a, b Entry) int {
+		return cmp.Or(compare(a.Account, b.Account), cmp.Compare(a.Index, b.Index))
+	})
+	return nil
+}
@@ -0,0 +1,228 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package handling_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/handling"
+	"github.com/nullzeiger/pwdcli/internal/otp"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// day is the unit of the ages in these tests.
+const day = 24 * time.Hour
+
+// TestTimestamps verifies which operations record the creation,
+// modification and password change times.
+func TestTimestamps(t *testing.T) {
+	s := storage.NewMemStore()
+	t0 := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// A new entry records the time in all three.
+	handling.SetNow(t, t0)
+	handling.Create(s, handling.Act{Website: "site", Username: "u", Email: "e", Pwd: "p"})
+	acc, _ := s.Load()
+	if got := acc[0]; !got.Created.Equal(t0) || !got.Modified.Equal(t0) || !got.PasswordChanged.Equal(t0) || !got.Accessed.IsZero() {
+		t.Fatalf("After Create, timestamps = %v %v %v %v; want %v and no access", got.Created, got.Modified, got.PasswordChanged, got.Accessed, t0)
+	}
+
+	// Changing another field leaves the password change time alone.
+	t1 := t0.Add(day)
+	handling.SetNow(t, t1)
+	folder := "work"
+	entry, _, err := handling.Update(s, 0, handling.Edit{Folder: &folder})
+	if err != nil {
+		t.Fatalf("Update() failed: %v", err)
+	}
+	if got := entry.Account; !got.Created.Equal(t0) || !got.Modified.Equal(t1) || !got.PasswordChanged.Equal(t0) {
+		t.Errorf("After Update, timestamps = %v %v %v; want %v %v %v", got.Created, got.Modified, got.PasswordChanged, t0, t1, t0)
+	}
+
+	// An edit without changes records nothing.
+	handling.SetNow(t, t1.Add(day))
+	if entry, _, _ := handling.Update(s, 0, handling.Edit{Folder: &folder}); !entry.Account.Modified.Equal(t1) {
+		t.Errorf("Update() without changes set Modified to %v; want %v", entry.Account.Modified, t1)
+	}
+
+	// Moving the folder modifies the entries in it.
+	t2 := t1.Add(2 * day)
+	handling.SetNow(t, t2)
+	if _, err := handling.MoveFolder(s, "work", "office"); err != nil {
+		t.Fatalf("MoveFolder() failed: %v", err)
+	}
+	acc, _ = s.Load()
+	if !acc[0].Modified.Equal(t2) || !acc[0].PasswordChanged.Equal(t0) {
+		t.Errorf("After MoveFolder, Modified = %v, PasswordChanged = %v; want %v, %v", acc[0].Modified, acc[0].PasswordChanged, t2, t0)
+	}
+}
+
+// TestTouch verifies that handling.Touch records the access time of one
+// entry only, without counting as a modification.
+func TestTouch(t *testing.T) {
+	s := storage.NewMemStore(handling.Act{Website: "a"}, handling.Act{Website: "b"})
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	handling.SetNow(t, now)
+
+	if err := handling.Touch(s, 1); err != nil {
+		t.Fatalf("Touch(1) failed: %v", err)
+	}
+	acc, _ := s.Load()
+	if !acc[0].Accessed.IsZero() || !acc[1].Accessed.Equal(now) || !acc[1].Modified.IsZero() {
+		t.Errorf("After Touch(1), Accessed = %v, %v and Modified = %v; want zero, %v and zero", acc[0].Accessed, acc[1].Accessed, acc[1].Modified, now)
+	}
+
+	// Attempt to touch an invalid index
+	if err := handling.Touch(s, 5); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Errorf("Touch(5) error = %v; want ErrIndexOutOfRange", err)
+	}
+}
+
+// TestTouchAll verifies that handling.TouchAll records an access to every
+// listed entry, found again by ID, and skips entries deleted since.
+func TestTouchAll(t *testing.T) {
+	s := storage.NewMemStore(
+		handling.Act{ID: "aaaa2aaaaaaaaaaa", Website: "a"},
+		handling.Act{ID: "bbbb2bbbbbbbbbbb", Website: "b"},
+		handling.Act{ID: "cccc2ccccccccccc", Website: "c"},
+	)
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	handling.SetNow(t, now)
+
+	listed, err := handling.List(s)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	if _, err := handling.DeleteBy(s, "a"); err != nil {
+		t.Fatalf("DeleteBy(a) failed: %v", err)
+	}
+	if err := handling.TouchAll(s, listed[1:]); err != nil {
+		t.Fatalf("TouchAll() failed: %v", err)
+	}
+	if err := handling.TouchAll(s, listed[:1]); err != nil {
+		t.Errorf("TouchAll() of a deleted entry failed: %v", err)
+	}
+
+	acc, _ := s.Load()
+	for _, a := range acc {
+		if !a.Accessed.Equal(now) || !a.Modified.IsZero() {
+			t.Errorf("After TouchAll(), %s has Accessed = %v and Modified = %v; want %v and zero", a.Website, a.Accessed, a.Modified, now)
+		}
+	}
+}
+
+// TestTouchKeepsBackups verifies that recording accesses, as show, copy
+// and otp do, leaves the backups of a file vault unchanged, so that
+// looking passwords up never pushes real changes out of retention.
+func TestTouchKeepsBackups(t *testing.T) {
+	s := storage.NewFileStore(filepath.Join(t.TempDir(), "vault"))
+	s.Params = vault.Params{Time: 1, Memory: 64, Threads: 1}
+	s.Unlock([]byte("master"))
+	s.Backups = 2
+	if err := s.Create(); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	handling.Create(s, handling.Act{Website: "a.com", OTP: "otpauth://hotp/a?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=0"})
+	before, err := os.ReadFile(storage.BackupName(s.Path, 1))
+	if err != nil {
+		t.Fatalf("ReadFile(backup 1) failed: %v", err)
+	}
+
+	if err := handling.TouchBy(s, "a.com"); err != nil {
+		t.Fatalf("TouchBy() failed: %v", err)
+	}
+	if _, _, err := handling.CodeBy(s, "a.com", time.Now()); err != nil {
+		t.Fatalf("CodeBy() failed: %v", err)
+	}
+
+	// Both were written to the vault, but not as new versions.
+	acc, _ := s.Load()
+	k, err := otp.Parse(acc[0].OTP)
+	if acc[0].Accessed.IsZero() || err != nil || k.Counter != 1 {
+		t.Errorf("After TouchBy and CodeBy, account = %+v; want an access time and counter 1", acc[0])
+	}
+	after, err := os.ReadFile(storage.BackupName(s.Path, 1))
+	if err != nil || !bytes.Equal(before, after) {
+		t.Errorf("backup 1 changed by recording accesses: %v", err)
+	}
+	if _, err := os.Stat(storage.BackupName(s.Path, 2)); err == nil {
+		t.Error("backup 2 exists; want accesses to keep no backups")
+	}
+}
+
+// timeStore returns a store with entries of different ages, relative to
+// now.
+func timeStore(now time.Time) *storage.MemStore {
+	return storage.NewMemStore(
+		handling.Act{Website: "old", Created: now.Add(-400 * day), Modified: now.Add(-10 * day),
+			PasswordChanged: now.Add(-400 * day), Accessed: now.Add(-1 * day)},
+		handling.Act{Website: "Fresh", Created: now.Add(-30 * day), Modified: now.Add(-30 * day),
+			PasswordChanged: now.Add(-30 * day)},
+		handling.Act{Website: "legacy"},
+		handling.Act{Website: "middle", Created: now.Add(-200 * day), Modified: now.Add(-5 * day),
+			PasswordChanged: now.Add(-190 * day), Accessed: now.Add(-100 * day)},
+	)
+}
+
+// TestFilterAge verifies that entries are selected by the age of their
+// password and of their last access, counting unknown times as old.
+func TestFilterAge(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	entries, _ := handling.List(timeStore(now))
+
+	tests := []struct {
+		filter handling.Filter
+		want   []string
+	}{
+		{handling.Filter{ChangedBefore: now.Add(-180 * day)}, []string{"old", "legacy", "middle"}},
+		{handling.Filter{ChangedBefore: now.Add(-365 * day)}, []string{"old", "legacy"}},
+		{handling.Filter{AccessedBefore: now.Add(-90 * day)}, []string{"Fresh", "legacy", "middle"}},
+		{handling.Filter{ChangedBefore: now.Add(-180 * day), AccessedBefore: now.Add(-7 * day)}, []string{"legacy", "middle"}},
+	}
+	for _, tt := range tests {
+		if got := websites(tt.filter.Apply(entries)); !slices.Equal(got, tt.want) {
+			t.Errorf("%+v.Apply() = %v; want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// TestSortEntries verifies each sort key, with unknown times first and
+// ties in index order, and that unknown keys are rejected.
+func TestSortEntries(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		key  string
+		want []string
+	}{
+		{"index", []string{"old", "Fresh", "legacy", "middle"}},
+		{"website", []string{"Fresh", "legacy", "middle", "old"}},
+		{"created", []string{"legacy", "old", "middle", "Fresh"}},
+		{"modified", []string{"legacy", "Fresh", "old", "middle"}},
+		{"changed", []string{"legacy", "old", "middle", "Fresh"}},
+		{"accessed", []string{"Fresh", "legacy", "middle", "old"}},
+	}
+	for _, tt := range tests {
+		entries, _ := handling.List(timeStore(now))
+		slices.Reverse(entries)
+		if err := handling.SortEntries(entries, tt.key); err != nil {
+			t.Fatalf("SortEntries(%q) failed: %v", tt.key, err)
+		}
+		if got := websites(entries); !slices.Equal(got, tt.want) {
+			t.Errorf("SortEntries(%q) = %v; want %v", tt.key, got, tt.want)
+		}
+	}
+
+	if err := handling.SortEntries(nil, "size"); err == nil {
+		t.Error("SortEntries(\"size\") succeeded; want an error")
+	}
+}
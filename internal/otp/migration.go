@@ -0,0 +1,196 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otp
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrMigration is returned for otpauth-migration URIs whose payload cannot
+// be decoded.
+var ErrMigration = errors.New("invalid otpauth-migration payload")
+
+// Keys returns the keys of an otpauth:// URI, or of an
+// otpauth-migration://offline URI as exported by Google Authenticator,
+// which may hold several keys.
+func Keys(uri string) ([]Key, error) {
+	uri = strings.TrimSpace(uri)
+	if !strings.HasPrefix(strings.ToLower(uri), "otpauth-migration:") {
+		k, err := Parse(uri)
+		if err != nil {
+			return nil, err
+		}
+		return []Key{k}, nil
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMigration, err)
+	}
+	data := u.Query().Get("data")
+	if data == "" {
+		return nil, fmt.Errorf("%w: missing data", ErrMigration)
+	}
+
+	// The payload is standard base64, but padding is sometimes dropped
+	// and "+" turned into a space by careless URL handling.
+	data = strings.TrimRight(strings.ReplaceAll(data, " ", "+"), "=")
+	payload, err := base64.RawStdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: data is not valid base64", ErrMigration)
+	}
+	return decodeMigration(payload)
+}
+
+// The payload is a protocol buffer message:
+//
+//	message MigrationPayload {
+//	  repeated OtpParameters otp_parameters = 1;
+//	  int32 version = 2;
+//	  int32 batch_size = 3;
+//	  int32 batch_index = 4;
+//	  int32 batch_id = 5;
+//	}
+//
+//	message OtpParameters {
+//	  bytes secret = 1;
+//	  string name = 2;
+//	  string issuer = 3;
+//	  Algorithm algorithm = 4; // 1 SHA1, 2 SHA256, 3 SHA512, 4 MD5
+//	  DigitCount digits = 5;   // 1 six, 2 eight
+//	  OtpType type = 6;        // 1 HOTP, 2 TOTP
+//	  int64 counter = 7;
+//	}
+//
+// Only the few wire types it uses are decoded.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// decodeMigration decodes a MigrationPayload message.
+func decodeMigration(payload []byte) ([]Key, error) {
+	keys := []Key{}
+	err := decodeMessage(payload, func(field int, value []byte, _ uint64) error {
+		if field != 1 || value == nil {
+			return nil
+		}
+		k, err := decodeParameters(value)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, k)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no keys", ErrMigration)
+	}
+	return keys, nil
+}
+
+// decodeParameters decodes an OtpParameters message.
+func decodeParameters(msg []byte) (Key, error) {
+	k := Key{Type: TypeTOTP, Algorithm: DefaultAlgorithm, Digits: DefaultDigits, Period: DefaultPeriod}
+	err := decodeMessage(msg, func(field int, value []byte, n uint64) error {
+		switch field {
+		case 1:
+			k.Secret = append([]byte(nil), value...)
+		case 2:
+			k.Label = string(value)
+		case 3:
+			k.Issuer = string(value)
+		case 4:
+			switch n {
+			case 0, 1:
+				k.Algorithm = SHA1
+			case 2:
+				k.Algorithm = SHA256
+			case 3:
+				k.Algorithm = SHA512
+			default:
+				return fmt.Errorf("%w: unsupported algorithm %d", ErrMigration, n)
+			}
+		case 5:
+			if n == 2 {
+				k.Digits = 8
+			}
+		case 6:
+			if n == 1 {
+				k.Type = TypeHOTP
+			}
+		case 7:
+			k.Counter = n
+		}
+		return nil
+	})
+	if err != nil {
+		return Key{}, err
+	}
+	if len(k.Secret) == 0 {
+		return Key{}, fmt.Errorf("%w: missing secret", ErrMigration)
+	}
+	return k, nil
+}
+
+// decodeMessage calls fn for every field of a protocol buffer message,
+// with the contents of length-delimited fields or the value of varints.
+// Fields of other wire types are skipped.
+func decodeMessage(msg []byte, fn func(field int, value []byte, n uint64) error) error {
+	for len(msg) > 0 {
+		tag, size := binary.Uvarint(msg)
+		if size <= 0 {
+			return fmt.Errorf("%w: truncated field", ErrMigration)
+		}
+		msg = msg[size:]
+
+		field, wire := int(tag>>3), tag&7
+		var (
+			value []byte
+			n     uint64
+		)
+		switch wire {
+		case wireVarint:
+			n, size = binary.Uvarint(msg)
+			if size <= 0 {
+				return fmt.Errorf("%w: truncated varint", ErrMigration)
+			}
+			msg = msg[size:]
+		case wireBytes:
+			length, size := binary.Uvarint(msg)
+			if size <= 0 || length > uint64(len(msg)-size) {
+				return fmt.Errorf("%w: truncated field", ErrMigration)
+			}
+			value = msg[size : size+int(length)]
+			msg = msg[size+int(length):]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return fmt.Errorf("%w: truncated field", ErrMigration)
+			}
+			msg = msg[8:]
+		case wireFixed32:
+			if len(msg) < 4 {
+				return fmt.Errorf("%w: truncated field", ErrMigration)
+			}
+			msg = msg[4:]
+		default:
+			return fmt.Errorf("%w: unsupported wire type %d", ErrMigration, wire)
+		}
+
+		if err := fn(field, value, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package otp_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/otp"
+)
+
+// field encodes a protocol buffer field: a varint for integers, a
+// length-delimited field for strings and bytes.
+func field(number int, value any) []byte {
+	varint := func(n uint64) []byte {
+		b := []byte{}
+		for n >= 0x80 {
+			b = append(b, byte(n)|0x80)
+			n >>= 7
+		}
+		return append(b, byte(n))
+	}
+
+	switch v := value.(type) {
+	case int:
+		return append(varint(uint64(number)<<3), varint(uint64(v))...)
+	case string:
+		return field(number, []byte(v))
+	case []byte:
+		b := append(varint(uint64(number)<<3|2), varint(uint64(len(v)))...)
+		return append(b, v...)
+	}
+	panic("unsupported field value")
+}
+
+// message concatenates encoded fields into a message.
+func message(fields ...[]byte) []byte {
+	msg := []byte{}
+	for _, f := range fields {
+		msg = append(msg, f...)
+	}
+	return msg
+}
+
+// migrationURI wraps the fields of a payload in an otpauth-migration URI.
+func migrationURI(fields ...[]byte) string {
+	data := base64.StdEncoding.EncodeToString(message(fields...))
+	return "otpauth-migration://offline?data=" + url.QueryEscape(data)
+}
+
+// TestKeysMigration verifies the decoding of a Google Authenticator export
+// holding a TOTP and a HOTP key.
+func TestKeysMigration(t *testing.T) {
+	totp := message(
+		field(1, []byte("Hello!\xde\xad\xbe\xef")),
+		field(2, "Example:alice@example.com"),
+		field(3, "Example"),
+		field(4, 2), // SHA256
+		field(5, 2), // eight digits
+		field(6, 2), // TOTP
+	)
+	hotp := message(
+		field(1, []byte("12345678901234567890")),
+		field(2, "vpn"),
+		field(6, 1), // HOTP
+		field(7, 42),
+	)
+
+	uri := migrationURI(field(1, totp), field(1, hotp), field(2, 1), field(3, 1), field(4, 0), field(5, 7))
+	keys, err := otp.Keys(uri)
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	if len(keys) != 2 {
+		t.Fatalf("Keys() returned %d keys; want 2", len(keys))
+	}
+
+	k := keys[0]
+	if k.Type != otp.TypeTOTP || k.Label != "Example:alice@example.com" || k.Issuer != "Example" ||
+		k.Algorithm != otp.SHA256 || k.Digits != 8 || string(k.Secret) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("Keys()[0] = %+v; want the Example TOTP key", k)
+	}
+
+	// Unset fields take their defaults.
+	k = keys[1]
+	if k.Type != otp.TypeHOTP || k.Counter != 42 || k.Algorithm != otp.SHA1 || k.Digits != 6 {
+		t.Errorf("Keys()[1] = %+v; want the vpn HOTP key at counter 42", k)
+	}
+}
+
+// TestKeysSample verifies a payload as exported by the app, whose padding
+// was lost on the way.
+func TestKeysSample(t *testing.T) {
+	keys, err := otp.Keys("otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGEV4YW1wbGU6YWxpY2VAZ29vZ2xlLmNvbRoHRXhhbXBsZTAC")
+	if err != nil {
+		t.Fatalf("Keys() failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0].Label != "Example:alice@google.com" || keys[0].Issuer != "Example" {
+		t.Fatalf("Keys() = %+v; want the Example key", keys)
+	}
+}
+
+// TestKeysURI verifies that a plain otpauth URI yields its single key.
+func TestKeysURI(t *testing.T) {
+	keys, err := otp.Keys("otpauth://totp/x?secret=JBSWY3DPEHPK3PXP")
+	if err != nil || len(keys) != 1 || keys[0].Label != "x" {
+		t.Fatalf("Keys() = %+v, %v; want the key x", keys, err)
+	}
+}
+
+// TestKeysInvalid verifies that broken payloads are rejected.
+func TestKeysInvalid(t *testing.T) {
+	for name, uri := range map[string]string{
+		"no data":        "otpauth-migration://offline",
+		"not base64":     "otpauth-migration://offline?data=%21%21%21",
+		"truncated":      migrationURI(field(1, field(1, []byte("secret")))[:5]),
+		"no keys":        migrationURI(field(2, 1)),
+		"no secret":      migrationURI(field(1, field(2, "x"))),
+		"bad algorithm":  migrationURI(field(1, message(field(1, []byte("s")), field(4, 4)))),
+		"bad wire type":  migrationURI([]byte{0x0b}),
+		"truncated size": migrationURI([]byte{0x0a, 0x80}),
+	} {
+		if _, err := otp.Keys(uri); !errors.Is(err, otp.ErrMigration) {
+			t.Errorf("%s: Keys() error = %v; want ErrMigration", name, err)
+		}
+	}
+}
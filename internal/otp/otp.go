@@ -0,0 +1,264 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package otp generates the one-time passwords used for two-factor
+// authentication. Keys are exchanged as otpauth:// URIs, the format of the
+// QR codes shown by websites when 2FA is enabled, and codes are computed
+// as in RFC 4226 (HOTP, counter-based) and RFC 6238 (TOTP, time-based).
+package otp
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Algorithm is the HMAC hash function of a key.
+type Algorithm string
+
+// Supported algorithms, named as in otpauth URIs.
+const (
+	SHA1   Algorithm = "SHA1"
+	SHA256 Algorithm = "SHA256"
+	SHA512 Algorithm = "SHA512"
+)
+
+// hash returns the constructor of the hash function.
+func (a Algorithm) hash() func() hash.Hash {
+	switch a {
+	case SHA256:
+		return sha256.New
+	case SHA512:
+		return sha512.New
+	default:
+		return sha1.New
+	}
+}
+
+// Type is the kind of a key, named as in otpauth URIs.
+type Type string
+
+// Supported key types.
+const (
+	// TypeTOTP keys derive codes from the current time.
+	TypeTOTP Type = "totp"
+
+	// TypeHOTP keys derive codes from a counter that is incremented
+	// every time a code is used.
+	TypeHOTP Type = "hotp"
+)
+
+// Defaults of the optional URI parameters.
+const (
+	DefaultAlgorithm = SHA1
+	DefaultDigits    = 6
+	DefaultPeriod    = 30 * time.Second
+)
+
+// DefaultWindow is how many counters Resync looks ahead by default.
+const DefaultWindow = 100
+
+// ErrURI is returned for URIs that do not describe a usable key.
+var ErrURI = errors.New("invalid otpauth URI")
+
+// Key is a TOTP or HOTP key, as described by an otpauth URI.
+type Key struct {
+	// Type is the kind of key.
+	Type Type
+
+	// Label names the account the key belongs to, usually as
+	// "Issuer:account".
+	Label string
+
+	// Issuer names the provider of the account.
+	Issuer string
+
+	// Secret is the shared secret.
+	Secret []byte
+
+	// Algorithm is the HMAC hash function.
+	Algorithm Algorithm
+
+	// Digits is the number of digits of the codes.
+	Digits int
+
+	// Period is how long each code of a TOTP key is valid.
+	Period time.Duration
+
+	// Counter is the counter of the next code of a HOTP key.
+	Counter uint64
+}
+
+// Parse parses an otpauth://totp/ or otpauth://hotp/ URI. Missing
+// optional parameters take their default values; HOTP URIs must carry
+// the counter.
+func Parse(uri string) (Key, error) {
+	u, err := url.Parse(strings.TrimSpace(uri))
+	if err != nil {
+		return Key{}, fmt.Errorf("%w: %v", ErrURI, err)
+	}
+	if u.Scheme != "otpauth" {
+		return Key{}, fmt.Errorf("%w: scheme must be otpauth", ErrURI)
+	}
+	typ := Type(strings.ToLower(u.Host))
+	if typ != TypeTOTP && typ != TypeHOTP {
+		return Key{}, fmt.Errorf("%w: unsupported type %q", ErrURI, u.Host)
+	}
+
+	q := u.Query()
+	k := Key{
+		Type:      typ,
+		Label:     strings.TrimPrefix(u.Path, "/"),
+		Issuer:    q.Get("issuer"),
+		Algorithm: DefaultAlgorithm,
+		Digits:    DefaultDigits,
+		Period:    DefaultPeriod,
+	}
+
+	if k.Secret, err = DecodeSecret(q.Get("secret")); err != nil {
+		return Key{}, err
+	}
+	if v := q.Get("algorithm"); v != "" {
+		switch a := Algorithm(strings.ToUpper(v)); a {
+		case SHA1, SHA256, SHA512:
+			k.Algorithm = a
+		default:
+			return Key{}, fmt.Errorf("%w: unsupported algorithm %q", ErrURI, v)
+		}
+	}
+	if v := q.Get("digits"); v != "" {
+		if k.Digits, err = strconv.Atoi(v); err != nil || k.Digits < 6 || k.Digits > 8 {
+			return Key{}, fmt.Errorf("%w: digits must be 6, 7 or 8", ErrURI)
+		}
+	}
+	if v := q.Get("period"); v != "" {
+		seconds, err := strconv.Atoi(v)
+		if err != nil || seconds < 1 {
+			return Key{}, fmt.Errorf("%w: period must be a positive number of seconds", ErrURI)
+		}
+		k.Period = time.Duration(seconds) * time.Second
+	}
+	if typ == TypeHOTP {
+		if k.Counter, err = strconv.ParseUint(q.Get("counter"), 10, 64); err != nil {
+			return Key{}, fmt.Errorf("%w: hotp requires a counter", ErrURI)
+		}
+	}
+	return k, nil
+}
+
+// DecodeSecret decodes a base32 secret. Case, spaces and padding are
+// ignored, as secrets are often typed in by hand.
+func DecodeSecret(s string) ([]byte, error) {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	s = strings.TrimRight(s, "=")
+	if s == "" {
+		return nil, fmt.Errorf("%w: missing secret", ErrURI)
+	}
+	secret, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w: secret is not valid base32", ErrURI)
+	}
+	return secret, nil
+}
+
+// URI returns the otpauth URI of the key. Parameters with default values
+// are left out.
+func (k Key) URI() string {
+	q := url.Values{}
+	q.Set("secret", base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(k.Secret))
+	if k.Issuer != "" {
+		q.Set("issuer", k.Issuer)
+	}
+	if k.Algorithm != "" && k.Algorithm != DefaultAlgorithm {
+		q.Set("algorithm", string(k.Algorithm))
+	}
+	if k.Digits != 0 && k.Digits != DefaultDigits {
+		q.Set("digits", strconv.Itoa(k.Digits))
+	}
+	typ := k.Type
+	if typ == TypeHOTP {
+		q.Set("counter", strconv.FormatUint(k.Counter, 10))
+	} else {
+		typ = TypeTOTP
+		if k.Period != 0 && k.Period != DefaultPeriod {
+			q.Set("period", strconv.Itoa(int(k.Period/time.Second)))
+		}
+	}
+
+	u := url.URL{Scheme: "otpauth", Host: string(typ), Path: "/" + k.Label, RawQuery: q.Encode()}
+	return u.String()
+}
+
+// Code returns the TOTP code valid at t and how long it remains valid.
+// HOTP keys use Next instead.
+func (k Key) Code(t time.Time) (string, time.Duration) {
+	period := int64(k.Period / time.Second)
+	if period <= 0 {
+		period = int64(DefaultPeriod / time.Second)
+	}
+	unix := t.Unix()
+	counter := uint64(unix / period)
+	remaining := time.Duration(period-unix%period) * time.Second
+	return HOTP(k.Secret, counter, k.Digits, k.Algorithm), remaining
+}
+
+// Next returns the HOTP code for the current counter and advances the
+// counter. The updated key must be saved before the code is used, so that
+// the same code is never handed out twice.
+func (k *Key) Next() string {
+	code := HOTP(k.Secret, k.Counter, k.Digits, k.Algorithm)
+	k.Counter++
+	return code
+}
+
+// Resync looks for code among the next window HOTP codes, starting at the
+// current counter. If it is found, the counter moves past it, skipping
+// the codes the token generated without the server seeing them, and
+// Resync returns how many codes were skipped.
+func (k *Key) Resync(code string, window int) (int, bool) {
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	for i := range window {
+		c := HOTP(k.Secret, k.Counter+uint64(i), k.Digits, k.Algorithm)
+		if hmac.Equal([]byte(c), []byte(code)) {
+			k.Counter += uint64(i) + 1
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// HOTP returns the RFC 4226 code of secret for counter, with the given
+// number of digits and hash function.
+func HOTP(secret []byte, counter uint64, digits int, alg Algorithm) string {
+	if digits <= 0 {
+		digits = DefaultDigits
+	}
+
+	mac := hmac.New(alg.hash(), secret)
+	binary.Write(mac, binary.BigEndian, counter)
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation: the low nibble of the last byte selects four
+	// bytes, read as a 31-bit number.
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:]) & 0x7fffffff
+
+	mod := uint64(1)
+	for range digits {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", digits, uint64(value)%mod)
+}
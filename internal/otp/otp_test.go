@@ -0,0 +1,185 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package otp_test contains unit tests for the otp package.
+// These tests check the codes against the test vectors of RFC 4226 and
+// RFC 6238, the parsing of otpauth URIs and HOTP counter handling.
+package otp_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/otp"
+)
+
+// TestHOTP verifies the test vectors of RFC 4226, Appendix D.
+func TestHOTP(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	want := []string{
+		"755224", "287082", "359152", "969429", "338314",
+		"254676", "287922", "162583", "399871", "520489",
+	}
+
+	for counter, code := range want {
+		if got := otp.HOTP(secret, uint64(counter), 6, otp.SHA1); got != code {
+			t.Errorf("HOTP(counter %d) = %s; want %s", counter, got, code)
+		}
+	}
+}
+
+// TestTOTP verifies the test vectors of RFC 6238, Appendix B.
+func TestTOTP(t *testing.T) {
+	// The RFC uses a seed of the hash size for each algorithm.
+	secrets := map[otp.Algorithm][]byte{
+		otp.SHA1:   []byte("12345678901234567890"),
+		otp.SHA256: []byte("12345678901234567890123456789012"),
+		otp.SHA512: []byte("1234567890123456789012345678901234567890123456789012345678901234"),
+	}
+	tests := []struct {
+		unix int64
+		alg  otp.Algorithm
+		want string
+	}{
+		{59, otp.SHA1, "94287082"},
+		{59, otp.SHA256, "46119246"},
+		{59, otp.SHA512, "90693936"},
+		{1111111109, otp.SHA1, "07081804"},
+		{1111111109, otp.SHA256, "68084774"},
+		{1111111109, otp.SHA512, "25091201"},
+		{1111111111, otp.SHA1, "14050471"},
+		{1111111111, otp.SHA256, "67062674"},
+		{1111111111, otp.SHA512, "99943326"},
+		{1234567890, otp.SHA1, "89005924"},
+		{1234567890, otp.SHA256, "91819424"},
+		{1234567890, otp.SHA512, "93441116"},
+		{2000000000, otp.SHA1, "69279037"},
+		{2000000000, otp.SHA256, "90698825"},
+		{2000000000, otp.SHA512, "38618901"},
+		{20000000000, otp.SHA1, "65353130"},
+		{20000000000, otp.SHA256, "77737706"},
+		{20000000000, otp.SHA512, "47863826"},
+	}
+
+	for _, tt := range tests {
+		k := otp.Key{Secret: secrets[tt.alg], Algorithm: tt.alg, Digits: 8, Period: 30 * time.Second}
+		got, _ := k.Code(time.Unix(tt.unix, 0))
+		if got != tt.want {
+			t.Errorf("%s code at %d = %s; want %s", tt.alg, tt.unix, got, tt.want)
+		}
+	}
+}
+
+// TestCodeRemaining verifies how long a code remains valid.
+func TestCodeRemaining(t *testing.T) {
+	k := otp.Key{Secret: []byte("12345678901234567890"), Digits: 6, Period: 30 * time.Second}
+
+	tests := []struct {
+		unix int64
+		want time.Duration
+	}{
+		{0, 30 * time.Second},
+		{59, time.Second},
+		{61, 29 * time.Second},
+	}
+	for _, tt := range tests {
+		if _, got := k.Code(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("Code(%d) remaining = %v; want %v", tt.unix, got, tt.want)
+		}
+	}
+}
+
+// TestParse verifies that otpauth URIs are parsed with their defaults and
+// survive a round trip through URI.
+func TestParse(t *testing.T) {
+	k, err := otp.Parse("otpauth://totp/Example:alice@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Example")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+
+	// Missing parameters take their defaults.
+	if k.Label != "Example:alice@example.com" || k.Issuer != "Example" || string(k.Secret) != "Hello!\xde\xad\xbe\xef" {
+		t.Errorf("Parse() = %+v; want the label, issuer and secret of the URI", k)
+	}
+	if k.Algorithm != otp.SHA1 || k.Digits != 6 || k.Period != 30*time.Second {
+		t.Errorf("Parse() = %+v; want SHA1, 6 digits, 30s", k)
+	}
+
+	// Explicit parameters are kept, also through URI.
+	k, err = otp.Parse("otpauth://totp/GitHub:bob?secret=jbsw%20y3dp&algorithm=sha256&digits=8&period=60")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	again, err := otp.Parse(k.URI())
+	if err != nil {
+		t.Fatalf("Parse(URI()) failed: %v", err)
+	}
+	if again.Algorithm != otp.SHA256 || again.Digits != 8 || again.Period != time.Minute ||
+		again.Label != "GitHub:bob" || string(again.Secret) != string(k.Secret) {
+		t.Errorf("Parse(URI()) = %+v; want %+v", again, k)
+	}
+}
+
+// TestParseInvalid verifies that unusable URIs are rejected.
+func TestParseInvalid(t *testing.T) {
+	for _, uri := range []string{
+		"https://example.com/?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://totp/x",
+		"otpauth://totp/x?secret=not-base32!",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&digits=4",
+		"otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=0",
+		"otpauth://other/x?secret=JBSWY3DPEHPK3PXP",
+		"otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP",
+	} {
+		if _, err := otp.Parse(uri); !errors.Is(err, otp.ErrURI) {
+			t.Errorf("Parse(%q) error = %v; want ErrURI", uri, err)
+		}
+	}
+}
+
+// TestNext verifies that HOTP keys hand out the RFC 4226 codes in order.
+func TestNext(t *testing.T) {
+	k, err := otp.Parse("otpauth://hotp/vpn?secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ&counter=3")
+	if err != nil {
+		t.Fatalf("Parse() failed: %v", err)
+	}
+	if k.Type != otp.TypeHOTP || k.Counter != 3 {
+		t.Fatalf("Parse() = %+v; want a HOTP key at counter 3", k)
+	}
+
+	for _, want := range []string{"969429", "338314", "254676"} {
+		if got := k.Next(); got != want {
+			t.Errorf("Next() = %s; want %s", got, want)
+		}
+	}
+
+	// The counter survives a round trip through URI.
+	again, err := otp.Parse(k.URI())
+	if err != nil || again.Type != otp.TypeHOTP || again.Counter != 6 {
+		t.Fatalf("Parse(URI()) = %+v, %v; want a HOTP key at counter 6", again, err)
+	}
+}
+
+// TestResync verifies that Resync finds a code ahead of the counter and
+// moves the counter past it, but only within the window.
+func TestResync(t *testing.T) {
+	k := otp.Key{Type: otp.TypeHOTP, Secret: []byte("12345678901234567890"), Digits: 6}
+
+	// The token is 7 codes ahead: counter 7 gives 162583.
+	skipped, ok := k.Resync("162583", 10)
+	if !ok || skipped != 7 || k.Counter != 8 {
+		t.Fatalf("Resync() = %d, %v, counter %d; want 7, true, counter 8", skipped, ok, k.Counter)
+	}
+	if got := k.Next(); got != "399871" {
+		t.Errorf("Next() after Resync() = %s; want 399871", got)
+	}
+
+	// A code beyond the window is not found and leaves the counter alone.
+	k.Counter = 0
+	if _, ok := k.Resync("520489", 5); ok || k.Counter != 0 {
+		t.Errorf("Resync() beyond the window = %v, counter %d; want false, counter 0", ok, k.Counter)
+	}
+}
@@ -0,0 +1,191 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package profile manages named profiles. Each profile has its own vault,
+// kept in its own directory below the profiles directory of util.DataDir,
+// so personal, team and production credentials stay physically separate.
+// The configuration file may point a profile at a vault elsewhere.
+package profile
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+	"github.com/nullzeiger/pwdcli/internal/util"
+)
+
+// Default is the name of the implicit profile whose vault is resolved
+// by util.VaultPath.
+const Default = "default"
+
+// Profile describes a named profile and the vault it uses.
+type Profile struct {
+	// Name identifies the profile on the command line.
+	Name string
+
+	// Vault is the path of the profile's vault file.
+	Vault string
+}
+
+var (
+	// ErrExists is returned when creating or renaming onto a profile
+	// that already exists.
+	ErrExists = errors.New("profile already exists")
+
+	// ErrNotFound is returned for a profile that does not exist.
+	ErrNotFound = errors.New("profile does not exist")
+
+	// ErrConfigured is returned when renaming or removing a profile whose
+	// vault location comes from the configuration file.
+	ErrConfigured = errors.New("profile vault is set in the configuration file")
+)
+
+// validName restricts names to characters that are safe in file names.
+var validName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// ValidName returns an error unless name can be used as a profile name.
+func ValidName(name string) error {
+	if !validName.MatchString(name) || name == Default {
+		return fmt.Errorf("invalid profile name %q", name)
+	}
+	return nil
+}
+
+// Dir returns the directory that holds one subdirectory per profile.
+func Dir() (string, error) {
+	dir, err := util.DataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "profiles"), nil
+}
+
+// VaultPath returns the path of the vault of profile name: the vault set
+// for it in cfg, or the vault file in the profile's own directory.
+func VaultPath(name string, cfg config.Config) (string, error) {
+	if err := ValidName(name); err != nil {
+		return "", err
+	}
+	if p, ok := cfg.Profiles[name]; ok && p.Vault != "" {
+		return util.ExpandHome(p.Vault)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, name, util.VaultFilename), nil
+}
+
+// Exists reports whether profile name has a vault or is declared in cfg.
+func Exists(name string, cfg config.Config) bool {
+	if _, ok := cfg.Profiles[name]; ok {
+		return true
+	}
+	path, err := VaultPath(name, cfg)
+	return err == nil && util.FileExists(path)
+}
+
+// List returns the profiles found in the profiles directory together with
+// those declared in cfg, sorted by name.
+func List(cfg config.Config) ([]Profile, error) {
+	names := []string{}
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, e := range entries {
+		if e.IsDir() && ValidName(e.Name()) == nil {
+			names = append(names, e.Name())
+		}
+	}
+
+	slices.Sort(names)
+	names = slices.Compact(names)
+
+	profiles := make([]Profile, 0, len(names))
+	for _, name := range names {
+		path, err := VaultPath(name, cfg)
+		if err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, Profile{Name: name, Vault: path})
+	}
+	return profiles, nil
+}
+
+// Create makes the directory for a new profile and returns the path its
+// vault should be created at.
+func Create(name string, cfg config.Config) (string, error) {
+	if err := ValidName(name); err != nil {
+		return "", err
+	}
+	if Exists(name, cfg) {
+		return "", ErrExists
+	}
+
+	path, err := VaultPath(name, cfg)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), util.DirPerm); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// Rename renames profile oldName to newName by renaming its directory.
+func Rename(oldName, newName string, cfg config.Config) error {
+	if err := ValidName(newName); err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[oldName]; ok {
+		return ErrConfigured
+	}
+	if !Exists(oldName, cfg) {
+		return ErrNotFound
+	}
+	if Exists(newName, cfg) {
+		return ErrExists
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	return os.Rename(filepath.Join(dir, oldName), filepath.Join(dir, newName))
+}
+
+// Remove deletes profile name together with its vault and any backups
+// kept in its directory.
+func Remove(name string, cfg config.Config) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	if _, ok := cfg.Profiles[name]; ok {
+		return ErrConfigured
+	}
+	if !Exists(name, cfg) {
+		return ErrNotFound
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(dir, name))
+}
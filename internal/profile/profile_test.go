@@ -0,0 +1,123 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package profile_test contains unit tests for the profile package.
+// These tests point XDG_DATA_HOME at a temporary directory so that
+// profiles are created without affecting the user's real data.
+package profile_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/config"
+	"github.com/nullzeiger/pwdcli/internal/profile"
+	"github.com/nullzeiger/pwdcli/internal/util"
+)
+
+// setupDataHome points XDG_DATA_HOME at a temporary directory.
+func setupDataHome(t *testing.T) {
+	t.Helper()
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+}
+
+// createProfile creates profile name and an empty vault file for it.
+func createProfile(t *testing.T, name string, cfg config.Config) string {
+	t.Helper()
+	path, err := profile.Create(name, cfg)
+	if err != nil {
+		t.Fatalf("Create(%s) failed: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte("vault"), 0o600); err != nil {
+		t.Fatalf("Failed to write vault: %v", err)
+	}
+	return path
+}
+
+// TestCreateAndList verifies that created profiles are listed by name with
+// their own vault paths, together with profiles declared in the config.
+func TestCreateAndList(t *testing.T) {
+	setupDataHome(t)
+	cfg := config.Config{Profiles: map[string]config.Profile{
+		"prod": {Vault: "/srv/prod/vault"},
+	}}
+
+	work := createProfile(t, "work", cfg)
+	personal := createProfile(t, "personal", cfg)
+	if work == personal || filepath.Base(work) != util.VaultFilename {
+		t.Fatalf("Unexpected vault paths %s and %s", work, personal)
+	}
+
+	// Creating the same profile twice fails
+	if _, err := profile.Create("work", cfg); !errors.Is(err, profile.ErrExists) {
+		t.Fatalf("Create(work) again = %v; want ErrExists", err)
+	}
+
+	profiles, err := profile.List(cfg)
+	if err != nil {
+		t.Fatalf("List() failed: %v", err)
+	}
+	want := []profile.Profile{
+		{Name: "personal", Vault: personal},
+		{Name: "prod", Vault: "/srv/prod/vault"},
+		{Name: "work", Vault: work},
+	}
+	if len(profiles) != len(want) {
+		t.Fatalf("List() = %v; want %v", profiles, want)
+	}
+	for i := range want {
+		if profiles[i] != want[i] {
+			t.Fatalf("List()[%d] = %v; want %v", i, profiles[i], want[i])
+		}
+	}
+}
+
+// TestRenameAndRemove verifies that renaming moves the vault and that
+// removing deletes it, while configured profiles are left alone.
+func TestRenameAndRemove(t *testing.T) {
+	setupDataHome(t)
+	cfg := config.Config{Profiles: map[string]config.Profile{
+		"prod": {Vault: "/srv/prod/vault"},
+	}}
+	createProfile(t, "work", cfg)
+
+	if err := profile.Rename("work", "team", cfg); err != nil {
+		t.Fatalf("Rename() failed: %v", err)
+	}
+	if profile.Exists("work", cfg) || !profile.Exists("team", cfg) {
+		t.Fatalf("After Rename(), work exists or team does not")
+	}
+
+	if err := profile.Remove("team", cfg); err != nil {
+		t.Fatalf("Remove() failed: %v", err)
+	}
+	if profile.Exists("team", cfg) {
+		t.Fatalf("After Remove(), team still exists")
+	}
+
+	// Configured profiles and missing ones cannot be changed
+	if err := profile.Remove("prod", cfg); !errors.Is(err, profile.ErrConfigured) {
+		t.Fatalf("Remove(prod) = %v; want ErrConfigured", err)
+	}
+	if err := profile.Rename("nope", "other", cfg); !errors.Is(err, profile.ErrNotFound) {
+		t.Fatalf("Rename(nope) = %v; want ErrNotFound", err)
+	}
+}
+
+// TestValidName verifies that names which could escape the profiles
+// directory, or clash with the default profile, are rejected.
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"work", "team-2", "a.b_c"} {
+		if err := profile.ValidName(name); err != nil {
+			t.Fatalf("ValidName(%q) = %v; want nil", name, err)
+		}
+	}
+	for _, name := range []string{"", "..", ".hidden", "a/b", profile.Default} {
+		if err := profile.ValidName(name); err == nil {
+			t.Fatalf("ValidName(%q) = nil; want error", name)
+		}
+	}
+}
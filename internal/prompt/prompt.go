@@ -0,0 +1,124 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prompt asks the user for secrets and confirmations. Secrets
+// typed at a terminal are not echoed, so they stay out of the shell
+// history, the process list and the scrollback. When the input is not a
+// terminal, one line is read per question instead, so that scripts can
+// pipe the answers in.
+package prompt
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+var (
+	// ErrNoInput is returned when the input ends before an answer.
+	ErrNoInput = errors.New("no input")
+
+	// ErrMismatch is returned when a secret and its confirmation differ.
+	ErrMismatch = errors.New("entries do not match")
+)
+
+// Prompter asks questions on an output and reads the answers from an
+// input.
+type Prompter struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	// fd is the file descriptor of the input if it is a terminal, or -1.
+	fd int
+}
+
+// New returns a Prompter reading from in and writing the questions to
+// out. Answers are read without echo if in is a terminal.
+func New(in io.Reader, out io.Writer) *Prompter {
+	p := &Prompter{in: bufio.NewReader(in), out: out, fd: -1}
+	if f, ok := in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		p.fd = int(f.Fd())
+	}
+	return p
+}
+
+// Default reads from standard input and asks on standard error, so that
+// standard output only holds the results of a command.
+var Default = New(os.Stdin, os.Stderr)
+
+// IsTerminal reports whether the answers are typed at a terminal.
+func (p *Prompter) IsTerminal() bool {
+	return p.fd >= 0
+}
+
+// Password asks for a secret and reads it without echo.
+func (p *Prompter) Password(question string) ([]byte, error) {
+	fmt.Fprint(p.out, question)
+
+	if p.IsTerminal() {
+		pwd, err := term.ReadPassword(p.fd)
+		fmt.Fprintln(p.out)
+		return pwd, err
+	}
+
+	line, err := p.line()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(line), nil
+}
+
+// NewPassword asks for a new secret twice, with question and then
+// confirm, and returns it if both answers match.
+func (p *Prompter) NewPassword(question, confirm string) ([]byte, error) {
+	pwd, err := p.Password(question)
+	if err != nil {
+		return nil, err
+	}
+	again, err := p.Password(confirm)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(pwd, again) {
+		return nil, ErrMismatch
+	}
+	return pwd, nil
+}
+
+// Line asks a question and returns the answer, which is echoed.
+func (p *Prompter) Line(question string) (string, error) {
+	fmt.Fprint(p.out, question)
+	return p.line()
+}
+
+// Confirm asks a yes or no question, which defaults to no.
+func (p *Prompter) Confirm(question string) (bool, error) {
+	fmt.Fprint(p.out, question+" [y/N] ")
+
+	answer, err := p.line()
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// line reads one line of input without its line ending. A last line
+// without a line ending counts; an input that has ended does not.
+func (p *Prompter) line() (string, error) {
+	line, err := p.in.ReadString('\n')
+	if err != nil && line == "" {
+		if errors.Is(err, io.EOF) {
+			return "", ErrNoInput
+		}
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
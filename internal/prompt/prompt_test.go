@@ -0,0 +1,87 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package prompt_test contains unit tests for the prompt package.
+// These tests pipe the answers in, as scripts do.
+package prompt_test
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/prompt"
+)
+
+// TestPassword verifies that consecutive questions read consecutive
+// lines and that the questions go to the output.
+func TestPassword(t *testing.T) {
+	var out bytes.Buffer
+	p := prompt.New(strings.NewReader("first\r\nsecond"), &out)
+
+	if p.IsTerminal() {
+		t.Fatalf("IsTerminal() = true for a piped input")
+	}
+	for _, want := range []string{"first", "second"} {
+		got, err := p.Password("Password: ")
+		if err != nil {
+			t.Fatalf("Password() failed: %v", err)
+		}
+		if string(got) != want {
+			t.Errorf("Password() = %q; want %q", got, want)
+		}
+	}
+	if out.String() != "Password: Password: " {
+		t.Errorf("output = %q; want both questions", out.String())
+	}
+
+	// Once the input has ended there is no answer.
+	if _, err := p.Password("Password: "); !errors.Is(err, prompt.ErrNoInput) {
+		t.Errorf("Password() at the end of input error = %v; want ErrNoInput", err)
+	}
+}
+
+// TestNewPassword verifies that a new secret must be confirmed.
+func TestNewPassword(t *testing.T) {
+	p := prompt.New(strings.NewReader("s3cret\ns3cret\ns3cret\ntypo\n"), &bytes.Buffer{})
+
+	pwd, err := p.NewPassword("New: ", "Confirm: ")
+	if err != nil || string(pwd) != "s3cret" {
+		t.Fatalf("NewPassword() = %q, %v; want s3cret", pwd, err)
+	}
+	if _, err := p.NewPassword("New: ", "Confirm: "); !errors.Is(err, prompt.ErrMismatch) {
+		t.Errorf("NewPassword() with a typo error = %v; want ErrMismatch", err)
+	}
+}
+
+// TestLine verifies that answers are returned as typed, without their
+// line ending.
+func TestLine(t *testing.T) {
+	p := prompt.New(strings.NewReader(" work \n"), &bytes.Buffer{})
+
+	got, err := p.Line("Profile: ")
+	if err != nil || got != " work " {
+		t.Fatalf("Line() = %q, %v; want %q", got, err, " work ")
+	}
+}
+
+// TestConfirm verifies the answers to yes or no questions.
+func TestConfirm(t *testing.T) {
+	var out bytes.Buffer
+	p := prompt.New(strings.NewReader("y\nYes\n\nno\nmaybe\n"), &out)
+
+	for _, want := range []bool{true, true, false, false, false} {
+		got, err := p.Confirm("Continue?")
+		if err != nil {
+			t.Fatalf("Confirm() failed: %v", err)
+		}
+		if got != want {
+			t.Errorf("Confirm() = %v; want %v", got, want)
+		}
+	}
+	if !strings.HasPrefix(out.String(), "Continue? [y/N] ") {
+		t.Errorf("output = %q; want the question with its default", out.String())
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/nullzeiger/pwdcli/internal/util"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// StaleBackupsError is returned by ChangePassword when the master password
+// was changed but some backups of the vault could not be sealed with the
+// new one, because they do not open with the previous password either.
+// They are left as they are and still open with the password that sealed
+// them.
+type StaleBackupsError struct {
+	// Paths are the backups left under an older password.
+	Paths []string
+}
+
+func (e *StaleBackupsError) Error() string {
+	return fmt.Sprintf("%d backups are still sealed with an older master password: %s",
+		len(e.Paths), strings.Join(e.Paths, ", "))
+}
+
+// BackupName returns the name of the n-th most recent backup of the vault
+// at path kept by a FileStore with Backups set, such as "vault.bak.1".
+func BackupName(path string, n int) string {
+	return path + BackupSuffix + "." + strconv.Itoa(n)
+}
+
+// rotateBackups keeps the current contents of path as its most recent
+// backup before the file is replaced, shifting older backups by one and
+// dropping those beyond keep. Nothing happens when keep is zero or the
+// file does not exist yet.
+//
+// The backup is a hard link to the current file, which the following
+// rename leaves in place, so no vault data is copied. File systems
+// without hard links get a copy instead.
+func rotateBackups(path string, keep int) error {
+	if keep <= 0 || !util.FileExists(path) {
+		return nil
+	}
+
+	if err := os.Remove(BackupName(path, keep)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for n := keep - 1; n >= 1; n-- {
+		err := os.Rename(BackupName(path, n), BackupName(path, n+1))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	if err := os.Link(path, BackupName(path, 1)); err == nil {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(BackupName(path, 1), data); err != nil {
+		return err
+	}
+	return os.Chmod(BackupName(path, 1), info.Mode().Perm())
+}
+
+// backupPaths returns the backups kept next to the vault at path that
+// exist: the numbered ones kept by Save, then the ones kept by Migrate and
+// ConvertToSQLite. Numbered backups beyond the current retention, left
+// when it was lowered, are included.
+func backupPaths(path string) []string {
+	var paths []string
+	for n := 1; util.FileExists(BackupName(path, n)); n++ {
+		paths = append(paths, BackupName(path, n))
+	}
+	for _, p := range []string{path + BackupSuffix, path + ConvertBackupSuffix} {
+		if util.FileExists(p) {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
+
+// resealBackups seals the backups of the vault at path again with k, which
+// is derived from a new master password, so that oldPassword, which may
+// have leaked, opens none of them. Backups that do not open with
+// oldPassword are left as they are and reported in a *StaleBackupsError.
+// Each backup is replaced atomically; it is a hard link to an earlier
+// vault file, which the rename leaves untouched.
+func resealBackups(path string, oldPassword []byte, k *vault.Key) error {
+	var stale []string
+	for _, p := range backupPaths(path) {
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		plaintext, _, err := vault.Open(oldPassword, data)
+		if err != nil {
+			stale = append(stale, p)
+			continue
+		}
+		sealed, err := k.Seal(plaintext)
+		if err != nil {
+			return err
+		}
+		if err := writeAtomic(p, sealed); err != nil {
+			return err
+		}
+	}
+	if len(stale) > 0 {
+		return &StaleBackupsError{Paths: stale}
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+// ReplaceFile exposes replaceFile to the external tests so that write
+// failures can be injected.
+var ReplaceFile = replaceFile
@@ -0,0 +1,485 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/util"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// BackupSuffix is appended to the storage file path to name the encrypted
+// backup kept by Migrate.
+const BackupSuffix = ".bak"
+
+// FileStore is a Store backed by a single encrypted file. The accounts are
+// encoded as JSON and sealed with a key derived from the master password
+// set by Unlock.
+type FileStore struct {
+	// Path is the location of the vault file.
+	Path string
+
+	// Params are the key derivation parameters used when a new vault
+	// is created.
+	Params vault.Params
+
+	// LockTimeout is how long Lock waits for another process to release
+	// the vault before giving up.
+	LockTimeout time.Duration
+
+	// Backups is how many previous versions of the vault Save keeps next
+	// to it, named by BackupName. Zero keeps none. Changing the password
+	// or the KDF parameters never creates backups, since they would
+	// still open with the old password.
+	Backups int
+
+	// password is the master password set by Unlock.
+	password []byte
+
+	// key caches the key derived from password for the current vault,
+	// so that a Load followed by a Save derives it only once.
+	key *vault.Key
+}
+
+// NewFileStore returns a FileStore for the vault at path using the default
+// KDF parameters and lock timeout. Unlock must be called before the vault
+// can be read or written.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{
+		Path:        path,
+		Params:      vault.DefaultParams,
+		LockTimeout: DefaultLockTimeout,
+	}
+}
+
+// Unlock sets the master password used to open and seal the vault.
+// The password is only verified when the vault is first read.
+func (s *FileStore) Unlock(pwd []byte) {
+	s.password = pwd
+	s.key = nil
+}
+
+// Create initializes the storage file if it does not already exist.
+// It ensures that the file exists and contains an encrypted empty
+// JSON array ([]). If the file already exists, the method does nothing.
+func (s *FileStore) Create() error {
+	// If the storage file already exists, nothing needs to be done.
+	if util.FileExists(s.Path) {
+		return nil
+	}
+
+	if len(s.password) == 0 {
+		return ErrNoPassword
+	}
+
+	// Make sure the vault directory exists.
+	if err := os.MkdirAll(filepath.Dir(s.Path), util.DirPerm); err != nil {
+		return err
+	}
+
+	// Derive a key for the new vault.
+	k, err := vault.NewKey(s.password, s.Params)
+	if err != nil {
+		return err
+	}
+
+	// Initialize the file with an encrypted empty JSON array.
+	data, err := k.Seal([]byte("[]"))
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(s.Path, data); err != nil {
+		return err
+	}
+
+	s.key = k
+	return nil
+}
+
+// Exists reports whether the storage file has already been created.
+func (s *FileStore) Exists() bool {
+	return util.FileExists(s.Path)
+}
+
+// IsLegacy reports whether the storage file is a plaintext JSON array as
+// written by versions of pwdcli that did not encrypt the vault.
+// It returns false if the file does not exist.
+func (s *FileStore) IsLegacy() (bool, error) {
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return isLegacy(data), nil
+}
+
+// isLegacy reports whether data looks like a bare JSON array.
+func isLegacy(data []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(data), []byte("["))
+}
+
+// Migrate encrypts a legacy plaintext storage file with the master password
+// set by Unlock. The encrypted vault replaces the original atomically and
+// the plaintext contents are then overwritten with zeros, so no readable
+// copy is left behind. If backup is true, an additional encrypted copy is
+// kept next to the vault with the BackupSuffix extension.
+func (s *FileStore) Migrate(backup bool) error {
+	if len(s.password) == 0 {
+		return ErrNoPassword
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	if !isLegacy(data) {
+		return errors.New("storage file is not a plaintext vault")
+	}
+
+	// Refuse to migrate anything that is not a valid account list, the
+	// original bytes are sealed unchanged so no field is ever lost.
+	var accounts []account.Account
+	if err := json.Unmarshal(data, &accounts); err != nil {
+		return err
+	}
+
+	k, err := vault.NewKey(s.password, s.Params)
+	if err != nil {
+		return err
+	}
+	sealed, err := k.Seal(data)
+	if err != nil {
+		return err
+	}
+
+	if backup {
+		bak, err := k.Seal(data)
+		if err != nil {
+			return err
+		}
+		if err := writeAtomic(s.Path+BackupSuffix, bak); err != nil {
+			return err
+		}
+	}
+
+	// Keep a handle on the plaintext so it can be wiped once the
+	// encrypted vault has taken its place.
+	plain, err := os.OpenFile(s.Path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer plain.Close()
+
+	if err := writeAtomic(s.Path, sealed); err != nil {
+		return err
+	}
+	s.key = k
+
+	return shred(plain, len(data))
+}
+
+// ChangePassword re-encrypts the whole vault with a key derived from
+// newPassword, keeping the current KDF parameters. The vault must open with
+// the password set by Unlock. On success newPassword becomes the master
+// password for subsequent operations. The backups kept next to the vault
+// are sealed with newPassword as well, so that the old password opens
+// none of them; those it does not open are reported in a
+// *StaleBackupsError, returned after the password was changed.
+func (s *FileStore) ChangePassword(newPassword []byte) error {
+	k, err := s.currentKey()
+	if err != nil {
+		return err
+	}
+	return s.reseal(newPassword, k.Params())
+}
+
+// Rekey re-encrypts the whole vault with the current master password and
+// the given KDF parameters, typically to raise the Argon2 cost.
+func (s *FileStore) Rekey(params vault.Params) error {
+	return s.reseal(s.password, params)
+}
+
+// reseal decrypts the vault and seals the same contents again under a new
+// key derived from pwd and params. The new file replaces the old one with
+// an atomic rename, so an interruption leaves either the old or the new
+// vault in place and both open with their respective password.
+func (s *FileStore) reseal(pwd []byte, params vault.Params) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return err
+	}
+	plaintext, err := s.open(data)
+	if err != nil {
+		return err
+	}
+
+	k, err := vault.NewKey(pwd, params)
+	if err != nil {
+		return err
+	}
+	sealed, err := k.Seal(plaintext)
+	if err != nil {
+		return err
+	}
+	if err := writeAtomic(s.Path, sealed); err != nil {
+		return err
+	}
+
+	old := s.password
+	s.password = pwd
+	s.key = k
+	if bytes.Equal(old, pwd) {
+		return nil
+	}
+	return resealBackups(s.Path, old, k)
+}
+
+// KDFParams returns the KDF parameters stored in the vault header.
+func (s *FileStore) KDFParams() (vault.Params, error) {
+	k, err := s.currentKey()
+	if err != nil {
+		return vault.Params{}, err
+	}
+	return k.Params(), nil
+}
+
+// Load reads all stored accounts from the vault into a slice.
+// Returns vault.ErrWrongPassword if the master password does not open
+// the vault, or an error if the file cannot be read or the JSON is malformed.
+func (s *FileStore) Load() ([]account.Account, error) {
+	// Read raw file contents.
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.open(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode JSON into a slice of Account structs.
+	var accounts []account.Account
+	err = json.Unmarshal(plaintext, &accounts)
+	return accounts, err
+}
+
+// Save replaces the entire storage file with the provided slice of accounts.
+// The new vault is written to a temporary file and renamed into place, so
+// a crash or a full disk never leaves a truncated vault behind. A new file
+// gets the permissions defined in util.Perm; an existing one keeps its own.
+// When Backups is set, the previous version is kept as a backup first.
+func (s *FileStore) Save(accounts []account.Account) error {
+	return s.write(accounts, true)
+}
+
+// Record replaces the storage file like Save without keeping a backup of
+// the previous version, so that writing access times does not push real
+// changes out of the retained backups.
+func (s *FileStore) Record(accounts []account.Account) error {
+	return s.write(accounts, false)
+}
+
+// write implements Save and Record, keeping a backup first if backup is
+// true and Backups is set.
+func (s *FileStore) write(accounts []account.Account, backup bool) error {
+	// Make sure the key matches the existing vault before overwriting it.
+	k, err := s.currentKey()
+	if err != nil {
+		return err
+	}
+
+	// Encode accounts as pretty-printed JSON.
+	jsonData, err := json.MarshalIndent(accounts, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	data, err := k.Seal(jsonData)
+	if err != nil {
+		return err
+	}
+
+	// Keep the previous version if asked to, then atomically replace
+	// the storage file with new data.
+	if backup {
+		if err := rotateBackups(s.Path, s.Backups); err != nil {
+			return err
+		}
+	}
+	return writeAtomic(s.Path, data)
+}
+
+// Append reads the existing accounts from storage, adds the new account,
+// and writes all accounts back to the file while holding the vault lock.
+// Returns an error if reading or writing fails.
+func (s *FileStore) Append(acc account.Account) error {
+	// Hold the lock for the whole read-modify-write sequence.
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	// Load existing data.
+	accounts, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	// Add the new entry.
+	accounts = append(accounts, acc)
+
+	// Save updated account list.
+	return s.Save(accounts)
+}
+
+// Lock takes an exclusive advisory lock on the vault. The lock lives on a
+// sidecar file next to the vault, because the vault itself is replaced by a
+// rename on every write. See lockFile.
+func (s *FileStore) Lock() (func() error, error) {
+	return lockFile(s.Path+LockSuffix, s.LockTimeout)
+}
+
+// open decrypts the raw vault contents with the master password and caches
+// the derived key for subsequent writes.
+func (s *FileStore) open(data []byte) ([]byte, error) {
+	if len(s.password) == 0 {
+		return nil, ErrNoPassword
+	}
+	if !vault.IsSealed(data) {
+		return nil, ErrNotEncrypted
+	}
+
+	plaintext, k, err := vault.Open(s.password, data)
+	if err != nil {
+		return nil, err
+	}
+	s.key = k
+	return plaintext, nil
+}
+
+// currentKey returns the key to seal the vault with. If the vault has not
+// been read yet, it is opened first so that a wrong master password can
+// never overwrite an existing vault.
+func (s *FileStore) currentKey() (*vault.Key, error) {
+	if s.key != nil {
+		return s.key, nil
+	}
+	if len(s.password) == 0 {
+		return nil, ErrNoPassword
+	}
+
+	data, err := os.ReadFile(s.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		k, err := vault.NewKey(s.password, s.Params)
+		if err != nil {
+			return nil, err
+		}
+		s.key = k
+		return k, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.open(data); err != nil {
+		return nil, err
+	}
+	return s.key, nil
+}
+
+// writeAtomic replaces the file at path with data. See replaceFile.
+func writeAtomic(path string, data []byte) error {
+	return replaceFile(path, func(w io.Writer) error {
+		_, err := w.Write(data)
+		return err
+	})
+}
+
+// replaceFile writes a temporary file in the same directory as path using
+// write, flushes it to disk and renames it over path, then flushes the
+// directory so the rename itself survives a crash. Readers therefore see
+// either the old or the new contents but never a partially written file,
+// and any failure leaves the previous file untouched.
+//
+// The mode and ownership of an existing file are preserved; a new file
+// is created with util.Perm.
+func replaceFile(path string, write func(io.Writer) error) error {
+	mode := os.FileMode(util.Perm)
+	old, err := os.Stat(path)
+	if err == nil {
+		mode = old.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	// Removing the temporary file is a no-op once it has been renamed.
+	defer os.Remove(tmp.Name())
+
+	if err := fillTemp(tmp, old, mode, write); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	return syncDir(dir)
+}
+
+// fillTemp writes the new contents to tmp, applies the mode and owner of
+// the file being replaced, and flushes everything to disk.
+func fillTemp(tmp *os.File, old os.FileInfo, mode os.FileMode, write func(io.Writer) error) error {
+	if err := write(tmp); err != nil {
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if old != nil {
+		if err := copyOwner(tmp, old); err != nil {
+			return err
+		}
+	}
+	return tmp.Sync()
+}
+
+// shred overwrites the first size bytes of f with zeros and flushes them
+// to disk. It is used to wipe plaintext that is no longer needed.
+func shred(f *os.File, size int) error {
+	if _, err := f.WriteAt(make([]byte, size), 0); err != nil {
+		return err
+	}
+	return f.Sync()
+}
@@ -0,0 +1,29 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build !unix && !windows
+
+package storage
+
+import "os"
+
+// copyOwner is a no-op on platforms without Unix file ownership.
+func copyOwner(f *os.File, old os.FileInfo) error {
+	return nil
+}
+
+// syncDir is a no-op on platforms where directories cannot be synced.
+func syncDir(dir string) error {
+	return nil
+}
+
+// tryLock always succeeds on platforms without file locking.
+func tryLock(f *os.File) (bool, error) {
+	return true, nil
+}
+
+// unlockFile is a no-op on platforms without file locking.
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LockSuffix is appended to the storage file path to name the sidecar
+// file used for inter-process locking.
+const LockSuffix = ".lock"
+
+// DefaultLockTimeout is how long a store waits by default for another
+// process to release the vault before giving up.
+const DefaultLockTimeout = 10 * time.Second
+
+// lockPoll is the interval between attempts to take a busy lock.
+const lockPoll = 50 * time.Millisecond
+
+// LockedError is returned by Lock when another process still holds the
+// vault lock after the lock timeout.
+type LockedError struct {
+	// PID is the process holding the lock, or 0 if it is unknown.
+	PID int
+}
+
+// Error implements the error interface.
+func (e *LockedError) Error() string {
+	if e.PID == 0 {
+		return "vault is locked by another process"
+	}
+	return fmt.Sprintf("vault is locked by PID %d", e.PID)
+}
+
+// lockFile takes an exclusive advisory lock on the file at path, creating
+// it if needed, so that a whole read-modify-write sequence runs without
+// interference from other pwdcli processes. It waits up to timeout for a
+// busy lock. The returned function releases the lock.
+func lockFile(path string, timeout time.Duration) (func() error, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	for {
+		ok, err := tryLock(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			pid := lockOwner(f)
+			f.Close()
+			return nil, &LockedError{PID: pid}
+		}
+		time.Sleep(lockPoll)
+	}
+
+	// Record our PID so that waiting processes can tell who holds the lock.
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())), 0)
+	}
+
+	return func() error {
+		defer f.Close()
+		return unlockFile(f)
+	}, nil
+}
+
+// lockOwner returns the PID recorded in the lock file, or 0 if it
+// cannot be read.
+func lockOwner(f *os.File) int {
+	buf := make([]byte, 32)
+	n, _ := f.ReadAt(buf, 0)
+	pid, err := strconv.Atoi(strings.TrimSpace(string(buf[:n])))
+	if err != nil {
+		return 0
+	}
+	return pid
+}
@@ -0,0 +1,63 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"slices"
+	"sync"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+)
+
+// MemStore is a Store that keeps accounts in memory. It is meant for tests
+// and for callers that need a scratch store; nothing is persisted.
+type MemStore struct {
+	// mu guards accounts.
+	mu       sync.Mutex
+	accounts []account.Account
+
+	// lock is the store lock handed out by Lock.
+	lock sync.Mutex
+}
+
+// NewMemStore returns a MemStore holding a copy of accounts.
+func NewMemStore(accounts ...account.Account) *MemStore {
+	return &MemStore{accounts: slices.Clone(accounts)}
+}
+
+// Load returns a copy of the stored accounts.
+func (s *MemStore) Load() ([]account.Account, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.accounts), nil
+}
+
+// Save replaces the stored accounts with a copy of accounts.
+func (s *MemStore) Save(accounts []account.Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = slices.Clone(accounts)
+	return nil
+}
+
+// Append adds acc after the existing accounts while holding the store lock.
+func (s *MemStore) Append(acc account.Account) error {
+	unlock, _ := s.Lock()
+	defer unlock()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.accounts = append(s.accounts, acc)
+	return nil
+}
+
+// Lock takes the store lock, blocking until it is available.
+func (s *MemStore) Lock() (func() error, error) {
+	s.lock.Lock()
+	return func() error {
+		s.lock.Unlock()
+		return nil
+	}, nil
+}
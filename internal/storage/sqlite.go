@@ -0,0 +1,629 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/util"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+
+	// Pure Go SQLite driver, registered as "sqlite".
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMagic is the header every SQLite 3 database file starts with.
+const sqliteMagic = "SQLite format 3\x00"
+
+// schema creates the tables of a SQLite vault. Every account is stored as
+// a sealed JSON document; website and username are additionally stored as
+// keyed tags so that exact lookups can use an index without revealing them.
+const schema = `
+CREATE TABLE IF NOT EXISTS meta (
+	name  TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS accounts (
+	id           INTEGER PRIMARY KEY,
+	position     INTEGER NOT NULL,
+	website_tag  BLOB NOT NULL,
+	username_tag BLOB NOT NULL,
+	data         BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS accounts_position ON accounts (position);
+CREATE INDEX IF NOT EXISTS accounts_lookup ON accounts (website_tag, username_tag);
+`
+
+// ConvertBackupSuffix is appended to the vault path to name the copy of
+// the JSON vault kept by ConvertToSQLite.
+const ConvertBackupSuffix = ".pre-sqlite" + BackupSuffix
+
+// checkValue is sealed into the meta table to verify the master password
+// and to carry the salt and KDF parameters of the vault.
+const checkValue = "pwdcli"
+
+// SQLStore is a Store backed by an embedded SQLite database. Unlike
+// FileStore it does not rewrite the whole vault on every change: accounts
+// are individual rows, each sealed with the vault key, so appends, updates
+// and deletes only touch the affected row.
+type SQLStore struct {
+	// Path is the location of the database file.
+	Path string
+
+	// Params are the key derivation parameters used when a new vault
+	// is created.
+	Params vault.Params
+
+	// LockTimeout is how long Lock waits for another process to release
+	// the vault before giving up.
+	LockTimeout time.Duration
+
+	password []byte
+	key      *vault.Key
+	db       *sql.DB
+}
+
+// NewSQLStore returns a SQLStore for the database at path using the default
+// KDF parameters and lock timeout. Unlock must be called before the vault
+// can be read or written.
+func NewSQLStore(path string) *SQLStore {
+	return &SQLStore{
+		Path:        path,
+		Params:      vault.DefaultParams,
+		LockTimeout: DefaultLockTimeout,
+	}
+}
+
+// IsSQLite reports whether the file at path is a SQLite database.
+func IsSQLite(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	buf := make([]byte, len(sqliteMagic))
+	if _, err := f.Read(buf); err != nil {
+		return false
+	}
+	return string(buf) == sqliteMagic
+}
+
+// Unlock sets the master password used to open and seal the vault.
+// The password is only verified when the vault is first accessed.
+func (s *SQLStore) Unlock(pwd []byte) {
+	s.password = pwd
+	s.key = nil
+}
+
+// Exists reports whether the database file has already been created.
+func (s *SQLStore) Exists() bool {
+	return util.FileExists(s.Path)
+}
+
+// Create initializes an empty database if the file does not already exist.
+func (s *SQLStore) Create() error {
+	if s.Exists() {
+		return nil
+	}
+	if len(s.password) == 0 {
+		return ErrNoPassword
+	}
+
+	k, err := vault.NewKey(s.password, s.Params)
+	if err != nil {
+		return err
+	}
+	check, err := k.Seal([]byte(checkValue))
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), util.DirPerm); err != nil {
+		return err
+	}
+	db, err := s.open()
+	if err != nil {
+		return err
+	}
+	if err := initSchema(db, s.Path, check); err != nil {
+		// Do not leave a vault without a check value behind: Exists
+		// would report it and every later unlock would fail.
+		s.Close()
+		os.Remove(s.Path)
+		return err
+	}
+
+	s.key = k
+	return nil
+}
+
+// initSchema restricts the new database at path to its owner and creates
+// the tables and the password check row in a single transaction.
+func initSchema(db *sql.DB, path string, check []byte) error {
+	if err := os.Chmod(path, util.Perm); err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(schema); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`INSERT INTO meta (name, value) VALUES ('check', ?)`, check); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Close releases the database handle. The store can be used again
+// afterwards; it is reopened on demand.
+func (s *SQLStore) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// Load returns all stored accounts in order.
+func (s *SQLStore) Load() ([]account.Account, error) {
+	db, k, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(`SELECT data FROM accounts ORDER BY position`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []account.Account{}
+	for rows.Next() {
+		acc, err := scanAccount(rows, k)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, acc)
+	}
+	return accounts, rows.Err()
+}
+
+// Save replaces all stored accounts with accounts in a single transaction.
+func (s *SQLStore) Save(accounts []account.Account) error {
+	db, k, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM accounts`); err != nil {
+		return err
+	}
+	for i, acc := range accounts {
+		if err := insertAccount(tx, k, i, acc); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Append inserts acc after the existing accounts. Only the new row is
+// written.
+func (s *SQLStore) Append(acc account.Account) error {
+	db, k, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var next int
+	if err := tx.QueryRow(`SELECT COALESCE(MAX(position) + 1, 0) FROM accounts`).Scan(&next); err != nil {
+		return err
+	}
+	if err := insertAccount(tx, k, next, acc); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update replaces the account at index without touching any other row.
+func (s *SQLStore) Update(index int, acc account.Account) error {
+	db, k, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	id, err := rowID(db, index)
+	if err != nil {
+		return err
+	}
+	website, username, data, err := sealAccount(k, acc)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`UPDATE accounts SET website_tag = ?, username_tag = ?, data = ? WHERE id = ?`,
+		website, username, data, id)
+	return err
+}
+
+// Delete removes the account at index without touching any other row.
+// Positions may be left with gaps; only their order matters.
+func (s *SQLStore) Delete(index int) error {
+	db, _, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+
+	id, err := rowID(db, index)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`DELETE FROM accounts WHERE id = ?`, id)
+	return err
+}
+
+// Find returns the accounts whose website, and username if not empty,
+// match exactly, ignoring case. The lookup uses the tag index instead of
+// decrypting every row.
+func (s *SQLStore) Find(website, username string) ([]Match, error) {
+	db, k, err := s.unlocked()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `SELECT position, data FROM accounts WHERE website_tag = ?`
+	args := []any{tag(k, website)}
+	if username != "" {
+		query += ` AND username_tag = ?`
+		args = append(args, tag(k, username))
+	}
+	query += ` ORDER BY position`
+
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []int
+	var accounts []account.Account
+	for rows.Next() {
+		var position int
+		var data []byte
+		if err := rows.Scan(&position, &data); err != nil {
+			return nil, err
+		}
+		acc, err := openAccount(k, data)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+		accounts = append(accounts, acc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	// Translate positions into indices; the position index makes each
+	// count cheap.
+	matches := make([]Match, len(accounts))
+	for i := range accounts {
+		var index int
+		err := db.QueryRow(`SELECT COUNT(*) FROM accounts WHERE position < ?`, positions[i]).Scan(&index)
+		if err != nil {
+			return nil, err
+		}
+		matches[i] = Match{Index: index, Account: accounts[i]}
+	}
+	return matches, nil
+}
+
+// Lock takes an exclusive advisory lock on the vault. It uses the same
+// sidecar lock file as FileStore, so a vault is locked consistently
+// whichever backend holds it.
+func (s *SQLStore) Lock() (func() error, error) {
+	return lockFile(s.Path+LockSuffix, s.LockTimeout)
+}
+
+// ChangePassword re-encrypts every row with a key derived from newPassword,
+// keeping the current KDF parameters. The file vault kept by
+// ConvertToSQLite and its backups are sealed with newPassword as well, as
+// FileStore.ChangePassword does.
+func (s *SQLStore) ChangePassword(newPassword []byte) error {
+	_, k, err := s.unlocked()
+	if err != nil {
+		return err
+	}
+	return s.reseal(newPassword, k.Params())
+}
+
+// Rekey re-encrypts every row with the current master password and the
+// given KDF parameters.
+func (s *SQLStore) Rekey(params vault.Params) error {
+	return s.reseal(s.password, params)
+}
+
+// KDFParams returns the KDF parameters the vault is sealed with.
+func (s *SQLStore) KDFParams() (vault.Params, error) {
+	_, k, err := s.unlocked()
+	if err != nil {
+		return vault.Params{}, err
+	}
+	return k.Params(), nil
+}
+
+// reseal re-encrypts the whole database under a new key inside a single
+// transaction, so an interruption leaves the old vault fully intact.
+func (s *SQLStore) reseal(pwd []byte, params vault.Params) error {
+	unlock, err := s.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accounts, err := s.Load()
+	if err != nil {
+		return err
+	}
+
+	k, err := vault.NewKey(pwd, params)
+	if err != nil {
+		return err
+	}
+	check, err := k.Seal([]byte(checkValue))
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE meta SET value = ? WHERE name = 'check'`, check); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM accounts`); err != nil {
+		return err
+	}
+	for i, acc := range accounts {
+		if err := insertAccount(tx, k, i, acc); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	old := s.password
+	s.password = pwd
+	s.key = k
+	if bytes.Equal(old, pwd) {
+		return nil
+	}
+	return resealBackups(s.Path, old, k)
+}
+
+// open returns the database handle, opening the file if needed.
+func (s *SQLStore) open() (*sql.DB, error) {
+	if s.db != nil {
+		return s.db, nil
+	}
+
+	// Wait for concurrent writers instead of failing with SQLITE_BUSY.
+	db, err := sql.Open("sqlite", s.Path+"?_pragma=busy_timeout(10000)")
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.db = db
+	return db, nil
+}
+
+// unlocked returns the database handle and the vault key, verifying the
+// master password against the check value on first use.
+func (s *SQLStore) unlocked() (*sql.DB, *vault.Key, error) {
+	if len(s.password) == 0 {
+		return nil, nil, ErrNoPassword
+	}
+	if !s.Exists() {
+		return nil, nil, os.ErrNotExist
+	}
+
+	db, err := s.open()
+	if err != nil {
+		return nil, nil, err
+	}
+	if s.key != nil {
+		return db, s.key, nil
+	}
+
+	var check []byte
+	if err := db.QueryRow(`SELECT value FROM meta WHERE name = 'check'`).Scan(&check); err != nil {
+		return nil, nil, err
+	}
+	plaintext, k, err := vault.Open(s.password, check)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !bytes.Equal(plaintext, []byte(checkValue)) {
+		return nil, nil, vault.ErrWrongPassword
+	}
+
+	s.key = k
+	return db, k, nil
+}
+
+// ConvertToSQLite converts the JSON vault of src into a SQLite vault at the
+// same path, sealed with the same master password and KDF parameters. The
+// database is built next to the vault and checked to hold exactly the same
+// accounts before it replaces the original, which is kept as an encrypted
+// backup with the ConvertBackupSuffix extension.
+func ConvertToSQLite(src *FileStore) error {
+	unlock, err := src.Lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	accounts, err := src.Load()
+	if err != nil {
+		return err
+	}
+	original, err := os.ReadFile(src.Path)
+	if err != nil {
+		return err
+	}
+
+	tmp := src.Path + ".sqlite.tmp"
+	os.Remove(tmp)
+	defer os.Remove(tmp)
+
+	dst := NewSQLStore(tmp)
+	dst.Params = src.key.Params()
+	dst.Unlock(src.password)
+	if err := dst.Create(); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Save(accounts); err != nil {
+		dst.Close()
+		return err
+	}
+
+	// Read everything back before touching the original.
+	converted, err := dst.Load()
+	dst.Close()
+	if err != nil {
+		return err
+	}
+	if err := sameAccounts(accounts, converted); err != nil {
+		return err
+	}
+
+	if err := writeAtomic(src.Path+ConvertBackupSuffix, original); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, src.Path); err != nil {
+		return err
+	}
+	return syncDir(filepath.Dir(src.Path))
+}
+
+// sameAccounts returns an error unless a and b encode to the same JSON.
+func sameAccounts(a, b []account.Account) error {
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(ja, jb) {
+		return errors.New("converted vault does not match the original")
+	}
+	return nil
+}
+
+// execer is the subset of *sql.DB and *sql.Tx used by insertAccount.
+type execer interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// insertAccount seals acc and inserts it at position.
+func insertAccount(db execer, k *vault.Key, position int, acc account.Account) error {
+	website, username, data, err := sealAccount(k, acc)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(`INSERT INTO accounts (position, website_tag, username_tag, data) VALUES (?, ?, ?, ?)`,
+		position, website, username, data)
+	return err
+}
+
+// sealAccount returns the lookup tags and the sealed JSON of acc.
+func sealAccount(k *vault.Key, acc account.Account) (website, username, data []byte, err error) {
+	plaintext, err := json.Marshal(acc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	data, err = k.Seal(plaintext)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return tag(k, acc.Website), tag(k, acc.Username), data, nil
+}
+
+// scanAccount decrypts the account in the current row.
+func scanAccount(rows *sql.Rows, k *vault.Key) (account.Account, error) {
+	var data []byte
+	if err := rows.Scan(&data); err != nil {
+		return account.Account{}, err
+	}
+	return openAccount(k, data)
+}
+
+// openAccount decrypts a sealed account.
+func openAccount(k *vault.Key, data []byte) (account.Account, error) {
+	var acc account.Account
+	plaintext, err := k.Open(data)
+	if err != nil {
+		return acc, err
+	}
+	err = json.Unmarshal(plaintext, &acc)
+	return acc, err
+}
+
+// tag returns the lookup tag of a website or username. Lookups ignore case.
+func tag(k *vault.Key, s string) []byte {
+	return k.Tag([]byte(strings.ToLower(s)))
+}
+
+// rowID returns the row id of the account at index.
+func rowID(db *sql.DB, index int) (int64, error) {
+	var id int64
+	err := db.QueryRow(`SELECT id FROM accounts ORDER BY position LIMIT 1 OFFSET ?`, index).Scan(&id)
+	if index < 0 || errors.Is(err, sql.ErrNoRows) {
+		return 0, ErrIndexOutOfRange
+	}
+	return id, err
+}
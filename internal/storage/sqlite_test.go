@@ -0,0 +1,212 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package storage_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/account"
+	"github.com/nullzeiger/pwdcli/internal/storage"
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// setupSQLStore creates an empty SQLite vault in a temporary directory,
+// unlocked with the test master password and cheap KDF parameters.
+func setupSQLStore(t *testing.T) *storage.SQLStore {
+	t.Helper()
+
+	s := storage.NewSQLStore(filepath.Join(t.TempDir(), "vault.db"))
+	s.Params = vault.Params{Time: 1, Memory: 64, Threads: 1}
+	s.Unlock([]byte(testPassword))
+	if err := s.Create(); err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+// TestSQLStoreAppendAndLoad verifies that appended accounts are returned
+// in order, that they are not stored in plaintext, and that Save replaces
+// them.
+func TestSQLStoreAppendAndLoad(t *testing.T) {
+	s := setupSQLStore(t)
+
+	for _, site := range []string{"a.com", "b.com", "c.com"} {
+		acc := account.Account{Website: site, Username: "u", Email: "e", Pwd: "s3cret-" + site}
+		if err := s.Append(acc); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+
+	accounts, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(accounts) != 3 || accounts[0].Website != "a.com" || accounts[2].Website != "c.com" {
+		t.Fatalf("Load() = %v; want a.com, b.com, c.com", accounts)
+	}
+
+	// The database file must not contain any field in the clear
+	data, _ := os.ReadFile(s.Path)
+	for _, secret := range []string{"a.com", "s3cret"} {
+		if strings.Contains(string(data), secret) {
+			t.Fatalf("Database contains %q in plaintext", secret)
+		}
+	}
+
+	if err := s.Save(accounts[:1]); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if accounts, _ = s.Load(); len(accounts) != 1 {
+		t.Fatalf("Load() after Save() returned %d accounts; want 1", len(accounts))
+	}
+}
+
+// TestSQLStoreCreatePrivate verifies that a new database is readable by
+// its owner only and already holds the password check.
+func TestSQLStoreCreatePrivate(t *testing.T) {
+	s := setupSQLStore(t)
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0o600 {
+		t.Errorf("Database mode = %o; want 600", perm)
+	}
+
+	s.Close()
+	other := storage.NewSQLStore(s.Path)
+	other.Unlock([]byte("wrong"))
+	defer other.Close()
+	if _, err := other.Load(); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Errorf("Load() with wrong password error = %v; want ErrWrongPassword", err)
+	}
+}
+
+// TestSQLStoreWrongPassword verifies that a wrong master password is
+// reported as vault.ErrWrongPassword.
+func TestSQLStoreWrongPassword(t *testing.T) {
+	s := setupSQLStore(t)
+	s.Close()
+
+	other := storage.NewSQLStore(s.Path)
+	other.Unlock([]byte("wrong"))
+	defer other.Close()
+
+	if _, err := other.Load(); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Load() error = %v; want ErrWrongPassword", err)
+	}
+}
+
+// TestSQLStoreUpdateDelete verifies row-level updates and deletes and that
+// indices stay contiguous after a delete.
+func TestSQLStoreUpdateDelete(t *testing.T) {
+	s := setupSQLStore(t)
+	for _, site := range []string{"a.com", "b.com", "c.com"} {
+		s.Append(account.Account{Website: site})
+	}
+
+	if err := s.Delete(1); err != nil {
+		t.Fatalf("Delete(1) failed: %v", err)
+	}
+	if err := s.Update(1, account.Account{Website: "d.com"}); err != nil {
+		t.Fatalf("Update(1) failed: %v", err)
+	}
+
+	accounts, _ := s.Load()
+	if len(accounts) != 2 || accounts[0].Website != "a.com" || accounts[1].Website != "d.com" {
+		t.Fatalf("Load() = %v; want a.com, d.com", accounts)
+	}
+
+	// Out-of-range indices are rejected
+	if err := s.Delete(5); !errors.Is(err, storage.ErrIndexOutOfRange) {
+		t.Fatalf("Delete(5) error = %v; want ErrIndexOutOfRange", err)
+	}
+}
+
+// TestSQLStoreFind verifies indexed lookups by website and username,
+// ignoring case and reporting the current index of each match.
+func TestSQLStoreFind(t *testing.T) {
+	s := setupSQLStore(t)
+	s.Append(account.Account{Website: "other.com", Username: "x"})
+	s.Append(account.Account{Website: "Example.com", Username: "alice"})
+	s.Append(account.Account{Website: "example.com", Username: "bob"})
+
+	matches, err := s.Find("EXAMPLE.COM", "")
+	if err != nil {
+		t.Fatalf("Find() failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Index != 1 || matches[1].Index != 2 {
+		t.Fatalf("Find(example.com) = %v; want indices 1 and 2", matches)
+	}
+
+	// After deleting the first entry every index shifts down by one
+	s.Delete(0)
+	matches, _ = s.Find("example.com", "Bob")
+	if len(matches) != 1 || matches[0].Index != 1 || matches[0].Account.Username != "bob" {
+		t.Fatalf("Find(example.com, bob) = %v; want bob at index 1", matches)
+	}
+}
+
+// TestConvertToSQLite verifies that a JSON vault is converted losslessly,
+// that storage.Open then picks the SQLite backend, and that the original
+// file is kept as an encrypted backup.
+func TestConvertToSQLite(t *testing.T) {
+	src := setupTempStorage(t)
+	want := []account.Account{
+		{Website: "example.com", Username: "alice", Email: "a@example.com", Pwd: "p1"},
+		{Website: "github.com", Username: "bob", Email: "b@example.org", Pwd: `p"2`},
+	}
+	if err := src.Save(want); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	if err := storage.ConvertToSQLite(src); err != nil {
+		t.Fatalf("ConvertToSQLite() failed: %v", err)
+	}
+
+	v := storage.Open(src.Path, storage.DefaultLockTimeout)
+	dst, ok := v.(*storage.SQLStore)
+	if !ok {
+		t.Fatalf("Open() after conversion returned %T; want *storage.SQLStore", v)
+	}
+	defer dst.Close()
+
+	dst.Unlock([]byte(testPassword))
+	got, err := dst.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Load() = %v; want %v", got, want)
+	}
+
+	// The JSON vault is kept, still encrypted
+	bak, err := os.ReadFile(src.Path + storage.ConvertBackupSuffix)
+	if err != nil || !vault.IsSealed(bak) {
+		t.Fatalf("Encrypted JSON backup missing: %v", err)
+	}
+
+	// The converted vault keeps working with password changes
+	if err := dst.ChangePassword([]byte("new master")); err != nil {
+		t.Fatalf("ChangePassword() failed: %v", err)
+	}
+	dst.Unlock([]byte("new master"))
+	if got, err = dst.Load(); err != nil || len(got) != 2 {
+		t.Fatalf("Load() after ChangePassword() = %v, %v", got, err)
+	}
+
+	// The JSON vault kept by the conversion follows the new password.
+	bak, _ = os.ReadFile(src.Path + storage.ConvertBackupSuffix)
+	if _, _, err := vault.Open([]byte("new master"), bak); err != nil {
+		t.Errorf("JSON backup does not open with the new password: %v", err)
+	}
+}
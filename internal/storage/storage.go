@@ -2,88 +2,124 @@
 // Use of this source code is governed by a BSD-style
 // license that can be found in the LICENSE file.
 
-// Package storage provides low-level functions for creating, reading,
-// writing, and appending account data to the JSON storage file.
-// The file path and permissions are managed via the util package.
+// Package storage provides the Store abstraction used to load and save
+// account data, together with its implementations: FileStore keeps the
+// accounts encoded as JSON in a file sealed with the vault package,
+// SQLStore keeps them as individually sealed rows of a SQLite database
+// for large vaults, and MemStore keeps them in memory for tests.
 package storage
 
 import (
-	"encoding/json"
-	"os"
+	"errors"
+	"time"
 
 	"github.com/nullzeiger/pwdcli/internal/account"
-	"github.com/nullzeiger/pwdcli/internal/util"
+	"github.com/nullzeiger/pwdcli/internal/vault"
 )
 
-// Create initializes the storage file if it does not already exist.
-// It ensures that the file path returned by util.FilePath()
-// exists and contains an empty JSON array ([]).
-// If the file already exists, the function does nothing.
-func Create() error {
-	path := util.FilePath()
+// Store is a place where accounts are kept. Implementations must be safe
+// for use by several processes when Lock is held around a sequence of
+// Load and Save calls.
+type Store interface {
+	// Load returns all stored accounts in order.
+	Load() ([]account.Account, error)
 
-	// If the storage file already exists, nothing needs to be done.
-	if util.FileExists(path) {
-		return nil
-	}
+	// Save replaces all stored accounts with accounts.
+	Save(accounts []account.Account) error
 
-	// Create the empty file.
-	file, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+	// Append adds acc after the existing accounts. It takes the lock
+	// itself, so it must not be called while Lock is held.
+	Append(acc account.Account) error
 
-	// Initialize the file with an empty JSON array.
-	_, err = file.Write([]byte("[]"))
-	return err
+	// Lock takes an exclusive lock on the store so that a whole
+	// read-modify-write sequence runs without interference. The returned
+	// function releases the lock.
+	Lock() (func() error, error)
 }
 
-// Read loads all stored accounts from the JSON file into a slice.
-// Returns an error if the file cannot be read or the JSON is malformed.
-func Read() ([]account.Account, error) {
-	path := util.FilePath()
+// Vault is a Store protected by a master password. Both FileStore and
+// SQLStore implement it.
+type Vault interface {
+	Store
 
-	// Read raw file contents.
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
+	// Unlock sets the master password used to open and seal the vault.
+	Unlock(pwd []byte)
+
+	// Exists reports whether the vault has already been created.
+	Exists() bool
+
+	// Create initializes an empty vault if it does not exist yet.
+	Create() error
 
-	// Decode JSON into a slice of Account structs.
-	var accounts []account.Account
-	err = json.Unmarshal(data, &accounts)
-	return accounts, err
+	// ChangePassword re-encrypts the vault with a new master password.
+	ChangePassword(newPassword []byte) error
+
+	// Rekey re-encrypts the vault with new KDF parameters.
+	Rekey(params vault.Params) error
+
+	// KDFParams returns the KDF parameters the vault is sealed with.
+	KDFParams() (vault.Params, error)
 }
 
-// Write replaces the entire storage file with the provided slice of accounts.
-// The file is overwritten using the permissions defined in util.Perm.
-func Write(accounts []account.Account) error {
-	path := util.FilePath()
+// Deleter is implemented by stores that can delete a single account
+// without rewriting the others.
+type Deleter interface {
+	// Delete removes the account at index.
+	Delete(index int) error
+}
 
-	// Encode accounts as pretty-printed JSON.
-	jsonData, err := json.MarshalIndent(accounts, "", "  ")
-	if err != nil {
-		return err
-	}
+// Updater is implemented by stores that can replace a single account
+// without rewriting the others.
+type Updater interface {
+	// Update replaces the account at index.
+	Update(index int, acc account.Account) error
+}
 
-	// Overwrite the storage file with new data.
-	return os.WriteFile(path, jsonData, util.Perm)
+// Recorder is implemented by stores that keep previous versions of the
+// vault, so that bookkeeping such as access times can be written without
+// counting as a version of its own.
+type Recorder interface {
+	// Record replaces all stored accounts like Save, but keeps no backup
+	// of the version it replaces.
+	Record(accounts []account.Account) error
 }
 
-// Append reads the existing accounts from storage, adds the new account,
-// and writes all accounts back to the file.
-// Returns an error if reading or writing fails.
-func Append(acc account.Account) error {
-	// Load existing data.
-	accounts, err := Read()
-	if err != nil {
-		return err
-	}
+// Finder is implemented by stores that can look accounts up by website
+// and username without loading the whole vault.
+type Finder interface {
+	// Find returns the accounts whose website, and username if not
+	// empty, match exactly, ignoring case.
+	Find(website, username string) ([]Match, error)
+}
 
-	// Add the new entry.
-	accounts = append(accounts, acc)
+// Match is an account together with its index in the store.
+type Match struct {
+	Index   int
+	Account account.Account
+}
 
-	// Save updated account list.
-	return Write(accounts)
+// Open returns the Vault stored at path, choosing the backend from the
+// file contents: a SQLite database opens as a SQLStore, anything else,
+// including a file that does not exist yet, as a FileStore.
+func Open(path string, lockTimeout time.Duration) Vault {
+	if IsSQLite(path) {
+		s := NewSQLStore(path)
+		s.LockTimeout = lockTimeout
+		return s
+	}
+	s := NewFileStore(path)
+	s.LockTimeout = lockTimeout
+	return s
 }
+
+var (
+	// ErrNoPassword is returned when the vault is accessed before Unlock.
+	ErrNoPassword = errors.New("master password not set")
+
+	// ErrNotEncrypted is returned when the storage file is not a sealed vault.
+	ErrNotEncrypted = errors.New("storage file is not encrypted")
+
+	// ErrIndexOutOfRange is returned when an index does not refer to a
+	// stored account.
+	ErrIndexOutOfRange = errors.New("index out of range")
+)
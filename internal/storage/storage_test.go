@@ -8,41 +8,64 @@
 package storage_test
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/nullzeiger/pwdcli/internal/account"
 	"github.com/nullzeiger/pwdcli/internal/storage"
 	"github.com/nullzeiger/pwdcli/internal/util"
+	"github.com/nullzeiger/pwdcli/internal/vault"
 )
 
-// setupTempStorage prepares a temporary storage environment for tests.
-// It performs the following steps:
-// - Creates a temporary directory to simulate a HOME directory
-// - Overrides the HOME environment variable for the test
-// - Ensures that the storage file exists and is initialized as empty
-func setupTempStorage(t *testing.T) string {
+// testPassword is the master password used by the tests.
+const testPassword = "correct horse"
+
+// newTempStore returns a FileStore for a vault file inside a temporary
+// directory, unlocked with the test master password and cheap KDF
+// parameters. The vault file itself is not created.
+func newTempStore(t *testing.T) *storage.FileStore {
 	t.Helper() // Marks this function as a helper to report errors at the caller
 
 	// Create a temporary directory for this test
-	tmpDir := t.TempDir()
-	t.Setenv("HOME", tmpDir)
+	s := storage.NewFileStore(filepath.Join(t.TempDir(), util.Filename))
+
+	// Keep key derivation fast and set the master password
+	s.Params = vault.Params{Time: 1, Memory: 64, Threads: 1}
+	s.Unlock([]byte(testPassword))
+	return s
+}
+
+// setupTempStorage prepares a temporary storage environment for tests.
+// It performs the following steps:
+// - Creates a store in a temporary directory with newTempStore
+// - Ensures that the storage file exists and is initialized as empty
+func setupTempStorage(t *testing.T) *storage.FileStore {
+	t.Helper()
 
-	// Get the full path to the storage file
-	path := util.FilePath()
+	s := newTempStore(t)
 
 	// Create the storage file if it does not exist
-	if err := storage.Create(); err != nil {
-		t.Fatalf("storage.Create() failed: %v", err)
+	if err := s.Create(); err != nil {
+		t.Fatalf("Create() failed: %v", err)
 	}
 
-	return path
+	return s
 }
 
-// TestCreate verifies that storage.Create correctly creates the storage file
-// and initializes it with an empty JSON array ("[]").
+// TestCreate verifies that FileStore.Create correctly creates the storage file
+// and initializes it with an encrypted empty JSON array ("[]").
 func TestCreate(t *testing.T) {
-	path := setupTempStorage(t)
+	s := setupTempStorage(t)
+	path := s.Path
 
 	// Confirm that the file now exists
 	if !util.FileExists(path) {
@@ -55,34 +78,91 @@ func TestCreate(t *testing.T) {
 		t.Fatalf("Failed to read file: %v", err)
 	}
 
-	if string(data) != "[]" {
-		t.Fatalf("File content = %s; want []", string(data))
+	plaintext, _, err := vault.Open([]byte(testPassword), data)
+	if err != nil {
+		t.Fatalf("vault.Open() failed: %v", err)
+	}
+
+	if string(plaintext) != "[]" {
+		t.Fatalf("File content = %s; want []", string(plaintext))
 	}
 }
 
-// TestWriteAndRead verifies that storage.Write correctly saves a slice of accounts
-// and that storage.Read can read them back accurately.
-func TestWriteAndRead(t *testing.T) {
-	setupTempStorage(t)
+// TestSaveEncrypts verifies that FileStore.Save never leaves account
+// data in plaintext on disk.
+func TestSaveEncrypts(t *testing.T) {
+	s := setupTempStorage(t)
+	path := s.Path
+
+	accounts := []account.Account{
+		{Website: "example.com", Username: "user", Email: "a@b.com", Pwd: "s3cret"},
+	}
+	if err := s.Save(accounts); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read file: %v", err)
+	}
+
+	// Neither the website nor the password may be readable
+	for _, s := range []string{"example.com", "s3cret"} {
+		if strings.Contains(string(data), s) {
+			t.Fatalf("Storage file contains %q in plaintext", s)
+		}
+	}
+}
+
+// TestWrongPassword verifies that opening the vault with the wrong master
+// password fails with vault.ErrWrongPassword and that a subsequent Save
+// does not overwrite the vault.
+func TestWrongPassword(t *testing.T) {
+	s := setupTempStorage(t)
+	path := s.Path
+
+	before, _ := os.ReadFile(path)
+
+	s.Unlock([]byte("wrong"))
+
+	// Reading must report the wrong password, not a JSON error
+	if _, err := s.Load(); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Load() error = %v; want ErrWrongPassword", err)
+	}
+
+	// Writing must refuse as well and leave the file untouched
+	if err := s.Save(nil); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Save() error = %v; want ErrWrongPassword", err)
+	}
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Fatalf("Save() with wrong password modified the vault")
+	}
+}
+
+// TestSaveAndLoad verifies that FileStore.Save correctly saves a slice of accounts
+// and that FileStore.Load can read them back accurately.
+func TestSaveAndLoad(t *testing.T) {
+	s := setupTempStorage(t)
 
 	accounts := []account.Account{
 		{Website: "example.com", Username: "user", Email: "a@b.com", Pwd: "123"},
 	}
 
-	// Write the account to storage
-	if err := storage.Write(accounts); err != nil {
-		t.Fatalf("Write() failed: %v", err)
+	// Save the account to storage
+	if err := s.Save(accounts); err != nil {
+		t.Fatalf("Save() failed: %v", err)
 	}
 
-	// Read accounts back from storage
-	readAccounts, err := storage.Read()
+	// Load accounts back from storage
+	readAccounts, err := s.Load()
 	if err != nil {
-		t.Fatalf("Read() failed: %v", err)
+		t.Fatalf("Load() failed: %v", err)
 	}
 
 	// Verify that exactly one account was read
 	if len(readAccounts) != 1 {
-		t.Fatalf("Read returned %d accounts; want 1", len(readAccounts))
+		t.Fatalf("Load returned %d accounts; want 1", len(readAccounts))
 	}
 
 	// Check the account data matches what was written
@@ -91,33 +171,33 @@ func TestWriteAndRead(t *testing.T) {
 	}
 }
 
-// TestAppend verifies that storage.Append correctly adds new accounts
+// TestAppend verifies that FileStore.Append correctly adds new accounts
 // without overwriting previous entries.
 func TestAppend(t *testing.T) {
-	setupTempStorage(t)
+	s := setupTempStorage(t)
 
 	acc1 := account.Account{Website: "example1.com", Username: "u1", Email: "e1", Pwd: "p1"}
 	acc2 := account.Account{Website: "example2.com", Username: "u2", Email: "e2", Pwd: "p2"}
 
 	// Append the first account
-	if err := storage.Append(acc1); err != nil {
+	if err := s.Append(acc1); err != nil {
 		t.Fatalf("Append() failed: %v", err)
 	}
 
 	// Append the second account
-	if err := storage.Append(acc2); err != nil {
+	if err := s.Append(acc2); err != nil {
 		t.Fatalf("Append() failed: %v", err)
 	}
 
-	// Read all accounts from storage
-	accounts, err := storage.Read()
+	// Load all accounts from storage
+	accounts, err := s.Load()
 	if err != nil {
-		t.Fatalf("Read() failed: %v", err)
+		t.Fatalf("Load() failed: %v", err)
 	}
 
 	// Check that both accounts exist
 	if len(accounts) != 2 {
-		t.Fatalf("Read returned %d accounts; want 2", len(accounts))
+		t.Fatalf("Load returned %d accounts; want 2", len(accounts))
 	}
 
 	// Verify that the accounts are in the correct order
@@ -129,17 +209,555 @@ func TestAppend(t *testing.T) {
 // TestAppendWithoutCreate verifies that Append fails if the storage file
 // has not been created, simulating incorrect usage.
 func TestAppendWithoutCreate(t *testing.T) {
-	t.Helper()
-
-	// Create temporary HOME directory but do not create storage file
-	tmpDir := t.TempDir()
-	t.Setenv("HOME", tmpDir)
+	// Use a temporary directory but do not create the storage file
+	s := newTempStore(t)
 
 	acc := account.Account{Website: "site.com", Username: "u", Email: "e", Pwd: "p"}
 
 	// Append should fail because the storage file does not exist
-	err := storage.Append(acc)
+	err := s.Append(acc)
 	if err == nil {
 		t.Fatalf("Append() should fail if storage file does not exist")
 	}
 }
+
+// setupLegacyStorage copies testdata/legacy.json, a file written by the
+// plaintext storage.Write of earlier versions, into a temporary directory.
+// It returns a store for that file and the original file contents.
+func setupLegacyStorage(t *testing.T) (*storage.FileStore, []byte) {
+	t.Helper()
+
+	s := newTempStore(t)
+
+	legacy, err := os.ReadFile(filepath.Join("testdata", "legacy.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	if err := os.WriteFile(s.Path, legacy, util.Perm); err != nil {
+		t.Fatalf("Failed to write legacy file: %v", err)
+	}
+	return s, legacy
+}
+
+// TestMigrate verifies that a plaintext vault is detected, encrypted
+// losslessly, backed up on request, and that the plaintext is wiped.
+func TestMigrate(t *testing.T) {
+	s, legacy := setupLegacyStorage(t)
+	path := s.Path
+
+	// A second link lets the test observe the original inode after
+	// the migration has replaced the file name.
+	witness := filepath.Join(filepath.Dir(path), "witness")
+	if err := os.Link(path, witness); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	legacyDetected, err := s.IsLegacy()
+	if err != nil || !legacyDetected {
+		t.Fatalf("IsLegacy() = %v, %v; want true", legacyDetected, err)
+	}
+
+	// Reading a legacy file without migrating is refused
+	if _, err := s.Load(); !errors.Is(err, storage.ErrNotEncrypted) {
+		t.Fatalf("Load() before Migrate() error = %v; want ErrNotEncrypted", err)
+	}
+
+	if err := s.Migrate(true); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+
+	// The vault is no longer legacy and reads back every entry
+	if legacyDetected, _ = s.IsLegacy(); legacyDetected {
+		t.Fatalf("IsLegacy() = true after Migrate()")
+	}
+	accounts, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() after Migrate() failed: %v", err)
+	}
+	want := []account.Account{
+		{Website: "example.com", Username: "alice", Email: "alice@example.com", Pwd: "hunter2"},
+		{Website: "github.com", Username: "bob", Email: "bob@example.org", Pwd: `p@ss w0rd "quoted"`},
+	}
+	if !reflect.DeepEqual(accounts, want) {
+		t.Fatalf("Load() after Migrate() = %v; want %v", accounts, want)
+	}
+
+	// The backup is encrypted and holds the original contents
+	bak, err := os.ReadFile(path + storage.BackupSuffix)
+	if err != nil {
+		t.Fatalf("Backup missing: %v", err)
+	}
+	plaintext, _, err := vault.Open([]byte(testPassword), bak)
+	if err != nil || string(plaintext) != string(legacy) {
+		t.Fatalf("Backup does not decrypt to the original file: %v", err)
+	}
+
+	// The original plaintext has been overwritten
+	wiped, _ := os.ReadFile(witness)
+	if strings.Contains(string(wiped), "hunter2") {
+		t.Fatalf("Plaintext was not wiped: %s", wiped)
+	}
+	os.Remove(witness)
+
+	// No file left in the directory contains a password in plaintext
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		data, _ := os.ReadFile(filepath.Join(filepath.Dir(path), e.Name()))
+		if strings.Contains(string(data), "hunter2") {
+			t.Fatalf("%s contains a password in plaintext", e.Name())
+		}
+	}
+}
+
+// TestMigrateWithoutBackup verifies that no backup is written unless
+// requested.
+func TestMigrateWithoutBackup(t *testing.T) {
+	s, _ := setupLegacyStorage(t)
+	path := s.Path
+
+	if err := s.Migrate(false); err != nil {
+		t.Fatalf("Migrate() failed: %v", err)
+	}
+	if util.FileExists(path + storage.BackupSuffix) {
+		t.Fatalf("Migrate(false) created a backup")
+	}
+}
+
+// TestMigrateMalformed verifies that a file which is not a valid account
+// list is left untouched.
+func TestMigrateMalformed(t *testing.T) {
+	s, _ := setupLegacyStorage(t)
+	path := s.Path
+
+	malformed := []byte(`[{"website": `)
+	os.WriteFile(path, malformed, util.Perm)
+
+	if err := s.Migrate(false); err == nil {
+		t.Fatalf("Migrate() of malformed file should fail")
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != string(malformed) {
+		t.Fatalf("Migrate() modified a malformed file")
+	}
+}
+
+// TestChangePassword verifies that the vault is re-encrypted with the new
+// master password, that the old one stops working, and that no entry is lost.
+func TestChangePassword(t *testing.T) {
+	s := setupTempStorage(t)
+
+	acc := account.Account{Website: "example.com", Username: "u", Email: "e", Pwd: "p"}
+	if err := s.Append(acc); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+
+	if err := s.ChangePassword([]byte("new master")); err != nil {
+		t.Fatalf("ChangePassword() failed: %v", err)
+	}
+
+	// The old password no longer opens the vault
+	s.Unlock([]byte(testPassword))
+	if _, err := s.Load(); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Load() with old password error = %v; want ErrWrongPassword", err)
+	}
+
+	// The new password opens it with the entry intact
+	s.Unlock([]byte("new master"))
+	accounts, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() with new password failed: %v", err)
+	}
+	if len(accounts) != 1 || !reflect.DeepEqual(accounts[0], acc) {
+		t.Fatalf("Load() after ChangePassword() = %v; want [%v]", accounts, acc)
+	}
+}
+
+// TestRekey verifies that Rekey stores the new KDF parameters in the
+// header while keeping the password and the entries.
+func TestRekey(t *testing.T) {
+	s := setupTempStorage(t)
+
+	acc := account.Account{Website: "example.com", Username: "u", Email: "e", Pwd: "p"}
+	s.Append(acc)
+
+	params := vault.Params{Time: 2, Memory: 128, Threads: 2}
+	if err := s.Rekey(params); err != nil {
+		t.Fatalf("Rekey() failed: %v", err)
+	}
+
+	// Re-open from scratch so the parameters come from the file
+	s.Unlock([]byte(testPassword))
+	got, err := s.KDFParams()
+	if err != nil {
+		t.Fatalf("KDFParams() failed: %v", err)
+	}
+	if got != params {
+		t.Fatalf("KDFParams() = %v; want %v", got, params)
+	}
+
+	accounts, err := s.Load()
+	if err != nil || len(accounts) != 1 || !reflect.DeepEqual(accounts[0], acc) {
+		t.Fatalf("Load() after Rekey() = %v, %v; want [%v]", accounts, err, acc)
+	}
+}
+
+// TestRekeyFailureKeepsVault verifies that a failed re-encryption leaves
+// the vault readable with the current password.
+func TestRekeyFailureKeepsVault(t *testing.T) {
+	s := setupTempStorage(t)
+	path := s.Path
+	s.Append(account.Account{Website: "example.com"})
+	before, _ := os.ReadFile(path)
+
+	// Zero threads are rejected by the key derivation
+	if err := s.Rekey(vault.Params{Time: 1, Memory: 64}); err == nil {
+		t.Fatalf("Rekey() with invalid parameters should fail")
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Fatalf("Failed Rekey() modified the vault")
+	}
+
+	s.Unlock([]byte(testPassword))
+	if accounts, err := s.Load(); err != nil || len(accounts) != 1 {
+		t.Fatalf("Load() after failed Rekey() = %v, %v", accounts, err)
+	}
+}
+
+// TestSavePreservesMode verifies that Save keeps the permissions of an
+// existing storage file instead of resetting them.
+func TestSavePreservesMode(t *testing.T) {
+	s := setupTempStorage(t)
+	path := s.Path
+
+	// A mode other than the default one for new files.
+	if err := os.Chmod(path, 0o640); err != nil {
+		t.Fatalf("Chmod() failed: %v", err)
+	}
+	if err := s.Save([]account.Account{{Website: "example.com"}}); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Stat() failed: %v", err)
+	}
+	if fi.Mode().Perm() != 0o640 {
+		t.Fatalf("Mode after Save() = %v; want 0640", fi.Mode().Perm())
+	}
+}
+
+// TestCreatePrivate verifies that a new vault and its backups can only be
+// read by their owner.
+func TestCreatePrivate(t *testing.T) {
+	s := setupTempStorage(t)
+	s.Backups = 1
+	if err := s.Append(account.Account{Website: "example.com"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+
+	for _, path := range []string{s.Path, storage.BackupName(s.Path, 1)} {
+		fi, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat(%s) failed: %v", path, err)
+		}
+		if fi.Mode().Perm() != 0o600 {
+			t.Errorf("Mode of %s = %v; want 0600", filepath.Base(path), fi.Mode().Perm())
+		}
+	}
+}
+
+// TestSaveReadOnlyDir verifies that a Save which cannot create its
+// temporary file fails and leaves the previous vault intact.
+func TestSaveReadOnlyDir(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	s := setupTempStorage(t)
+	path := s.Path
+	s.Append(account.Account{Website: "example.com"})
+	before, _ := os.ReadFile(path)
+
+	dir := filepath.Dir(path)
+	os.Chmod(dir, 0o500)
+	defer os.Chmod(dir, 0o700)
+
+	if err := s.Save(nil); err == nil {
+		t.Fatalf("Save() into a read-only directory should fail")
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Fatalf("Failed Save() modified the vault")
+	}
+}
+
+// TestReplaceFileWriterError verifies that an error raised halfway through
+// writing the new contents leaves the previous vault intact and removes the
+// temporary file.
+func TestReplaceFileWriterError(t *testing.T) {
+	s := setupTempStorage(t)
+	path := s.Path
+	s.Append(account.Account{Website: "example.com"})
+	before, _ := os.ReadFile(path)
+
+	// Write part of the data, then fail like a full disk would
+	errDiskFull := errors.New("no space left on device")
+	err := storage.ReplaceFile(path, func(w io.Writer) error {
+		w.Write([]byte("PWDCLI partial"))
+		return errDiskFull
+	})
+	if !errors.Is(err, errDiskFull) {
+		t.Fatalf("ReplaceFile() error = %v; want %v", err, errDiskFull)
+	}
+
+	after, _ := os.ReadFile(path)
+	if string(before) != string(after) {
+		t.Fatalf("Failed ReplaceFile() modified the vault")
+	}
+
+	// The vault still opens and no temporary file is left behind
+	if accounts, err := s.Load(); err != nil || len(accounts) != 1 {
+		t.Fatalf("Load() after failed write = %v, %v", accounts, err)
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	for _, e := range entries {
+		if strings.Contains(e.Name(), ".tmp") {
+			t.Fatalf("Temporary file %s left behind", e.Name())
+		}
+	}
+}
+
+// TestLockTimeout verifies that Lock gives up after LockTimeout while the
+// vault is held and reports the PID of the holder.
+func TestLockTimeout(t *testing.T) {
+	s := setupTempStorage(t)
+
+	unlock, err := s.Lock()
+	if err != nil {
+		t.Fatalf("Lock() failed: %v", err)
+	}
+
+	s.LockTimeout = 100 * time.Millisecond
+
+	// A second lock must time out naming this process
+	_, err = s.Lock()
+	var locked *storage.LockedError
+	if !errors.As(err, &locked) {
+		t.Fatalf("Second Lock() error = %v; want *LockedError", err)
+	}
+	if locked.PID != os.Getpid() {
+		t.Fatalf("LockedError.PID = %d; want %d", locked.PID, os.Getpid())
+	}
+
+	// Once released the lock can be taken again
+	unlock()
+	unlock, err = s.Lock()
+	if err != nil {
+		t.Fatalf("Lock() after release failed: %v", err)
+	}
+	unlock()
+}
+
+// helperEnv names the environment variable that turns TestHelperAppend
+// into a child process of TestConcurrentAppend. helperVaultEnv passes the
+// path of the shared vault.
+const (
+	helperEnv      = "PWDCLI_TEST_HELPER_APPEND"
+	helperVaultEnv = "PWDCLI_TEST_HELPER_VAULT"
+)
+
+// helperAppends is the number of entries each child process appends.
+const helperAppends = 10
+
+// TestHelperAppend is not a real test: it is run as a separate process by
+// TestConcurrentAppend and appends helperAppends entries to the vault named
+// by helperVaultEnv.
+func TestHelperAppend(t *testing.T) {
+	id := os.Getenv(helperEnv)
+	if id == "" {
+		t.Skip("helper process for TestConcurrentAppend")
+	}
+
+	s := storage.NewFileStore(os.Getenv(helperVaultEnv))
+	s.Unlock([]byte(testPassword))
+
+	for i := range helperAppends {
+		acc := account.Account{Website: fmt.Sprintf("site-%s-%d", id, i)}
+		if err := s.Append(acc); err != nil {
+			t.Fatalf("Append() failed: %v", err)
+		}
+	}
+}
+
+// TestConcurrentAppend runs several processes appending to the same vault
+// at once and verifies that no entry is lost.
+func TestConcurrentAppend(t *testing.T) {
+	s := setupTempStorage(t)
+
+	const procs = 4
+	cmds := make([]*exec.Cmd, procs)
+	for i := range cmds {
+		cmds[i] = exec.Command(os.Args[0], "-test.run=^TestHelperAppend$")
+		cmds[i].Env = append(os.Environ(),
+			fmt.Sprintf("%s=%d", helperEnv, i),
+			fmt.Sprintf("%s=%s", helperVaultEnv, s.Path))
+		if err := cmds[i].Start(); err != nil {
+			t.Fatalf("Failed to start helper: %v", err)
+		}
+	}
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("Helper failed: %v", err)
+		}
+	}
+
+	accounts, err := s.Load()
+	if err != nil {
+		t.Fatalf("Load() failed: %v", err)
+	}
+	if len(accounts) != procs*helperAppends {
+		t.Fatalf("Load returned %d accounts; want %d", len(accounts), procs*helperAppends)
+	}
+}
+
+// TestMemStore verifies that MemStore behaves like a Store: appended
+// accounts are returned in order and Save replaces them. Loaded slices
+// are copies, so changing them does not affect the store.
+func TestMemStore(t *testing.T) {
+	var s storage.Store = storage.NewMemStore(account.Account{Website: "a.com"})
+
+	if err := s.Append(account.Account{Website: "b.com"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+
+	accounts, _ := s.Load()
+	if len(accounts) != 2 || accounts[0].Website != "a.com" || accounts[1].Website != "b.com" {
+		t.Fatalf("Load() = %v; want a.com, b.com", accounts)
+	}
+
+	// Modifying the loaded slice must not change the store
+	accounts[0].Website = "changed"
+	if again, _ := s.Load(); again[0].Website != "a.com" {
+		t.Fatalf("Load() returned a slice sharing memory with the store")
+	}
+
+	if err := s.Save(accounts[1:]); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	if accounts, _ = s.Load(); len(accounts) != 1 || accounts[0].Website != "b.com" {
+		t.Fatalf("Load() after Save() = %v; want b.com", accounts)
+	}
+}
+
+// TestSaveKeepsBackups verifies that Save keeps the configured number of
+// previous versions and that each one still opens.
+func TestSaveKeepsBackups(t *testing.T) {
+	store := setupTempStorage(t)
+	store.Backups = 2
+
+	// Save three more versions after the initial empty vault.
+	for _, site := range []string{"a", "b", "c"} {
+		if err := store.Append(account.Account{Website: site}); err != nil {
+			t.Fatalf("Append(%s) failed: %v", site, err)
+		}
+	}
+
+	// The most recent backup holds the version before the last save.
+	want := map[int]int{1: 2, 2: 1}
+	for n, count := range want {
+		bak := storage.NewFileStore(storage.BackupName(store.Path, n))
+		bak.Unlock([]byte(testPassword))
+		accounts, err := bak.Load()
+		if err != nil {
+			t.Fatalf("Load(backup %d) failed: %v", n, err)
+		}
+		if len(accounts) != count {
+			t.Errorf("backup %d has %d accounts; want %d", n, len(accounts), count)
+		}
+	}
+
+	// Older versions beyond the retention are removed.
+	if util.FileExists(storage.BackupName(store.Path, 3)) {
+		t.Error("backup 3 exists; want at most 2 backups")
+	}
+}
+
+// TestRecordKeepsBackups verifies that Record replaces the vault without
+// rotating the backups kept by Save.
+func TestRecordKeepsBackups(t *testing.T) {
+	store := setupTempStorage(t)
+	store.Backups = 2
+	if err := store.Append(account.Account{Website: "a"}); err != nil {
+		t.Fatalf("Append() failed: %v", err)
+	}
+	before, err := os.ReadFile(storage.BackupName(store.Path, 1))
+	if err != nil {
+		t.Fatalf("ReadFile(backup 1) failed: %v", err)
+	}
+
+	if err := store.Record([]account.Account{{Website: "a", Notes: "recorded"}}); err != nil {
+		t.Fatalf("Record() failed: %v", err)
+	}
+
+	// The vault holds the recorded version and the backups are as they were.
+	if accounts, _ := store.Load(); len(accounts) != 1 || accounts[0].Notes != "recorded" {
+		t.Errorf("Load() after Record() = %v; want the recorded account", accounts)
+	}
+	after, err := os.ReadFile(storage.BackupName(store.Path, 1))
+	if err != nil || !bytes.Equal(before, after) {
+		t.Errorf("backup 1 changed by Record(): %v", err)
+	}
+	if util.FileExists(storage.BackupName(store.Path, 2)) {
+		t.Error("backup 2 exists after Record(); want a single backup")
+	}
+}
+
+// TestChangePasswordResealsBackups verifies that ChangePassword seals the
+// retained backups with the new password, so that the old one opens none
+// of them, and reports the backups it could not open.
+func TestChangePasswordResealsBackups(t *testing.T) {
+	store := setupTempStorage(t)
+	store.Backups = 3
+	for _, site := range []string{"a", "b"} {
+		if err := store.Append(account.Account{Website: site}); err != nil {
+			t.Fatalf("Append(%s) failed: %v", site, err)
+		}
+	}
+
+	// A third backup sealed with an unrelated password.
+	k, err := vault.NewKey([]byte("older master"), vault.Params{Time: 1, Memory: 64, Threads: 1})
+	if err != nil {
+		t.Fatalf("NewKey() failed: %v", err)
+	}
+	sealed, _ := k.Seal([]byte("[]"))
+	if err := os.WriteFile(storage.BackupName(store.Path, 3), sealed, 0o600); err != nil {
+		t.Fatalf("WriteFile(backup 3) failed: %v", err)
+	}
+
+	// The password is changed, and the unrelated backup reported.
+	err = store.ChangePassword([]byte("new master"))
+	var stale *storage.StaleBackupsError
+	if !errors.As(err, &stale) || len(stale.Paths) != 1 || stale.Paths[0] != storage.BackupName(store.Path, 3) {
+		t.Fatalf("ChangePassword() error = %v; want backup 3 reported as stale", err)
+	}
+
+	// The other backups open with the new password only and keep their
+	// contents.
+	for n, count := range map[int]int{1: 1, 2: 0} {
+		data, err := os.ReadFile(storage.BackupName(store.Path, n))
+		if err != nil {
+			t.Fatalf("ReadFile(backup %d) failed: %v", n, err)
+		}
+		if _, _, err := vault.Open([]byte(testPassword), data); !errors.Is(err, vault.ErrWrongPassword) {
+			t.Errorf("backup %d opens with the old password: %v", n, err)
+		}
+		bak := storage.NewFileStore(storage.BackupName(store.Path, n))
+		bak.Unlock([]byte("new master"))
+		if accounts, err := bak.Load(); err != nil || len(accounts) != count {
+			t.Errorf("backup %d with the new password = %v, %v; want %d accounts", n, accounts, err, count)
+		}
+	}
+}
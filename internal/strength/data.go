@@ -0,0 +1,45 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strength
+
+import (
+	"embed"
+	"strings"
+	"sync"
+)
+
+// lists holds the embedded frequency lists. See data/README.md for their
+// origin.
+//
+//go:embed data/*.txt
+var lists embed.FS
+
+// dictionaries maps the name of each frequency list to the rank of its
+// entries, starting from 1 for the most common.
+var dictionaries = sync.OnceValue(func() map[string]map[string]int {
+	names := []string{"passwords", "english", "surnames", "male_names", "female_names"}
+	dicts := make(map[string]map[string]int, len(names))
+	for _, name := range names {
+		data, err := lists.ReadFile("data/" + name + ".txt")
+		if err != nil {
+			panic(err)
+		}
+		dicts[name] = rankedDictionary(strings.Fields(string(data)))
+	}
+	return dicts
+})
+
+// rankedDictionary returns the rank of each word, starting from 1. A word
+// that appears twice keeps its better rank.
+func rankedDictionary(words []string) map[string]int {
+	ranks := make(map[string]int, len(words))
+	for i, w := range words {
+		w = strings.ToLower(w)
+		if _, ok := ranks[w]; !ok {
+			ranks[w] = i + 1
+		}
+	}
+	return ranks
+}
@@ -0,0 +1,145 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strength
+
+import (
+	"unicode"
+)
+
+// Feedback explains a score and how to improve it.
+type Feedback struct {
+	// Warning names the main weakness, if any.
+	Warning string
+
+	// Suggestions are ways to make the password harder to guess.
+	Suggestions []string
+}
+
+// Suggestions offered by feedback.
+const (
+	suggestMoreWords   = "Add another word or two. Uncommon words are better."
+	suggestNoPatterns  = "Use a few words, avoid common phrases."
+	suggestNoSymbols   = "No need for symbols, digits, or uppercase letters."
+	suggestKeyboard    = "Use a longer keyboard pattern with more turns."
+	suggestRepeats     = "Avoid repeated words and characters."
+	suggestSequences   = "Avoid sequences."
+	suggestDates       = "Avoid dates and years that are associated with you."
+	suggestCapitals    = "Capitalization doesn't help very much."
+	suggestAllCaps     = "All-uppercase is almost as easy to guess as all-lowercase."
+	suggestReversed    = "Reversed words aren't much harder to guess."
+	suggestSubstitutes = "Predictable substitutions like '@' instead of 'a' don't help very much."
+)
+
+// feedback explains score from the weakest parts of sequence. Strong
+// passwords get no feedback.
+func feedback(score int, sequence []Match) Feedback {
+	if len(sequence) == 0 {
+		return Feedback{Suggestions: []string{suggestNoPatterns, suggestNoSymbols}}
+	}
+	if score > 2 {
+		return Feedback{}
+	}
+
+	// Comment on the longest pattern.
+	longest := sequence[0]
+	for _, m := range sequence[1:] {
+		if m.J-m.I > longest.J-longest.I {
+			longest = m
+		}
+	}
+
+	f := matchFeedback(longest, len(sequence) == 1)
+	f.Suggestions = append([]string{suggestMoreWords}, f.Suggestions...)
+	return f
+}
+
+// matchFeedback explains why m is easy to guess. sole reports whether m
+// covers the whole password.
+func matchFeedback(m Match, sole bool) Feedback {
+	switch m.Pattern {
+	case Dictionary:
+		return dictionaryFeedback(m, sole)
+	case Spatial:
+		warning := "Straight rows of keys are easy to guess."
+		if m.Turns > 1 {
+			warning = "Short keyboard patterns are easy to guess."
+		}
+		return Feedback{Warning: warning, Suggestions: []string{suggestKeyboard}}
+	case Repeat:
+		warning := `Repeats like "aaa" are easy to guess.`
+		if len([]rune(m.Base)) > 1 {
+			warning = `Repeats like "abcabcabc" are only slightly harder to guess than "abc".`
+		}
+		return Feedback{Warning: warning, Suggestions: []string{suggestRepeats}}
+	case Sequence:
+		return Feedback{Warning: "Sequences like abc or 6543 are easy to guess.", Suggestions: []string{suggestSequences}}
+	case Date, Year:
+		warning := "Dates are often easy to guess."
+		if m.Pattern == Year {
+			warning = "Recent years are easy to guess."
+		}
+		return Feedback{Warning: warning, Suggestions: []string{suggestDates}}
+	}
+	return Feedback{}
+}
+
+// dictionaryFeedback explains why a dictionary match is easy to guess.
+func dictionaryFeedback(m Match, sole bool) Feedback {
+	var f Feedback
+	switch m.Dictionary {
+	case "passwords":
+		switch {
+		case !sole:
+			f.Warning = "This is similar to a commonly used password."
+		case m.Rank <= 10 && !m.L33t && !m.Reversed:
+			f.Warning = "This is a top-10 common password."
+		case m.Rank <= 100 && !m.L33t && !m.Reversed:
+			f.Warning = "This is a top-100 common password."
+		default:
+			f.Warning = "This is a very common password."
+		}
+	case "english":
+		if sole {
+			f.Warning = "A word by itself is easy to guess."
+		}
+	case "surnames", "male_names", "female_names":
+		if sole {
+			f.Warning = "Names and surnames by themselves are easy to guess."
+		} else {
+			f.Warning = "Common names and surnames are easy to guess."
+		}
+	case "user_inputs":
+		f.Warning = "This is based on the account's own details."
+	}
+
+	runes := []rune(m.Token)
+	switch {
+	case allUpper(runes):
+		f.Suggestions = append(f.Suggestions, suggestAllCaps)
+	case len(runes) > 0 && unicode.IsUpper(runes[0]):
+		f.Suggestions = append(f.Suggestions, suggestCapitals)
+	}
+	if m.Reversed && len(runes) >= 4 {
+		f.Suggestions = append(f.Suggestions, suggestReversed)
+	}
+	if m.L33t {
+		f.Suggestions = append(f.Suggestions, suggestSubstitutes)
+	}
+	return f
+}
+
+// allUpper reports whether runes contains letters, all in upper case.
+func allUpper(runes []rune) bool {
+	letters := 0
+	for _, r := range runes {
+		if unicode.IsLower(r) {
+			return false
+		}
+		if unicode.IsUpper(r) {
+			letters++
+		}
+	}
+	return letters > 1
+}
@@ -0,0 +1,119 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strength
+
+import (
+	"math"
+	"strings"
+	"sync"
+)
+
+// key is a key of a keyboard layout.
+type key struct {
+	// x and y locate the key, in key widths from the top left corner.
+	x, y float64
+
+	// shifted reports whether the character needs the shift key.
+	shifted bool
+}
+
+// layout is a keyboard layout used to find spatial patterns.
+type layout struct {
+	// name identifies the layout in matches.
+	name string
+
+	// keys maps each character to its key.
+	keys map[rune]key
+
+	// slanted reports whether rows are offset from each other, as on a
+	// typewriter keyboard; keys of a straight grid such as a keypad also
+	// touch diagonally.
+	slanted bool
+
+	// starts is the number of keys and degree their average number of
+	// neighbours, which size the space of patterns.
+	starts float64
+	degree float64
+}
+
+// newLayout builds a layout from rows of space-separated keys. Each key
+// lists its unshifted character, optionally followed by the shifted one,
+// and the rows start at the given horizontal offsets.
+func newLayout(name string, slanted bool, offsets []float64, rows ...string) *layout {
+	l := &layout{name: name, keys: map[rune]key{}, slanted: slanted}
+	for y, row := range rows {
+		for x, chars := range strings.Fields(row) {
+			for i, ch := range chars {
+				l.keys[ch] = key{x: float64(x) + offsets[y], y: float64(y), shifted: i > 0}
+			}
+		}
+	}
+
+	// Count the neighbours of each unshifted key.
+	positions := map[[2]float64]bool{}
+	for _, k := range l.keys {
+		positions[[2]float64{k.x, k.y}] = true
+	}
+	total := 0
+	for a := range positions {
+		for b := range positions {
+			if l.touching(a[0], a[1], b[0], b[1]) {
+				total++
+			}
+		}
+	}
+	l.starts = float64(len(positions))
+	l.degree = float64(total) / l.starts
+	return l
+}
+
+// touching reports whether the keys at (x1, y1) and (x2, y2) are
+// neighbours.
+func (l *layout) touching(x1, y1, x2, y2 float64) bool {
+	dx, dy := math.Abs(x1-x2), math.Abs(y1-y2)
+	if dx == 0 && dy == 0 {
+		return false
+	}
+	if l.slanted {
+		return (dy == 0 && dx == 1) || (dy == 1 && dx < 1)
+	}
+	return dx <= 1 && dy <= 1
+}
+
+// direction returns the direction of the step from a to b, and false if
+// the keys are not neighbours.
+func (l *layout) direction(a, b rune) ([2]float64, bool) {
+	ka, okA := l.keys[a]
+	kb, okB := l.keys[b]
+	if !okA || !okB || !l.touching(ka.x, ka.y, kb.x, kb.y) {
+		return [2]float64{}, false
+	}
+	return [2]float64{kb.x - ka.x, kb.y - ka.y}, true
+}
+
+// layouts returns the keyboard layouts searched for spatial patterns.
+var layouts = sync.OnceValue(func() []*layout {
+	return []*layout{
+		newLayout("qwerty", true, []float64{0, 1.5, 1.75, 2.25},
+			"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) -_ =+",
+			"qQ wW eE rR tT yY uU iI oO pP [{ ]} \\|",
+			"aA sS dD fF gG hH jJ kK lL ;: '\"",
+			"zZ xX cC vV bB nN mM ,< .> /?",
+		),
+		newLayout("dvorak", true, []float64{0, 1.5, 1.75, 2.25},
+			"`~ 1! 2@ 3# 4$ 5% 6^ 7& 8* 9( 0) [{ ]}",
+			"'\" ,< .> pP yY fF gG cC rR lL /? =+ \\|",
+			"aA oO eE uU iI dD hH tT nN sS -_",
+			";: qQ jJ kK xX bB mM wW vV zZ",
+		),
+		newLayout("keypad", false, []float64{1, 0, 0, 0, 1},
+			"/ * -",
+			"7 8 9 +",
+			"4 5 6",
+			"1 2 3",
+			"0 .",
+		),
+	}
+})
@@ -0,0 +1,643 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package strength
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Pattern names the kind of a Match.
+type Pattern string
+
+// Patterns recognised by the estimator.
+const (
+	Dictionary Pattern = "dictionary"
+	Spatial    Pattern = "spatial"
+	Repeat     Pattern = "repeat"
+	Sequence   Pattern = "sequence"
+	Date       Pattern = "date"
+	Year       Pattern = "year"
+	Bruteforce Pattern = "bruteforce"
+)
+
+// Match is a part of a password that follows a guessable pattern.
+type Match struct {
+	// Pattern is the kind of the match.
+	Pattern Pattern
+
+	// I and J are the indexes of the first and last rune of the match.
+	I, J int
+
+	// Token is the matched part of the password.
+	Token string
+
+	// Guesses estimates how many attempts an attacker who knows the
+	// pattern needs to find the token.
+	Guesses float64
+
+	// Dictionary is the list a dictionary match was found in, Rank its
+	// position there, and Reversed and L33t tell whether the token was
+	// written backwards or with substitutions such as 4 for a.
+	Dictionary string
+	Rank       int
+	Reversed   bool
+	L33t       bool
+
+	// Layout is the keyboard of a spatial match and Turns the number of
+	// changes of direction.
+	Layout string
+	Turns  int
+
+	// Base is the repeated unit of a repeat match.
+	Base string
+}
+
+// Guess counts that shape the estimate, as in zxcvbn.
+const (
+	// bruteforceCardinality is the number of guesses per character of a
+	// part of the password that follows no pattern.
+	bruteforceCardinality = 10
+
+	// minGuessesSingleChar and minGuessesMultiChar are the least number of
+	// guesses charged for a match that is only part of the password.
+	minGuessesSingleChar = 10
+	minGuessesMultiChar  = 50
+
+	// referenceYear is the year dates are compared with, and minYearSpace
+	// the least number of years an attacker is assumed to try.
+	referenceYear = 2025
+	minYearSpace  = 20
+)
+
+// allMatches returns every pattern found in password. userInputs are
+// treated as a dictionary of their own, ranked in order.
+func allMatches(password []rune, userInputs []string) []Match {
+	dicts := dictionaries()
+	if len(userInputs) > 0 {
+		inputs := make(map[string]map[string]int, len(dicts)+1)
+		for name, d := range dicts {
+			inputs[name] = d
+		}
+		inputs["user_inputs"] = rankedDictionary(userInputs)
+		dicts = inputs
+	}
+
+	var matches []Match
+	matches = append(matches, dictionaryMatches(password, dicts)...)
+	matches = append(matches, reverseDictionaryMatches(password, dicts)...)
+	matches = append(matches, l33tMatches(password, dicts)...)
+	matches = append(matches, spatialMatches(password)...)
+	matches = append(matches, repeatMatches(password, userInputs)...)
+	matches = append(matches, sequenceMatches(password)...)
+	matches = append(matches, dateMatches(password)...)
+	return matches
+}
+
+// dictionaryMatches finds every substring of password that is in one of
+// dicts, ignoring case.
+func dictionaryMatches(password []rune, dicts map[string]map[string]int) []Match {
+	lower := []rune(strings.ToLower(string(password)))
+	var matches []Match
+	for i := range lower {
+		for j := i; j < len(lower); j++ {
+			word := string(lower[i : j+1])
+			for name, d := range dicts {
+				rank, ok := d[word]
+				if !ok {
+					continue
+				}
+				token := string(password[i : j+1])
+				matches = append(matches, Match{
+					Pattern:    Dictionary,
+					I:          i,
+					J:          j,
+					Token:      token,
+					Dictionary: name,
+					Rank:       rank,
+					Guesses:    float64(rank) * uppercaseVariations(token),
+				})
+			}
+		}
+	}
+	return matches
+}
+
+// reverseDictionaryMatches finds dictionary words written backwards.
+func reverseDictionaryMatches(password []rune, dicts map[string]map[string]int) []Match {
+	n := len(password)
+	reversed := make([]rune, n)
+	for i, ch := range password {
+		reversed[n-1-i] = ch
+	}
+
+	var matches []Match
+	for _, m := range dictionaryMatches(reversed, dicts) {
+		// Reversing a palindrome gives nothing new.
+		if reverse(m.Token) == m.Token {
+			continue
+		}
+		m.I, m.J = n-1-m.J, n-1-m.I
+		m.Token = string(password[m.I : m.J+1])
+		m.Reversed = true
+		m.Guesses *= 2
+		matches = append(matches, m)
+	}
+	return matches
+}
+
+// l33tTable maps letters to the characters commonly substituted for them.
+var l33tTable = map[rune]string{
+	'a': "4@",
+	'b': "8",
+	'c': "({[<",
+	'e': "3",
+	'g': "69",
+	'i': "1!|",
+	'l': "1|7",
+	'o': "0",
+	's': "$5",
+	't': "+7",
+	'x': "%",
+	'z': "2",
+}
+
+// maxL33tVariants limits how many substitution combinations are tried.
+const maxL33tVariants = 64
+
+// l33tMatches finds dictionary words written with substitutions, such as
+// "p4ssw0rd".
+func l33tMatches(password []rune, dicts map[string]map[string]int) []Match {
+	// Collect, for each substituted character in password, the letters it
+	// may stand for.
+	candidates := map[rune][]rune{}
+	for letter, subs := range l33tTable {
+		for _, sub := range subs {
+			if strings.ContainsRune(string(password), sub) {
+				candidates[sub] = append(candidates[sub], letter)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	var matches []Match
+	seen := map[[3]int]bool{}
+	for _, table := range l33tVariants(candidates) {
+		unsubbed := make([]rune, len(password))
+		for i, ch := range password {
+			if letter, ok := table[ch]; ok {
+				unsubbed[i] = letter
+			} else {
+				unsubbed[i] = ch
+			}
+		}
+
+		for _, m := range dictionaryMatches(unsubbed, dicts) {
+			token := string(password[m.I : m.J+1])
+			if strings.EqualFold(token, m.Token) {
+				// No substitution inside this match.
+				continue
+			}
+			key := [3]int{m.I, m.J, m.Rank}
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			m.Guesses *= l33tVariations(password[m.I:m.J+1], table)
+			m.Token = token
+			m.L33t = true
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// l33tVariants returns up to maxL33tVariants tables mapping substituted
+// characters to letters, one for each combination of meanings.
+func l33tVariants(candidates map[rune][]rune) []map[rune]rune {
+	tables := []map[rune]rune{{}}
+	for sub, letters := range candidates {
+		var next []map[rune]rune
+		for _, t := range tables {
+			for _, letter := range letters {
+				c := make(map[rune]rune, len(t)+1)
+				for k, v := range t {
+					c[k] = v
+				}
+				c[sub] = letter
+				next = append(next, c)
+				if len(next) >= maxL33tVariants {
+					break
+				}
+			}
+		}
+		tables = next
+	}
+	return tables
+}
+
+// uppercaseVariations returns how many ways of capitalizing a word an
+// attacker tries before reaching token.
+func uppercaseVariations(token string) float64 {
+	var upper, lower int
+	for _, ch := range token {
+		switch {
+		case unicode.IsUpper(ch):
+			upper++
+		case unicode.IsLower(ch):
+			lower++
+		}
+	}
+	if upper == 0 {
+		return 1
+	}
+
+	// Capitalizing the first or last letter, or all of them, is common.
+	runes := []rune(token)
+	first, last := unicode.IsUpper(runes[0]), unicode.IsUpper(runes[len(runes)-1])
+	if lower == 0 || (upper == 1 && (first || last)) {
+		return 2
+	}
+	return variations(upper, lower)
+}
+
+// l33tVariations returns how many ways of substituting characters an
+// attacker tries before reaching token.
+func l33tVariations(token []rune, table map[rune]rune) float64 {
+	v := 1.0
+	for sub, letter := range table {
+		var subbed, unsubbed int
+		for _, ch := range token {
+			switch unicode.ToLower(ch) {
+			case sub:
+				subbed++
+			case letter:
+				unsubbed++
+			}
+		}
+		if subbed == 0 {
+			continue
+		}
+		if unsubbed == 0 {
+			v *= 2
+		} else {
+			v *= variations(subbed, unsubbed)
+		}
+	}
+	return v
+}
+
+// variations returns the number of ways to pick up to min(a, b) of a+b
+// positions.
+func variations(a, b int) float64 {
+	v := 0.0
+	for i := 1; i <= min(a, b); i++ {
+		v += binomial(a+b, i)
+	}
+	return v
+}
+
+// spatialMatches finds runs of at least three adjacent keys, such as
+// "qwerty" or "zxcvbn".
+func spatialMatches(password []rune) []Match {
+	var matches []Match
+	for _, l := range layouts() {
+		i := 0
+		for i < len(password)-2 {
+			j := i + 1
+			turns := 0
+			var last [2]float64
+			for j < len(password) {
+				dir, ok := l.direction(password[j-1], password[j])
+				if !ok {
+					break
+				}
+				if j > i+1 && dir != last {
+					turns++
+				}
+				last = dir
+				j++
+			}
+
+			if j-i >= 3 {
+				shifted := 0
+				for _, ch := range password[i:j] {
+					if l.keys[ch].shifted {
+						shifted++
+					}
+				}
+				matches = append(matches, Match{
+					Pattern: Spatial,
+					I:       i,
+					J:       j - 1,
+					Token:   string(password[i:j]),
+					Layout:  l.name,
+					Turns:   turns + 1,
+					Guesses: spatialGuesses(l, j-i, turns+1, shifted),
+				})
+				i = j - 1
+				continue
+			}
+			i++
+		}
+	}
+	return matches
+}
+
+// spatialGuesses estimates the guesses needed for a keyboard pattern of
+// the given length, turns and shifted keys: every start, length and
+// placement of turns up to the actual ones.
+func spatialGuesses(l *layout, length, turns, shifted int) float64 {
+	guesses := 0.0
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * l.starts * math.Pow(l.degree, float64(j))
+		}
+	}
+
+	unshifted := length - shifted
+	if shifted > 0 {
+		if unshifted == 0 {
+			guesses *= 2
+		} else {
+			guesses *= variations(shifted, unshifted)
+		}
+	}
+	return guesses
+}
+
+// repeatMatches finds a unit repeated at least twice, such as "aaa" or
+// "abcabc". The guesses are those of the unit times the repeat count.
+func repeatMatches(password []rune, userInputs []string) []Match {
+	var matches []Match
+	i := 0
+	for i < len(password) {
+		// Find the longest repetition starting at i, preferring the
+		// shortest unit.
+		bestEnd, bestUnit := i, 0
+		for unit := 1; i+2*unit <= len(password); unit++ {
+			end := i + unit
+			for end+unit <= len(password) && string(password[end:end+unit]) == string(password[i:i+unit]) {
+				end += unit
+			}
+			if end-i >= 2*unit && end > bestEnd {
+				bestEnd, bestUnit = end, unit
+			}
+		}
+		if bestUnit == 0 {
+			i++
+			continue
+		}
+
+		base := password[i : i+bestUnit]
+		count := (bestEnd - i) / bestUnit
+		baseGuesses := estimate(base, userInputs).Guesses
+		matches = append(matches, Match{
+			Pattern: Repeat,
+			I:       i,
+			J:       bestEnd - 1,
+			Token:   string(password[i:bestEnd]),
+			Base:    string(base),
+			Guesses: baseGuesses * float64(count),
+		})
+		i = bestEnd
+	}
+	return matches
+}
+
+// maxSequenceDelta is the largest step between characters of a sequence.
+const maxSequenceDelta = 5
+
+// sequenceMatches finds runs of at least three characters with a constant
+// step, such as "abcd", "97531" or "ZYX".
+func sequenceMatches(password []rune) []Match {
+	var matches []Match
+	add := func(i, j int, delta rune) {
+		if j-i < 2 || delta == 0 || abs(delta) > maxSequenceDelta {
+			return
+		}
+		token := password[i : j+1]
+		matches = append(matches, Match{
+			Pattern: Sequence,
+			I:       i,
+			J:       j,
+			Token:   string(token),
+			Guesses: sequenceGuesses(token, delta > 0),
+		})
+	}
+
+	if len(password) < 3 {
+		return nil
+	}
+	i := 0
+	lastDelta := password[1] - password[0]
+	for k := 2; k < len(password); k++ {
+		delta := password[k] - password[k-1]
+		if delta != lastDelta {
+			add(i, k-1, lastDelta)
+			i = k - 1
+			lastDelta = delta
+		}
+	}
+	add(i, len(password)-1, lastDelta)
+	return matches
+}
+
+// sequenceGuesses estimates the guesses for a sequence: its first
+// character, its direction and its length.
+func sequenceGuesses(token []rune, ascending bool) float64 {
+	first := token[0]
+	var base float64
+	switch {
+	case strings.ContainsRune("aAzZ019", first):
+		// Obvious starting points.
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	case unicode.IsLetter(first):
+		base = 26
+	default:
+		base = 95
+	}
+	if !ascending {
+		base *= 2
+	}
+	return base * float64(len(token))
+}
+
+var (
+	// yearPattern matches recent years.
+	yearPattern = regexp.MustCompile(`19\d\d|20\d\d`)
+
+	// separatedDate matches dates with separators, such as 13/05/1990. The
+	// two separators are compared separately, as Go regexps have no back
+	// references.
+	separatedDate = regexp.MustCompile(`^(\d{1,4})([\s/\\_.-])(\d{1,2})([\s/\\_.-])(\d{1,4})$`)
+)
+
+// dateMatches finds dates, with or without separators, and recent years.
+func dateMatches(password []rune) []Match {
+	var matches []Match
+	s := string(password)
+
+	// Years on their own. The regexp works on bytes, so convert the
+	// offsets back to runes.
+	for _, loc := range yearPattern.FindAllStringIndex(s, -1) {
+		i := len([]rune(s[:loc[0]]))
+		token := s[loc[0]:loc[1]]
+		year, _ := strconv.Atoi(token)
+		matches = append(matches, Match{
+			Pattern: Year,
+			I:       i,
+			J:       i + len(token) - 1,
+			Token:   token,
+			Guesses: yearSpace(year),
+		})
+	}
+
+	for i := range password {
+		for j := i + 3; j < len(password) && j-i < 10; j++ {
+			token := string(password[i : j+1])
+			guesses, ok := dateGuesses(token)
+			if !ok {
+				continue
+			}
+			matches = append(matches, Match{
+				Pattern: Date,
+				I:       i,
+				J:       j,
+				Token:   token,
+				Guesses: guesses,
+			})
+		}
+	}
+	return matches
+}
+
+// dateGuesses returns the guesses for token if it is a date: digits only,
+// such as 13051990 or 1390, or with separators, such as 1990-05-13.
+func dateGuesses(token string) (float64, bool) {
+	if m := separatedDate.FindStringSubmatch(token); m != nil {
+		if m[2] != m[4] {
+			return 0, false
+		}
+		year, ok := validDate(atoi(m[1]), atoi(m[3]), atoi(m[5]), len(m[1]), len(m[5]))
+		if !ok {
+			return 0, false
+		}
+		// The separator adds a little.
+		return yearSpace(year) * 365 * 4, true
+	}
+
+	if len(token) > 8 || strings.Trim(token, "0123456789") != "" {
+		return 0, false
+	}
+
+	// Try every split into three numbers, the year being first or last.
+	for a := 1; a < len(token)-1; a++ {
+		for b := a + 1; b < len(token); b++ {
+			first, middle, last := token[:a], token[a:b], token[b:]
+			if len(middle) > 2 {
+				continue
+			}
+			if year, ok := validDate(atoi(first), atoi(middle), atoi(last), len(first), len(last)); ok {
+				return yearSpace(year) * 365, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// validDate reports whether the numbers form a date with the year first
+// or last, returning the year with two-digit years expanded.
+func validDate(first, middle, last, firstLen, lastLen int) (int, bool) {
+	type candidate struct{ year, yearLen, a, b int }
+	for _, c := range []candidate{
+		{last, lastLen, first, middle},
+		{first, firstLen, middle, last},
+	} {
+		year, ok := expandYear(c.year, c.yearLen)
+		if !ok {
+			continue
+		}
+		// Either order of day and month.
+		if (validDay(c.a) && validMonth(c.b)) || (validMonth(c.a) && validDay(c.b)) {
+			return year, true
+		}
+	}
+	return 0, false
+}
+
+// expandYear turns two-digit years into full years and rejects years far
+// from the present.
+func expandYear(year, digits int) (int, bool) {
+	switch digits {
+	case 2:
+		if year > 50 {
+			return 1900 + year, true
+		}
+		return 2000 + year, true
+	case 4:
+		return year, year >= 1000 && year <= 2050
+	}
+	return 0, false
+}
+
+// validDay reports whether d can be a day of the month.
+func validDay(d int) bool {
+	return d >= 1 && d <= 31
+}
+
+// validMonth reports whether m can be a month.
+func validMonth(m int) bool {
+	return m >= 1 && m <= 12
+}
+
+// yearSpace returns the number of years an attacker tries before reaching
+// year, counting outwards from the reference year.
+func yearSpace(year int) float64 {
+	return math.Max(math.Abs(float64(year-referenceYear)), minYearSpace)
+}
+
+// atoi converts a string of digits to a number.
+func atoi(s string) int {
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// abs returns the absolute value of r.
+func abs(r rune) rune {
+	if r < 0 {
+		return -r
+	}
+	return r
+}
+
+// reverse returns s written backwards.
+func reverse(s string) string {
+	r := []rune(s)
+	for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+		r[i], r[j] = r[j], r[i]
+	}
+	return string(r)
+}
+
+// binomial returns n choose k.
+func binomial(n, k int) float64 {
+	if k < 0 || k > n {
+		return 0
+	}
+	r := 1.0
+	for i := 1; i <= k; i++ {
+		r = r * float64(n-k+i) / float64(i)
+	}
+	return r
+}
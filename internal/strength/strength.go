@@ -0,0 +1,268 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package strength estimates how hard a password is to guess, in the
+// manner of Dropbox's zxcvbn. Rather than counting character classes, it
+// looks for the patterns attackers try first: common passwords and words
+// from embedded frequency lists (also reversed or with l33t substitutions),
+// keyboard walks, repeats, sequences and dates. The password is covered by
+// the combination of patterns that is cheapest to guess, which yields an
+// estimated number of guesses, a score from 0 to 4, crack times and
+// feedback on how to do better.
+package strength
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Score thresholds on the number of guesses: a password needing fewer
+// guesses than Thresholds[n] scores n.
+var Thresholds = [4]float64{1e3, 1e6, 1e8, 1e10}
+
+// Guessing rates used to express crack times, in guesses per second.
+const (
+	// OnlineThrottled is an attack on a service that limits attempts.
+	OnlineThrottled = 100.0 / 3600
+
+	// OnlineUnthrottled is an attack on a service that does not.
+	OnlineUnthrottled = 10.0
+
+	// OfflineSlowHash is an offline attack on a slow hash such as the
+	// Argon2id key derivation protecting the vault.
+	OfflineSlowHash = 1e4
+
+	// OfflineFastHash is an offline attack on a fast, unsalted hash.
+	OfflineFastHash = 1e10
+)
+
+// maxLength limits the part of a password that is analysed at once, which
+// keeps the matching fast. Longer passwords are analysed in pieces of that
+// length, whose guesses multiply, except that a piece repeating the runes
+// before it only adds one to a repeat count, so that a long run of a
+// short pattern is still recognised as a repeat.
+const maxLength = 100
+
+// Result is the strength estimate of a password.
+type Result struct {
+	// Guesses is the estimated number of guesses to find the password.
+	Guesses float64
+
+	// Score rates the password from 0 (too guessable) to 4 (very
+	// unguessable).
+	Score int
+
+	// Sequence is the combination of patterns that makes up the password
+	// most cheaply, in order.
+	Sequence []Match
+
+	// Feedback explains the score.
+	Feedback Feedback
+}
+
+// Estimate returns the strength of password. userInputs, such as the
+// website, username and email of the account, are treated as an extra
+// dictionary, so passwords derived from them are recognised.
+func Estimate(password string, userInputs ...string) Result {
+	runes := []rune(password)
+	r := estimate(runes[:min(len(runes), maxLength)], userInputs)
+
+	// Once the guesses overflow, further pieces cannot change the result.
+	repeats := 0
+	for offset := maxLength; offset < len(runes) && !math.IsInf(r.Guesses, 1); offset += maxLength {
+		end := min(len(runes), offset+maxLength)
+
+		// The piece occurs in the maxLength runes before it, possibly
+		// overlapping itself, as in any run of a pattern up to that long.
+		// Like repeatMatches, count one more unit instead of its guesses.
+		piece := string(runes[offset:end])
+		if strings.Contains(string(runes[offset-maxLength:end-1]), piece) {
+			repeats++
+			factor := float64(repeats+1) / float64(repeats)
+			r.Guesses *= factor
+			r.Sequence = append(r.Sequence, Match{Pattern: Repeat, I: offset, J: end - 1, Token: piece, Base: piece, Guesses: factor})
+			continue
+		}
+
+		p := estimate(runes[offset:end], userInputs)
+		r.Guesses *= p.Guesses
+		for _, m := range p.Sequence {
+			m.I, m.J = m.I+offset, m.J+offset
+			r.Sequence = append(r.Sequence, m)
+		}
+	}
+	r.Score = score(r.Guesses)
+	r.Feedback = feedback(r.Score, r.Sequence)
+	return r
+}
+
+// CrackTime returns how long finding the password takes at the given
+// rate of guesses per second.
+func (r Result) CrackTime(rate float64) time.Duration {
+	seconds := r.Guesses / rate
+	if seconds >= math.MaxInt64/float64(time.Second) {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// CrackTimeString returns CrackTime in words, such as "3 hours" or
+// "centuries".
+func (r Result) CrackTimeString(rate float64) string {
+	seconds := r.Guesses / rate
+	const (
+		minute  = 60
+		hour    = 60 * minute
+		day     = 24 * hour
+		month   = 31 * day
+		year    = 12 * month
+		century = 100 * year
+	)
+
+	units := []struct {
+		name string
+		size float64
+	}{
+		{"year", year}, {"month", month}, {"day", day}, {"hour", hour}, {"minute", minute}, {"second", 1},
+	}
+	switch {
+	case seconds < 1:
+		return "less than a second"
+	case seconds >= century:
+		return "centuries"
+	}
+	for _, u := range units {
+		if seconds >= u.size {
+			n := math.Round(seconds / u.size)
+			if n == 1 {
+				return "1 " + u.name
+			}
+			return fmt.Sprintf("%.0f %ss", n, u.name)
+		}
+	}
+	return "less than a second"
+}
+
+// String summarises the result, for example "1/4 (3 hours offline)".
+func (r Result) String() string {
+	return fmt.Sprintf("%d/4 (%s offline)", r.Score, r.CrackTimeString(OfflineSlowHash))
+}
+
+// score maps a number of guesses to a score from 0 to 4.
+func score(guesses float64) int {
+	for i, t := range Thresholds {
+		if guesses < t {
+			return i
+		}
+	}
+	return len(Thresholds)
+}
+
+// estimate finds the cheapest sequence of matches covering password.
+// Unmatched parts are covered by bruteforce matches.
+func estimate(password []rune, userInputs []string) Result {
+	n := len(password)
+	if n == 0 {
+		return Result{Guesses: 1}
+	}
+
+	// Group the candidate matches by their last rune, applying the
+	// minimum guesses to matches shorter than the whole password.
+	byEnd := make([][]Match, n)
+	for _, m := range allMatches(password, userInputs) {
+		if length := m.J - m.I + 1; length < n {
+			minGuesses := float64(minGuessesMultiChar)
+			if length == 1 {
+				minGuesses = minGuessesSingleChar
+			}
+			m.Guesses = math.Max(m.Guesses, minGuesses)
+		}
+		byEnd[m.J] = append(byEnd[m.J], m)
+	}
+	for i := range n {
+		for j := i; j < n; j++ {
+			m := Match{
+				Pattern: Bruteforce,
+				I:       i,
+				J:       j,
+				Token:   string(password[i : j+1]),
+				Guesses: math.Pow(bruteforceCardinality, float64(j-i+1)),
+			}
+			if j-i+1 < n {
+				m.Guesses = math.Max(m.Guesses, minGuessesSingleChar+1)
+			}
+			byEnd[j] = append(byEnd[j], m)
+		}
+	}
+
+	// best[k][l] is the cheapest product of guesses of l matches covering
+	// password[:k+1], ending with last[k][l].
+	type step struct {
+		product float64
+		match   Match
+		ok      bool
+	}
+	best := make([][]step, n)
+	for k := range best {
+		best[k] = make([]step, n+1)
+	}
+	for k := range n {
+		for _, m := range byEnd[k] {
+			for l := 1; l <= m.I+1; l++ {
+				prev := 1.0
+				if m.I > 0 {
+					p := best[m.I-1][l-1]
+					if !p.ok {
+						continue
+					}
+					prev = p.product
+				} else if l != 1 {
+					continue
+				}
+				product := prev * m.Guesses
+				if s := best[k][l]; !s.ok || product < s.product {
+					best[k][l] = step{product: product, match: m, ok: true}
+				}
+			}
+		}
+	}
+
+	// The total also pays for not knowing how many patterns there are.
+	bestL, bestTotal := 0, math.Inf(1)
+	for l := 1; l <= n; l++ {
+		s := best[n-1][l]
+		if !s.ok {
+			continue
+		}
+		total := factorial(l)*s.product + math.Pow(minGuessesSequence, float64(l-1))
+		if total < bestTotal {
+			bestL, bestTotal = l, total
+		}
+	}
+
+	// Walk back to recover the sequence.
+	sequence := make([]Match, bestL)
+	k := n - 1
+	for l := bestL; l >= 1; l-- {
+		m := best[k][l].match
+		sequence[l-1] = m
+		k = m.I - 1
+	}
+	return Result{Guesses: bestTotal, Sequence: sequence}
+}
+
+// minGuessesSequence is the base of the additive cost of a sequence of
+// several patterns, as in zxcvbn.
+const minGuessesSequence = 10000
+
+// factorial returns n!.
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
@@ -0,0 +1,190 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package strength_test contains unit tests for the strength package.
+// These tests check that common password patterns are recognised and
+// scored low, while random passwords score high.
+package strength_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nullzeiger/pwdcli/internal/strength"
+)
+
+// TestEstimatePatterns verifies that each kind of pattern is recognised
+// and that passwords made of a single pattern score low.
+func TestEstimatePatterns(t *testing.T) {
+	tests := []struct {
+		password string
+		pattern  strength.Pattern
+		maxScore int
+	}{
+		{"password", strength.Dictionary, 0},
+		{"123456", strength.Dictionary, 0},
+		{"drowssap", strength.Dictionary, 0},
+		{"Tr0mb0ne", strength.Dictionary, 1},
+		{"7896321", strength.Spatial, 1},
+		{"aaaaaaaa", strength.Repeat, 0},
+		{"xyzxyzxyz", strength.Repeat, 0},
+		{"abcdefgh", strength.Sequence, 0},
+		{"97531", strength.Sequence, 0},
+		{"13/05/1990", strength.Date, 1},
+		{"19900513", strength.Date, 1},
+		{"1987", strength.Year, 0},
+	}
+
+	for _, tt := range tests {
+		r := strength.Estimate(tt.password)
+		if r.Score > tt.maxScore {
+			t.Errorf("Estimate(%q).Score = %d; want at most %d", tt.password, r.Score, tt.maxScore)
+		}
+		if len(r.Sequence) != 1 || r.Sequence[0].Pattern != tt.pattern {
+			t.Errorf("Estimate(%q).Sequence = %+v; want a single %s match", tt.password, r.Sequence, tt.pattern)
+		}
+	}
+}
+
+// TestEstimateVariants verifies that reversed and l33t dictionary words
+// are flagged as such.
+func TestEstimateVariants(t *testing.T) {
+	r := strength.Estimate("drowssap")
+	if m := r.Sequence[0]; !m.Reversed || m.Dictionary != "passwords" {
+		t.Errorf("Estimate(drowssap) match = %+v; want a reversed password", m)
+	}
+
+	r = strength.Estimate("Tr0mb0ne")
+	if m := r.Sequence[0]; !m.L33t || m.Dictionary == "" {
+		t.Errorf("Estimate(Tr0mb0ne) match = %+v; want a l33t dictionary word", m)
+	}
+}
+
+// TestEstimateStrong verifies that random passwords and long passphrases
+// get the highest score and no warning.
+func TestEstimateStrong(t *testing.T) {
+	for _, pwd := range []string{
+		"9C|n))6)UoepVa7}-un8",
+		"retinal-reversing-triumph-recliner-eagle2-goldfish",
+		"Vk3#pQ9!zR2@",
+	} {
+		r := strength.Estimate(pwd)
+		if r.Score != 4 {
+			t.Errorf("Estimate(%q).Score = %d; want 4", pwd, r.Score)
+		}
+		if r.Feedback.Warning != "" {
+			t.Errorf("Estimate(%q) warns %q; want no warning", pwd, r.Feedback.Warning)
+		}
+	}
+}
+
+// TestEstimateLong verifies that passwords longer than the analysed
+// length are still recognised as a repeated trivial pattern, while long
+// random ones stay strong, and that huge inputs are estimated quickly.
+func TestEstimateLong(t *testing.T) {
+	for _, pwd := range []string{
+		strings.Repeat("a", 116),
+		strings.Repeat("ab", 150),
+		strings.Repeat("1234567890", 25),
+		strings.Repeat("abc", 100),
+		strings.Repeat("ab", 150) + "x",
+	} {
+		if r := strength.Estimate(pwd); r.Score > 2 {
+			t.Errorf("Estimate(%d runes of %q).Score = %d; want at most 2", len(pwd), pwd[:10], r.Score)
+		}
+	}
+
+	const random = `PtYgj=mUh&Bel31iEl@2h_pCh~YgCf^rL1s?p~N^xn~yVm@i_hA%=2O76UMFxFk~M-%R5Kjp*1vRt#1fj^~ORS%6ilI8ihN~5KXSc7Tvo%hBKqFYY%kv5Z@Jr3@J1TWDtk`
+	if r := strength.Estimate(random); r.Score != 4 || r.Sequence[len(r.Sequence)-1].J != len(random)-1 {
+		t.Errorf("Estimate(%d random runes) = %d/4 covering up to %d; want 4/4 covering the whole password",
+			len(random), r.Score, r.Sequence[len(r.Sequence)-1].J)
+	}
+
+	start := time.Now()
+	strength.Estimate(strings.Repeat("a", 1<<20))
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Estimate of 1 MiB took %s", elapsed)
+	}
+}
+
+// TestEstimateScoreIncreases verifies that adding random characters never
+// makes a password easier to guess.
+func TestEstimateScoreIncreases(t *testing.T) {
+	pwd := "monkey"
+	last := strength.Estimate(pwd).Guesses
+	for _, ch := range "7#Qz!k2" {
+		pwd += string(ch)
+		g := strength.Estimate(pwd).Guesses
+		if g < last {
+			t.Fatalf("Estimate(%q).Guesses = %g < %g for a shorter prefix", pwd, g, last)
+		}
+		last = g
+	}
+}
+
+// TestEstimateUserInputs verifies that passwords derived from the account
+// details are recognised.
+func TestEstimateUserInputs(t *testing.T) {
+	const pwd = "zanzibarco"
+
+	without := strength.Estimate(pwd)
+	with := strength.Estimate(pwd, "zanzibarco", "someone@example.com")
+	if with.Guesses >= without.Guesses {
+		t.Fatalf("Estimate with user inputs = %g guesses; want fewer than %g", with.Guesses, without.Guesses)
+	}
+	if !strings.Contains(with.Feedback.Warning, "account") {
+		t.Errorf("Estimate with user inputs warns %q; want a warning about the account details", with.Feedback.Warning)
+	}
+}
+
+// TestFeedback verifies the warnings and suggestions of weak passwords.
+func TestFeedback(t *testing.T) {
+	tests := []struct {
+		password string
+		warning  string
+	}{
+		{"password", "top-10"},
+		{"qwertyuiop", "common password"},
+		{"aaaaaaaa", "Repeats"},
+		{"abcdefgh", "Sequences"},
+		{"1987", "years"},
+	}
+
+	for _, tt := range tests {
+		f := strength.Estimate(tt.password).Feedback
+		if !strings.Contains(f.Warning, tt.warning) {
+			t.Errorf("Estimate(%q).Feedback.Warning = %q; want it to mention %q", tt.password, f.Warning, tt.warning)
+		}
+		if len(f.Suggestions) == 0 {
+			t.Errorf("Estimate(%q).Feedback.Suggestions is empty", tt.password)
+		}
+	}
+}
+
+// TestCrackTime verifies the conversion of guesses to crack times.
+func TestCrackTime(t *testing.T) {
+	tests := []struct {
+		guesses float64
+		rate    float64
+		want    string
+	}{
+		{10, strength.OfflineSlowHash, "less than a second"},
+		{3 * 3600 * 1e4, strength.OfflineSlowHash, "3 hours"},
+		{60, 1, "1 minute"},
+		{1e20, strength.OfflineSlowHash, "centuries"},
+	}
+
+	for _, tt := range tests {
+		r := strength.Result{Guesses: tt.guesses}
+		if got := r.CrackTimeString(tt.rate); got != tt.want {
+			t.Errorf("CrackTimeString(%g guesses at %g/s) = %q; want %q", tt.guesses, tt.rate, got, tt.want)
+		}
+	}
+
+	r := strength.Result{Guesses: 36000}
+	if got := r.CrackTime(strength.OnlineUnthrottled); got != time.Hour {
+		t.Errorf("CrackTime() = %v; want 1h", got)
+	}
+}
@@ -3,31 +3,124 @@
 // license that can be found in the LICENSE file.
 
 // Package util provides utility functions and constants used across the application,
-// including file path generation and file existence checks.
+// including vault and configuration path resolution, file existence checks
+// and the parsing of ages given on the command line.
 package util
 
-import "os"
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
 
 const (
-	// Filename defines the default name of the JSON storage file.
+	// Filename defines the name of the storage file used by earlier
+	// versions, located directly in the home directory.
 	Filename = ".passwords.json"
 
-	// Perm specifies the file permissions used when writing the storage file.
-	// 0o644 = owner read/write, group read, others read.
-	Perm = 0o644
+	// VaultFilename is the name of the vault inside the data directory.
+	VaultFilename = "vault"
+
+	// AppName names the application directories under the XDG base
+	// directories.
+	AppName = "pwdcli"
+
+	// EnvVault names the environment variable that overrides the vault path.
+	EnvVault = "PWDCLI_VAULT"
+
+	// Perm specifies the file permissions of a new storage file and its
+	// backups. 0o600 = owner read/write only: the vault is encrypted, but
+	// anyone able to copy it could attack the master password offline.
+	Perm = 0o600
+
+	// DirPerm specifies the permissions of directories created for the vault.
+	// 0o700 = accessible by the owner only.
+	DirPerm = 0o700
 )
 
-// FilePath returns the full path of the storage file located
-// in the user's home directory. If the home directory cannot be
-// determined, the function panics since the application cannot continue.
-func FilePath() string {
+// ErrNoVaultPath is returned when none of the vault location sources is
+// set and there is no home directory to fall back on.
+var ErrNoVaultPath = errors.New("cannot determine the vault location: use -vault or set " + EnvVault)
+
+// FilePath returns the full path of the storage file used by earlier
+// versions, located in the user's home directory. It returns an error
+// if the home directory cannot be determined.
+func FilePath() (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, Filename), nil
+}
+
+// DataDir returns the directory where pwdcli keeps its data:
+// $XDG_DATA_HOME/pwdcli, or ~/.local/share/pwdcli when XDG_DATA_HOME
+// is not set.
+func DataDir() (string, error) {
+	return xdgDir("XDG_DATA_HOME", filepath.Join(".local", "share"))
+}
+
+// ConfigDir returns the directory where pwdcli looks for its configuration:
+// $XDG_CONFIG_HOME/pwdcli, or ~/.config/pwdcli when XDG_CONFIG_HOME
+// is not set.
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// xdgDir returns the pwdcli directory below the XDG base directory named
+// by env, falling back to fallback relative to the home directory.
+// Relative values of env are ignored as the specification requires.
+func xdgDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, AppName), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, fallback, AppName), nil
+}
+
+// VaultPath resolves the location of the vault. The first non-empty source
+// wins, in order: flagValue (the -vault flag), the PWDCLI_VAULT environment
+// variable, configValue (the configuration file), an existing
+// ~/.passwords.json left by earlier versions, and finally the vault file
+// in DataDir. A leading "~/" is expanded to the home directory.
+func VaultPath(flagValue, configValue string) (string, error) {
+	for _, p := range []string{flagValue, os.Getenv(EnvVault), configValue} {
+		if p != "" {
+			return ExpandHome(p)
+		}
+	}
+
+	// Keep using the file of earlier versions if there is one.
+	if legacy, err := FilePath(); err == nil && FileExists(legacy) {
+		return legacy, nil
+	}
+
+	dir, err := DataDir()
+	if err != nil {
+		return "", ErrNoVaultPath
+	}
+	return filepath.Join(dir, VaultFilename), nil
+}
+
+// ExpandHome replaces a leading "~/" in path with the home directory.
+func ExpandHome(path string) (string, error) {
+	rest, ok := strings.CutPrefix(path, "~/")
+	if !ok {
+		return path, nil
+	}
 	home, err := os.UserHomeDir()
 	if err != nil {
-		// Panic is acceptable here because without a home directory
-		// the program cannot determine where to store user data.
-		panic(err)
+		return "", err
 	}
-	return home + "/" + Filename
+	return filepath.Join(home, rest), nil
 }
 
 // FileExists checks whether a file exists at the given path.
@@ -36,3 +129,32 @@ func FileExists(path string) bool {
 	_, err := os.Stat(path)
 	return err == nil
 }
+
+// ageUnits are the units ParseAge accepts besides those of
+// time.ParseDuration.
+var ageUnits = map[string]time.Duration{
+	"d": 24 * time.Hour,
+	"w": 7 * 24 * time.Hour,
+}
+
+// ParseAge parses an age such as "180d": a whole number of days (d) or
+// weeks (w), or any non-negative duration accepted by time.ParseDuration,
+// as in "12h".
+func ParseAge(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	invalid := fmt.Errorf("invalid age %q: use days as in 180d, weeks as in 2w, or a duration as in 12h", s)
+	for suffix, unit := range ageUnits {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.ParseInt(n, 10, 64)
+			if err != nil || v < 0 || v > math.MaxInt64/int64(unit) {
+				return 0, invalid
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, invalid
+	}
+	return d, nil
+}
@@ -3,14 +3,16 @@
 // license that can be found in the LICENSE file.
 
 // Package util_test contains unit tests for the util package.
-// These tests validate file path generation and file existence checks
+// These tests validate path resolution and file existence checks
 // without affecting the user's real HOME directory or filesystem.
 package util_test
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/nullzeiger/pwdcli/internal/util"
 )
@@ -29,7 +31,10 @@ func TestFilePath(t *testing.T) {
 	expected := filepath.Join(tmpDir, util.Filename)
 
 	// Call FilePath
-	got := util.FilePath()
+	got, err := util.FilePath()
+	if err != nil {
+		t.Fatalf("FilePath() failed: %v", err)
+	}
 
 	// Compare result
 	if got != expected {
@@ -37,6 +42,71 @@ func TestFilePath(t *testing.T) {
 	}
 }
 
+// TestVaultPath verifies the order in which util.VaultPath consults its
+// sources: flag, environment, configuration, legacy file, data directory.
+func TestVaultPath(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("HOME", tmpDir)
+	t.Setenv("XDG_DATA_HOME", filepath.Join(tmpDir, "data"))
+	t.Setenv(util.EnvVault, "")
+
+	tests := []struct {
+		name     string
+		env      string
+		flag     string
+		config   string
+		legacy   bool
+		expected string
+	}{
+		{"flag wins", "/env/vault", "/flag/vault", "/config/vault", true, "/flag/vault"},
+		{"environment", "/env/vault", "", "/config/vault", true, "/env/vault"},
+		{"config file", "", "", "/config/vault", true, "/config/vault"},
+		{"config with tilde", "", "", "~/vault", false, filepath.Join(tmpDir, "vault")},
+		{"legacy file", "", "", "", true, filepath.Join(tmpDir, util.Filename)},
+		{"XDG default", "", "", "", false, filepath.Join(tmpDir, "data", util.AppName, util.VaultFilename)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv(util.EnvVault, tt.env)
+
+			// Create or remove the file left by earlier versions
+			legacy := filepath.Join(tmpDir, util.Filename)
+			os.Remove(legacy)
+			if tt.legacy {
+				os.WriteFile(legacy, []byte("[]"), 0o600)
+			}
+
+			got, err := util.VaultPath(tt.flag, tt.config)
+			if err != nil {
+				t.Fatalf("VaultPath() failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Fatalf("VaultPath() = %s; want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+// TestVaultPathNoHome verifies that util.VaultPath reports an error instead
+// of panicking when neither HOME nor XDG_DATA_HOME is set, and that an
+// explicit location still works in that environment.
+func TestVaultPathNoHome(t *testing.T) {
+	t.Setenv("HOME", "")
+	t.Setenv("XDG_DATA_HOME", "")
+	t.Setenv(util.EnvVault, "")
+
+	if _, err := util.VaultPath("", ""); !errors.Is(err, util.ErrNoVaultPath) {
+		t.Fatalf("VaultPath() error = %v; want ErrNoVaultPath", err)
+	}
+
+	t.Setenv(util.EnvVault, "/srv/ci/vault")
+	got, err := util.VaultPath("", "")
+	if err != nil || got != "/srv/ci/vault" {
+		t.Fatalf("VaultPath() = %s, %v; want /srv/ci/vault", got, err)
+	}
+}
+
 // TestFileExists checks that util.FileExists correctly identifies
 // whether a file exists at a given path. It tests both existing and
 // non-existing files using a temporary directory.
@@ -61,3 +131,38 @@ func TestFileExists(t *testing.T) {
 		t.Fatalf("FileExists(%s) = true; want false", nonExistent)
 	}
 }
+
+// TestParseAge checks the ages util.ParseAge accepts, in days, weeks or
+// as Go durations, and that it rejects malformed and negative ones.
+func TestParseAge(t *testing.T) {
+	day := 24 * time.Hour
+	tests := []struct {
+		in   string
+		want time.Duration
+		ok   bool
+	}{
+		{"180d", 180 * day, true},
+		{" 2w ", 14 * day, true},
+		{"0d", 0, true},
+		{"12h", 12 * time.Hour, true},
+		{"1h30m", 90 * time.Minute, true},
+		{"", 0, false},
+		{"d", 0, false},
+		{"1.5d", 0, false},
+		{"-3d", 0, false},
+		{"-1h", 0, false},
+		{"99999999999d", 0, false},
+		{"soon", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := util.ParseAge(tt.in)
+		if (err == nil) != tt.ok {
+			t.Errorf("ParseAge(%q) error = %v; want ok %v", tt.in, err, tt.ok)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ParseAge(%q) = %v; want %v", tt.in, got, tt.want)
+		}
+	}
+}
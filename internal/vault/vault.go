@@ -0,0 +1,278 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vault implements the encrypted on-disk format of the password
+// store. The plaintext is sealed with AES-256-GCM using a key derived from
+// the master password with Argon2id. The salt, KDF parameters and nonce are
+// kept in a small versioned header that is authenticated together with the
+// ciphertext.
+package vault
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	// Magic identifies a sealed vault file.
+	Magic = "PWDCLI"
+
+	// Version is the current version of the vault header.
+	Version = 1
+
+	// kdfArgon2id identifies the Argon2id key derivation function.
+	kdfArgon2id = 1
+
+	saltSize  = 16
+	nonceSize = 12
+	keySize   = 32
+
+	// headerSize is the length of the fixed header that precedes the
+	// ciphertext: magic, version, KDF id, time, memory, threads, salt
+	// and nonce.
+	headerSize = len(Magic) + 1 + 1 + 4 + 4 + 1 + saltSize + nonceSize
+)
+
+var (
+	// ErrWrongPassword is returned when the vault cannot be opened with
+	// the given master password. Because the cipher is authenticated this
+	// also covers a file that has been tampered with.
+	ErrWrongPassword = errors.New("wrong master password or corrupted vault")
+
+	// ErrNotSealed is returned when the data does not start with a
+	// vault header.
+	ErrNotSealed = errors.New("data is not an encrypted vault")
+
+	// ErrEmptyPassword is returned when an empty master password is used.
+	ErrEmptyPassword = errors.New("master password must not be empty")
+
+	// ErrInvalidParams is returned for KDF parameters that Argon2 cannot
+	// work with or that exceed MaxParams.
+	ErrInvalidParams = errors.New("invalid KDF parameters")
+)
+
+// Params holds the Argon2id cost parameters stored in the vault header.
+type Params struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+
+	// Memory is the amount of memory used, in KiB.
+	Memory uint32
+
+	// Threads is the degree of parallelism.
+	Threads uint8
+}
+
+// DefaultParams are the cost parameters used for newly created vaults.
+var DefaultParams = Params{Time: 3, Memory: 64 * 1024, Threads: 4}
+
+// MaxParams are the highest cost parameters accepted. The header is only
+// authenticated once the key is derived, so without a ceiling a corrupted
+// or hostile vault could make key derivation run for hours or allocate
+// terabytes of memory before being rejected.
+var MaxParams = Params{Time: 100, Memory: 4 * 1024 * 1024, Threads: 255}
+
+// String returns the parameters in a human readable form.
+func (p Params) String() string {
+	return fmt.Sprintf("argon2id t=%d m=%dKiB p=%d", p.Time, p.Memory, p.Threads)
+}
+
+// validate rejects parameters that Argon2 cannot work with and those
+// above MaxParams.
+func (p Params) validate() error {
+	if p.Time < 1 || p.Threads < 1 || p.Memory < 8*uint32(p.Threads) {
+		return fmt.Errorf("%w: %s", ErrInvalidParams, p)
+	}
+	if p.Time > MaxParams.Time || p.Memory > MaxParams.Memory || p.Threads > MaxParams.Threads {
+		return fmt.Errorf("%w: %s exceeds the limit of %s", ErrInvalidParams, p, MaxParams)
+	}
+	return nil
+}
+
+// Key is a key derived from a master password together with the salt and
+// parameters that produced it. A Key can seal any number of payloads; each
+// call uses a fresh random nonce.
+type Key struct {
+	params Params
+	salt   [saltSize]byte
+	key    []byte
+}
+
+// NewKey derives a new key from password using a fresh random salt.
+func NewKey(password []byte, params Params) (*Key, error) {
+	if len(password) == 0 {
+		return nil, ErrEmptyPassword
+	}
+	if err := params.validate(); err != nil {
+		return nil, err
+	}
+
+	k := &Key{params: params}
+	if _, err := rand.Read(k.salt[:]); err != nil {
+		return nil, err
+	}
+	k.key = derive(password, k.salt[:], params)
+	return k, nil
+}
+
+// Params returns the KDF parameters the key was derived with.
+func (k *Key) Params() Params {
+	return k.params
+}
+
+// Seal encrypts plaintext and returns the complete vault file contents,
+// header included.
+func (k *Key) Seal(plaintext []byte) ([]byte, error) {
+	var nonce [nonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+
+	header := k.header(nonce)
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+
+	// The header is passed as additional data so that tampering with
+	// the salt or parameters is detected just like ciphertext changes.
+	return aead.Seal(header, nonce[:], plaintext, header), nil
+}
+
+// header encodes the vault header for the given nonce.
+func (k *Key) header(nonce [nonceSize]byte) []byte {
+	h := make([]byte, 0, headerSize)
+	h = append(h, Magic...)
+	h = append(h, Version, kdfArgon2id)
+	h = binary.BigEndian.AppendUint32(h, k.params.Time)
+	h = binary.BigEndian.AppendUint32(h, k.params.Memory)
+	h = append(h, k.params.Threads)
+	h = append(h, k.salt[:]...)
+	h = append(h, nonce[:]...)
+	return h
+}
+
+// Open decrypts data sealed by the same key. It avoids deriving the key
+// again when many payloads share it. Data sealed with a different salt or
+// parameters is rejected with ErrWrongPassword.
+func (k *Key) Open(data []byte) ([]byte, error) {
+	if !IsSealed(data) {
+		return nil, ErrNotSealed
+	}
+	if len(data) < headerSize {
+		return nil, ErrWrongPassword
+	}
+
+	nonce := [nonceSize]byte(data[headerSize-nonceSize : headerSize])
+	if !bytes.Equal(data[:headerSize], k.header(nonce)) {
+		return nil, ErrWrongPassword
+	}
+
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce[:], data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, ErrWrongPassword
+	}
+	return plaintext, nil
+}
+
+// Tag returns a keyed SHA-256 MAC of data. Equal inputs give equal tags
+// under the same key, which allows exact-match lookups on encrypted
+// records without storing the looked-up values in the clear.
+func (k *Key) Tag(data []byte) []byte {
+	mac := hmac.New(sha256.New, k.tagKey())
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+// tagKey derives the MAC key from the encryption key, so that the two
+// are never used for more than one purpose.
+func (k *Key) tagKey() []byte {
+	tk, err := hkdf.Key(sha256.New, k.key, nil, "pwdcli tag", keySize)
+	if err != nil {
+		// hkdf.Key only fails for lengths far above keySize.
+		panic(err)
+	}
+	return tk
+}
+
+// Open decrypts a sealed vault with password. It returns the plaintext and
+// the Key derived from the header so that the caller can seal the updated
+// contents without deriving the key again.
+func Open(password, data []byte) ([]byte, *Key, error) {
+	if !IsSealed(data) {
+		return nil, nil, ErrNotSealed
+	}
+	if len(data) < headerSize {
+		return nil, nil, ErrWrongPassword
+	}
+
+	// Decode the header fields following the magic.
+	p := len(Magic)
+	if data[p] != Version {
+		return nil, nil, fmt.Errorf("unsupported vault version %d", data[p])
+	}
+	if data[p+1] != kdfArgon2id {
+		return nil, nil, fmt.Errorf("unsupported key derivation function %d", data[p+1])
+	}
+	p += 2
+
+	k := &Key{}
+	k.params.Time = binary.BigEndian.Uint32(data[p:])
+	k.params.Memory = binary.BigEndian.Uint32(data[p+4:])
+	k.params.Threads = data[p+8]
+	p += 9
+	if err := k.params.validate(); err != nil {
+		return nil, nil, err
+	}
+	copy(k.salt[:], data[p:p+saltSize])
+	nonce := data[p+saltSize : headerSize]
+
+	if len(password) == 0 {
+		return nil, nil, ErrEmptyPassword
+	}
+	k.key = derive(password, k.salt[:], k.params)
+
+	aead, err := newAEAD(k.key)
+	if err != nil {
+		return nil, nil, err
+	}
+	plaintext, err := aead.Open(nil, nonce, data[headerSize:], data[:headerSize])
+	if err != nil {
+		return nil, nil, ErrWrongPassword
+	}
+	return plaintext, k, nil
+}
+
+// IsSealed reports whether data starts with the vault magic.
+func IsSealed(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(Magic))
+}
+
+// derive runs Argon2id over password and salt.
+func derive(password, salt []byte, p Params) []byte {
+	return argon2.IDKey(password, salt, p.Time, p.Memory, p.Threads, keySize)
+}
+
+// newAEAD builds the AES-256-GCM cipher for key.
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,150 @@
+// Copyright 2025 Ivan Guerreschi. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package vault_test contains unit tests for the vault package.
+// These tests verify that sealed data round-trips with the right master
+// password and is rejected with the wrong one or after tampering.
+package vault_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/nullzeiger/pwdcli/internal/vault"
+)
+
+// testParams keeps key derivation cheap so the tests run quickly.
+var testParams = vault.Params{Time: 1, Memory: 64, Threads: 1}
+
+// TestSealOpen verifies that data sealed with a key can be opened with
+// the same master password and that the header parameters are preserved.
+func TestSealOpen(t *testing.T) {
+	key, err := vault.NewKey([]byte("master"), testParams)
+	if err != nil {
+		t.Fatalf("NewKey() failed: %v", err)
+	}
+
+	sealed, err := key.Seal([]byte(`[{"website":"example.com"}]`))
+	if err != nil {
+		t.Fatalf("Seal() failed: %v", err)
+	}
+
+	// The plaintext must not appear in the sealed output
+	if bytes.Contains(sealed, []byte("example.com")) {
+		t.Fatalf("Sealed data contains plaintext")
+	}
+	if !vault.IsSealed(sealed) {
+		t.Fatalf("IsSealed() = false for sealed data")
+	}
+
+	plaintext, opened, err := vault.Open([]byte("master"), sealed)
+	if err != nil {
+		t.Fatalf("Open() failed: %v", err)
+	}
+	if string(plaintext) != `[{"website":"example.com"}]` {
+		t.Fatalf("Open() = %s; want original plaintext", plaintext)
+	}
+	if opened.Params() != testParams {
+		t.Fatalf("Open() params = %v; want %v", opened.Params(), testParams)
+	}
+}
+
+// TestOpenWrongPassword verifies that a wrong master password yields
+// ErrWrongPassword rather than garbage plaintext.
+func TestOpenWrongPassword(t *testing.T) {
+	key, _ := vault.NewKey([]byte("master"), testParams)
+	sealed, _ := key.Seal([]byte("[]"))
+
+	_, _, err := vault.Open([]byte("not the master"), sealed)
+	if !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Open() with wrong password = %v; want ErrWrongPassword", err)
+	}
+}
+
+// TestOpenTampered verifies that modifying either the header or the
+// ciphertext is detected.
+func TestOpenTampered(t *testing.T) {
+	key, _ := vault.NewKey([]byte("master"), testParams)
+	sealed, _ := key.Seal([]byte("[]"))
+
+	// Flip one bit of the salt (header) and one of the ciphertext
+	for _, i := range []int{len(vault.Magic) + 12, len(sealed) - 1} {
+		tampered := bytes.Clone(sealed)
+		tampered[i] ^= 1
+
+		_, _, err := vault.Open([]byte("master"), tampered)
+		if !errors.Is(err, vault.ErrWrongPassword) {
+			t.Fatalf("Open() of data tampered at %d = %v; want ErrWrongPassword", i, err)
+		}
+	}
+}
+
+// TestOpenExcessiveParams verifies that a header asking for more than
+// MaxParams is rejected before any key derivation, which would otherwise
+// exhaust memory or time.
+func TestOpenExcessiveParams(t *testing.T) {
+	key, _ := vault.NewKey([]byte("master"), testParams)
+	sealed, _ := key.Seal([]byte("[]"))
+
+	// The time and memory follow the magic, the version and the KDF id.
+	for name, offset := range map[string]int{"time": len(vault.Magic) + 2, "memory": len(vault.Magic) + 6} {
+		tampered := bytes.Clone(sealed)
+		copy(tampered[offset:], []byte{0xff, 0xff, 0xff, 0xf0})
+
+		_, _, err := vault.Open([]byte("master"), tampered)
+		if !errors.Is(err, vault.ErrInvalidParams) {
+			t.Errorf("Open() with excessive %s = %v; want ErrInvalidParams", name, err)
+		}
+	}
+
+	// New keys are held to the same limit.
+	if _, err := vault.NewKey([]byte("master"), vault.Params{Time: 1, Memory: vault.MaxParams.Memory + 1, Threads: 1}); !errors.Is(err, vault.ErrInvalidParams) {
+		t.Errorf("NewKey() above MaxParams error = %v; want ErrInvalidParams", err)
+	}
+}
+
+// TestOpenNotSealed verifies that plain JSON is reported as not sealed.
+func TestOpenNotSealed(t *testing.T) {
+	_, _, err := vault.Open([]byte("master"), []byte("[]"))
+	if !errors.Is(err, vault.ErrNotSealed) {
+		t.Fatalf("Open() of plain data = %v; want ErrNotSealed", err)
+	}
+}
+
+// TestKeyOpen verifies that a key opens its own payloads without deriving
+// again and rejects payloads sealed under another key.
+func TestKeyOpen(t *testing.T) {
+	key, _ := vault.NewKey([]byte("master"), testParams)
+	other, _ := vault.NewKey([]byte("master"), testParams)
+
+	sealed, _ := key.Seal([]byte("row"))
+	plaintext, err := key.Open(sealed)
+	if err != nil || string(plaintext) != "row" {
+		t.Fatalf("Key.Open() = %q, %v; want row", plaintext, err)
+	}
+
+	// Same password but a different salt must not open it
+	if _, err := other.Open(sealed); !errors.Is(err, vault.ErrWrongPassword) {
+		t.Fatalf("Key.Open() with another key = %v; want ErrWrongPassword", err)
+	}
+}
+
+// TestTag verifies that tags are deterministic per key and differ
+// between keys and inputs.
+func TestTag(t *testing.T) {
+	key, _ := vault.NewKey([]byte("master"), testParams)
+	other, _ := vault.NewKey([]byte("master"), testParams)
+
+	a := key.Tag([]byte("example.com"))
+	if !bytes.Equal(a, key.Tag([]byte("example.com"))) {
+		t.Fatalf("Tag() is not deterministic")
+	}
+	if bytes.Equal(a, key.Tag([]byte("example.org"))) {
+		t.Fatalf("Tag() is equal for different inputs")
+	}
+	if bytes.Equal(a, other.Tag([]byte("example.com"))) {
+		t.Fatalf("Tag() is equal under different keys")
+	}
+}